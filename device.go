@@ -0,0 +1,85 @@
+// +build linux
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/opencontainers/runc/libcontainer/devices"
+	"github.com/urfave/cli"
+)
+
+var deviceCommand = cli.Command{
+	Name:  "device",
+	Usage: "add or remove device nodes of a running container",
+	Subcommands: []cli.Command{
+		deviceAddCommand,
+		deviceRemoveCommand,
+	},
+}
+
+var deviceAddCommand = cli.Command{
+	Name:  "add",
+	Usage: "add a host device node to a running container",
+	ArgsUsage: `<container-id> <path>
+
+Where "<container-id>" is the name for the instance of the container and
+"<path>" is the path of the device node on the host. The node is created at
+the same path inside the container.`,
+	Description: `The device add command allows the container to access the device node in its
+devices cgroup, creates the node inside the container and records the device
+in the container's state.
+
+EXAMPLE:
+
+       # runc device add --permissions rw mycontainer /dev/ttyUSB0`,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "permissions",
+			Value: "rwm",
+			Usage: "cgroup permissions of the device (any of r, w and m)",
+		},
+	},
+	Action: func(context *cli.Context) error {
+		if err := checkArgs(context, 2, exactArgs); err != nil {
+			return err
+		}
+		permissions := context.String("permissions")
+		for _, p := range permissions {
+			if p != 'r' && p != 'w' && p != 'm' {
+				return fmt.Errorf("invalid device permissions %q", permissions)
+			}
+		}
+		dev, err := devices.DeviceFromPath(context.Args().Get(1), permissions)
+		if err != nil {
+			return err
+		}
+		container, err := getContainer(context)
+		if err != nil {
+			return err
+		}
+		return container.AddDevice(dev)
+	},
+}
+
+var deviceRemoveCommand = cli.Command{
+	Name:  "remove",
+	Usage: "remove a device node from a running container",
+	ArgsUsage: `<container-id> <path>
+
+Where "<container-id>" is the name for the instance of the container and
+"<path>" is the path of the device node inside the container.`,
+	Description: `The device remove command removes the device node from the container, denies
+access to it in the container's devices cgroup and removes it from the
+container's state. The default devices, such as /dev/null, cannot be removed.`,
+	Action: func(context *cli.Context) error {
+		if err := checkArgs(context, 2, exactArgs); err != nil {
+			return err
+		}
+		container, err := getContainer(context)
+		if err != nil {
+			return err
+		}
+		return container.RemoveDevice(context.Args().Get(1))
+	},
+}
@@ -32,6 +32,11 @@ type stats struct {
 	Blkio    blkio              `json:"blkio"`
 	Hugetlb  map[string]hugetlb `json:"hugetlb"`
 	IntelRdt intelRdt           `json:"intel_rdt"`
+	CPUSet   cpuset             `json:"cpuset,omitempty"`
+}
+
+type cpuset struct {
+	Mems []uint16 `json:"mems,omitempty"`
 }
 
 type hugetlb struct {
@@ -43,6 +48,7 @@ type hugetlb struct {
 type blkioEntry struct {
 	Major uint64 `json:"major,omitempty"`
 	Minor uint64 `json:"minor,omitempty"`
+	Name  string `json:"name,omitempty"`
 	Op    string `json:"op,omitempty"`
 	Value uint64 `json:"value,omitempty"`
 }
@@ -69,17 +75,33 @@ type throttling struct {
 	ThrottledTime    uint64 `json:"throttledTime,omitempty"`
 }
 
+type burst struct {
+	Periods uint64 `json:"periods,omitempty"`
+	Time    uint64 `json:"time,omitempty"`
+}
+
+type schedStats struct {
+	// Units: nanoseconds.
+	RunTime    uint64 `json:"runTime,omitempty"`
+	WaitTime   uint64 `json:"waitTime,omitempty"`
+	Timeslices uint64 `json:"timeslices,omitempty"`
+}
+
 type cpuUsage struct {
 	// Units: nanoseconds.
-	Total  uint64   `json:"total,omitempty"`
-	Percpu []uint64 `json:"percpu,omitempty"`
-	Kernel uint64   `json:"kernel"`
-	User   uint64   `json:"user"`
+	Total        uint64   `json:"total,omitempty"`
+	Percpu       []uint64 `json:"percpu,omitempty"`
+	PercpuKernel []uint64 `json:"percpuKernel,omitempty"`
+	PercpuUser   []uint64 `json:"percpuUser,omitempty"`
+	Kernel       uint64   `json:"kernel"`
+	User         uint64   `json:"user"`
 }
 
 type cpu struct {
 	Usage      cpuUsage   `json:"usage,omitempty"`
 	Throttling throttling `json:"throttling,omitempty"`
+	Burst      burst      `json:"burst,omitempty"`
+	Sched      schedStats `json:"sched,omitempty"`
 }
 
 type memoryEntry struct {
@@ -89,6 +111,23 @@ type memoryEntry struct {
 	Failcnt uint64 `json:"failcnt"`
 }
 
+type pageStats struct {
+	Total uint64            `json:"total,omitempty"`
+	Nodes map[uint16]uint64 `json:"nodes,omitempty"`
+}
+
+type pageUsageByNUMAInner struct {
+	Total       pageStats `json:"total,omitempty"`
+	File        pageStats `json:"file,omitempty"`
+	Anon        pageStats `json:"anon,omitempty"`
+	Unevictable pageStats `json:"unevictable,omitempty"`
+}
+
+type pageUsageByNUMA struct {
+	pageUsageByNUMAInner
+	Hierarchical pageUsageByNUMAInner `json:"hierarchical,omitempty"`
+}
+
 type memory struct {
 	Cache     uint64            `json:"cache,omitempty"`
 	Usage     memoryEntry       `json:"usage,omitempty"`
@@ -96,6 +135,10 @@ type memory struct {
 	Kernel    memoryEntry       `json:"kernel,omitempty"`
 	KernelTCP memoryEntry       `json:"kernelTCP,omitempty"`
 	Raw       map[string]uint64 `json:"raw,omitempty"`
+	// Pages per NUMA node.
+	PageUsageByNUMA pageUsageByNUMA `json:"pageUsageByNUMA,omitempty"`
+	// Pages on NUMA nodes outside of cpuset.mems.
+	RemotePageUsage pageStats `json:"remotePageUsage,omitempty"`
 }
 
 type l3CacheInfo struct {
@@ -111,6 +154,19 @@ type memBwInfo struct {
 	NumClosids    uint64 `json:"num_closids,omitempty"`
 }
 
+type mbmNumaNodeStats struct {
+	// The 'mbm_total_bytes' in 'container_id' group
+	MBMTotalBytes uint64 `json:"mbm_total_bytes"`
+
+	// The 'mbm_local_bytes' in 'container_id' group
+	MBMLocalBytes uint64 `json:"mbm_local_bytes"`
+}
+
+type cmtNumaNodeStats struct {
+	// The 'llc_occupancy' in 'container_id' group
+	LLCOccupancy uint64 `json:"llc_occupancy"`
+}
+
 type intelRdt struct {
 	// The read-only L3 cache information
 	L3CacheInfo *l3CacheInfo `json:"l3_cache_info,omitempty"`
@@ -129,6 +185,12 @@ type intelRdt struct {
 
 	// The memory bandwidth schema in 'container_id' group
 	MemBwSchema string `json:"mem_bw_schema,omitempty"`
+
+	// The memory bandwidth monitoring statistics per L3 cache domain in 'container_id' group
+	MBMStats *[]mbmNumaNodeStats `json:"mbm_stats,omitempty"`
+
+	// The cache monitoring technology statistics per L3 cache domain in 'container_id' group
+	CMTStats *[]cmtNumaNodeStats `json:"cmt_stats,omitempty"`
 }
 
 var eventsCommand = cli.Command{
@@ -187,6 +249,11 @@ information is displayed once every 5 seconds.`,
 			group.Wait()
 			return nil
 		}
+		// Streaming events does not end until the container exits, so don't
+		// keep other runc processes from operating on it meanwhile.
+		if err := container.ReleaseStateLock(); err != nil {
+			return err
+		}
 		go func() {
 			for range time.Tick(context.Duration("interval")) {
 				s, err := container.Stats()
@@ -238,9 +305,16 @@ func convertLibcontainerStats(ls *libcontainer.Stats) *stats {
 	s.CPU.Usage.User = cg.CpuStats.CpuUsage.UsageInUsermode
 	s.CPU.Usage.Total = cg.CpuStats.CpuUsage.TotalUsage
 	s.CPU.Usage.Percpu = cg.CpuStats.CpuUsage.PercpuUsage
+	s.CPU.Usage.PercpuKernel = cg.CpuStats.CpuUsage.PercpuUsageInKernelmode
+	s.CPU.Usage.PercpuUser = cg.CpuStats.CpuUsage.PercpuUsageInUsermode
 	s.CPU.Throttling.Periods = cg.CpuStats.ThrottlingData.Periods
 	s.CPU.Throttling.ThrottledPeriods = cg.CpuStats.ThrottlingData.ThrottledPeriods
 	s.CPU.Throttling.ThrottledTime = cg.CpuStats.ThrottlingData.ThrottledTime
+	s.CPU.Burst.Periods = cg.CpuStats.BurstData.BurstsPeriods
+	s.CPU.Burst.Time = cg.CpuStats.BurstData.BurstTime
+	s.CPU.Sched.RunTime = cg.CpuStats.SchedStats.RunTime
+	s.CPU.Sched.WaitTime = cg.CpuStats.SchedStats.WaitTime
+	s.CPU.Sched.Timeslices = cg.CpuStats.SchedStats.Timeslices
 
 	s.Memory.Cache = cg.MemoryStats.Cache
 	s.Memory.Kernel = convertMemoryEntry(cg.MemoryStats.KernelUsage)
@@ -248,6 +322,10 @@ func convertLibcontainerStats(ls *libcontainer.Stats) *stats {
 	s.Memory.Swap = convertMemoryEntry(cg.MemoryStats.SwapUsage)
 	s.Memory.Usage = convertMemoryEntry(cg.MemoryStats.Usage)
 	s.Memory.Raw = cg.MemoryStats.Stats
+	s.Memory.PageUsageByNUMA = convertPageUsageByNUMA(cg.MemoryStats.PageUsageByNUMA)
+	s.Memory.RemotePageUsage = convertPageStats(cg.MemoryStats.PageUsageByNUMA.Total.RemotePages(cg.CPUSetStats.Mems))
+
+	s.CPUSet.Mems = cg.CPUSetStats.Mems
 
 	s.Blkio.IoServiceBytesRecursive = convertBlkioEntry(cg.BlkioStats.IoServiceBytesRecursive)
 	s.Blkio.IoServicedRecursive = convertBlkioEntry(cg.BlkioStats.IoServicedRecursive)
@@ -274,6 +352,12 @@ func convertLibcontainerStats(ls *libcontainer.Stats) *stats {
 			s.IntelRdt.MemBwSchemaRoot = is.MemBwSchemaRoot
 			s.IntelRdt.MemBwSchema = is.MemBwSchema
 		}
+		if intelrdt.IsMbmEnabled() {
+			s.IntelRdt.MBMStats = convertMBMStats(is.MBMStats)
+		}
+		if intelrdt.IsCmtEnabled() {
+			s.IntelRdt.CMTStats = convertCMTStats(is.CMTStats)
+		}
 	}
 
 	return &s
@@ -287,6 +371,29 @@ func convertHugtlb(c cgroups.HugetlbStats) hugetlb {
 	}
 }
 
+func convertPageStats(c cgroups.PageStats) pageStats {
+	return pageStats{
+		Total: c.Total,
+		Nodes: c.Nodes,
+	}
+}
+
+func convertPageUsageByNUMAInner(c cgroups.PageUsageByNUMAInner) pageUsageByNUMAInner {
+	return pageUsageByNUMAInner{
+		Total:       convertPageStats(c.Total),
+		File:        convertPageStats(c.File),
+		Anon:        convertPageStats(c.Anon),
+		Unevictable: convertPageStats(c.Unevictable),
+	}
+}
+
+func convertPageUsageByNUMA(c cgroups.PageUsageByNUMA) pageUsageByNUMA {
+	return pageUsageByNUMA{
+		pageUsageByNUMAInner: convertPageUsageByNUMAInner(c.PageUsageByNUMAInner),
+		Hierarchical:         convertPageUsageByNUMAInner(c.Hierarchical),
+	}
+}
+
 func convertMemoryEntry(c cgroups.MemoryData) memoryEntry {
 	return memoryEntry{
 		Limit:   c.Limit,
@@ -302,6 +409,7 @@ func convertBlkioEntry(c []cgroups.BlkioStatEntry) []blkioEntry {
 		out = append(out, blkioEntry{
 			Major: e.Major,
 			Minor: e.Minor,
+			Name:  e.Name,
 			Op:    e.Op,
 			Value: e.Value,
 		})
@@ -325,3 +433,30 @@ func convertMemBwInfo(i *intelrdt.MemBwInfo) *memBwInfo {
 		NumClosids:    i.NumClosids,
 	}
 }
+
+func convertMBMStats(s *[]intelrdt.MBMNumaNodeStats) *[]mbmNumaNodeStats {
+	if s == nil {
+		return nil
+	}
+	mbmStats := make([]mbmNumaNodeStats, len(*s))
+	for i, stats := range *s {
+		mbmStats[i] = mbmNumaNodeStats{
+			MBMTotalBytes: stats.MBMTotalBytes,
+			MBMLocalBytes: stats.MBMLocalBytes,
+		}
+	}
+	return &mbmStats
+}
+
+func convertCMTStats(s *[]intelrdt.CMTNumaNodeStats) *[]cmtNumaNodeStats {
+	if s == nil {
+		return nil
+	}
+	cmtStats := make([]cmtNumaNodeStats, len(*s))
+	for i, stats := range *s {
+		cmtStats[i] = cmtNumaNodeStats{
+			LLCOccupancy: stats.LLCOccupancy,
+		}
+	}
+	return &cmtStats
+}
@@ -10,6 +10,7 @@ import (
 	"strings"
 
 	"github.com/opencontainers/runc/libcontainer"
+	"github.com/opencontainers/runc/libcontainer/configs"
 	"github.com/opencontainers/runc/libcontainer/utils"
 	"github.com/opencontainers/runtime-spec/specs-go"
 	"github.com/urfave/cli"
@@ -84,6 +85,42 @@ following will output a list of processes running in the container:
 			Value: &cli.StringSlice{},
 			Usage: "add a capability to the bounding set for the process",
 		},
+		cli.StringFlag{
+			Name:  "sched-policy",
+			Usage: "set the scheduling policy of the process (other, fifo, rr, batch, idle or deadline)",
+		},
+		cli.IntFlag{
+			Name:  "sched-nice",
+			Usage: "set the nice value of the process with the other or batch policy",
+		},
+		cli.IntFlag{
+			Name:  "sched-priority",
+			Usage: "set the static priority of the process with the fifo or rr policy",
+		},
+		cli.DurationFlag{
+			Name:  "sched-runtime",
+			Usage: "set the runtime of the process with the deadline policy",
+		},
+		cli.DurationFlag{
+			Name:  "sched-deadline",
+			Usage: "set the deadline of the process with the deadline policy",
+		},
+		cli.DurationFlag{
+			Name:  "sched-period",
+			Usage: "set the period of the process with the deadline policy",
+		},
+		cli.StringFlag{
+			Name:  "ioprio-class",
+			Usage: "set the I/O scheduling class of the process (rt, be or idle)",
+		},
+		cli.IntFlag{
+			Name:  "ioprio-level",
+			Usage: "set the I/O priority of the process within its class, from 0 (highest) to 7",
+		},
+		cli.StringFlag{
+			Name:  "cpu-affinity",
+			Usage: "set the initial list of CPUs the process may run on, e.g. 0-3,7",
+		},
 		cli.BoolFlag{
 			Name:   "no-subreaper",
 			Usage:  "disable the use of the subreaper used to reap reparented processes",
@@ -132,6 +169,10 @@ func execProcess(context *cli.Context) (int, error) {
 	if err != nil {
 		return -1, err
 	}
+	scheduler, ioPriority, err := getProcessScheduling(context)
+	if err != nil {
+		return -1, err
+	}
 	r := &runner{
 		enableSubreaper: false,
 		shouldDestroy:   false,
@@ -141,10 +182,54 @@ func execProcess(context *cli.Context) (int, error) {
 		pidFile:         context.String("pid-file"),
 		action:          CT_ACT_RUN,
 		init:            false,
+		scheduler:       scheduler,
+		ioPriority:      ioPriority,
+		cpuAffinity:     context.String("cpu-affinity"),
 	}
 	return r.run(p)
 }
 
+// getProcessScheduling returns the scheduling policy and I/O priority set by
+// the command line flags, or nil if they are not set. The policy and class
+// may be given with or without their SCHED_ and IOPRIO_CLASS_ prefixes.
+func getProcessScheduling(context *cli.Context) (*configs.Scheduler, *configs.IOPriority, error) {
+	var scheduler *configs.Scheduler
+	if policy := context.String("sched-policy"); policy != "" {
+		policy = strings.ToUpper(policy)
+		if !strings.HasPrefix(policy, "SCHED_") {
+			policy = "SCHED_" + policy
+		}
+		scheduler = &configs.Scheduler{
+			Policy:   policy,
+			Nice:     int32(context.Int("sched-nice")),
+			Priority: int32(context.Int("sched-priority")),
+			Runtime:  uint64(context.Duration("sched-runtime")),
+			Deadline: uint64(context.Duration("sched-deadline")),
+			Period:   uint64(context.Duration("sched-period")),
+		}
+	} else {
+		for _, name := range []string{"sched-nice", "sched-priority", "sched-runtime", "sched-deadline", "sched-period"} {
+			if context.IsSet(name) {
+				return nil, nil, fmt.Errorf("--%s requires --sched-policy", name)
+			}
+		}
+	}
+	var ioPriority *configs.IOPriority
+	if class := context.String("ioprio-class"); class != "" {
+		class = strings.ToUpper(class)
+		if !strings.HasPrefix(class, "IOPRIO_CLASS_") {
+			class = "IOPRIO_CLASS_" + class
+		}
+		ioPriority = &configs.IOPriority{
+			Class:    class,
+			Priority: context.Int("ioprio-level"),
+		}
+	} else if context.IsSet("ioprio-level") {
+		return nil, nil, fmt.Errorf("--ioprio-level requires --ioprio-class")
+	}
+	return scheduler, ioPriority, nil
+}
+
 func getProcess(context *cli.Context, bundle string) (*specs.Process, error) {
 	if path := context.String("process"); path != "" {
 		f, err := os.Open(path)
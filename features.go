@@ -0,0 +1,141 @@
+// +build linux
+
+package main
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/opencontainers/runc/libcontainer"
+	"github.com/opencontainers/runc/libcontainer/apparmor"
+	"github.com/opencontainers/runc/libcontainer/cgroups"
+	"github.com/opencontainers/runc/libcontainer/cgroups/systemd"
+	"github.com/opencontainers/runc/libcontainer/intelrdt"
+	"github.com/opencontainers/runc/libcontainer/landlock"
+	"github.com/opencontainers/runc/libcontainer/seccomp"
+	"github.com/opencontainers/runc/libcontainer/specconv"
+	"github.com/opencontainers/runtime-spec/specs-go"
+	selinux "github.com/opencontainers/selinux/go-selinux"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+)
+
+// features is the output of the features command.
+type features struct {
+	OCIVersionMin string        `json:"ociVersionMin"`
+	OCIVersionMax string        `json:"ociVersionMax"`
+	Hooks         []string      `json:"hooks"`
+	MountOptions  []string      `json:"mountOptions"`
+	Linux         linuxFeatures `json:"linux"`
+	Annotations   []string      `json:"annotations"`
+}
+
+type linuxFeatures struct {
+	Namespaces []string               `json:"namespaces"`
+	Cgroup     cgroupFeatures         `json:"cgroup"`
+	Seccomp    seccompFeatures        `json:"seccomp"`
+	AppArmor   bool                   `json:"apparmor"`
+	SELinux    bool                   `json:"selinux"`
+	IntelRdt   intelRdtFeatures       `json:"intelRdt"`
+	Landlock   landlockFeatures       `json:"landlock"`
+	Criu       *libcontainer.CriuInfo `json:"criu,omitempty"`
+}
+
+type cgroupFeatures struct {
+	V1          bool     `json:"v1"`
+	V2          bool     `json:"v2"`
+	Systemd     bool     `json:"systemd"`
+	SystemdUser bool     `json:"systemdUser"`
+	Controllers []string `json:"controllers"`
+}
+
+type seccompFeatures struct {
+	Enabled   bool     `json:"enabled"`
+	Actions   []string `json:"actions"`
+	Operators []string `json:"operators"`
+	Archs     []string `json:"archs"`
+}
+
+type intelRdtFeatures struct {
+	Cat bool `json:"cat"`
+	Mba bool `json:"mba"`
+	Cmt bool `json:"cmt"`
+	Mbm bool `json:"mbm"`
+}
+
+type landlockFeatures struct {
+	Enabled bool `json:"enabled"`
+	ABI     int  `json:"abi,omitempty"`
+}
+
+var featuresCommand = cli.Command{
+	Name:  "features",
+	Usage: "show the features supported by runc and the host",
+	Description: `The features command reports the namespaces, cgroup controllers, seccomp
+actions and architectures, mount options, hooks, annotations and CRIU features
+supported by this build of runc on this host, as JSON.`,
+	Action: func(context *cli.Context) error {
+		if err := checkArgs(context, 0, exactArgs); err != nil {
+			return err
+		}
+		unified := cgroups.IsCgroup2UnifiedMode()
+		f := features{
+			OCIVersionMin: "1.0.0",
+			OCIVersionMax: specs.Version,
+			Hooks:         []string{"prestart", "poststart", "poststop"},
+			MountOptions:  specconv.KnownMountOptions(),
+			Annotations:   specconv.KnownAnnotations(),
+			Linux: linuxFeatures{
+				Namespaces: []string{},
+				Cgroup: cgroupFeatures{
+					// Only the v1 hierarchies are managed, the v2
+					// controller files are written where they exist.
+					V1:          !unified,
+					V2:          unified,
+					Systemd:     systemd.UseSystemd(),
+					SystemdUser: systemd.UseSystemdUser(),
+				},
+				Seccomp: seccompFeatures{
+					Enabled:   seccomp.IsEnabled(),
+					Actions:   seccomp.KnownActions(),
+					Operators: seccomp.KnownOperators(),
+					Archs:     seccomp.KnownArchs(),
+				},
+				AppArmor: apparmor.IsEnabled(),
+				SELinux:  selinux.GetEnabled(),
+				IntelRdt: intelRdtFeatures{
+					Cat: intelrdt.IsCatEnabled(),
+					Mba: intelrdt.IsMbaEnabled(),
+					Cmt: intelrdt.IsCmtEnabled(),
+					Mbm: intelrdt.IsMbmEnabled(),
+				},
+			},
+		}
+		for _, ns := range specconv.KnownNamespaces() {
+			if specconv.IsNamespaceSupported(ns) {
+				f.Linux.Namespaces = append(f.Linux.Namespaces, ns)
+			}
+		}
+		f.Linux.Cgroup.Controllers = []string{}
+		if controllers, err := cgroups.GetAllSubsystems(); err == nil {
+			f.Linux.Cgroup.Controllers = controllers
+		} else {
+			logrus.Debugf("unable to list the cgroup controllers: %v", err)
+		}
+		if abi, err := landlock.ABIVersion(); err == nil {
+			f.Linux.Landlock = landlockFeatures{Enabled: true, ABI: abi}
+		}
+		// CRIU is optional, it is only needed to checkpoint and restore.
+		if info, err := libcontainer.GetCriuInfo(context.GlobalString("criu")); err == nil {
+			f.Linux.Criu = info
+		} else {
+			logrus.Debugf("unable to check the CRIU features: %v", err)
+		}
+		data, err := json.MarshalIndent(f, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(data)
+		return err
+	},
+}
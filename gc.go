@@ -0,0 +1,62 @@
+// +build linux
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/opencontainers/runc/libcontainer"
+	"github.com/urfave/cli"
+)
+
+var gcCommand = cli.Command{
+	Name:  "gc",
+	Usage: "remove dead containers and the resources they leaked",
+	Description: `The gc command destroys all containers whose init process has exited, or
+whose init process pid has been reused, including containers which were only
+partially created because runc or the host crashed, whose processes are killed
+and whose cgroups are removed with them. It also removes the
+cgroups and Intel RDT groups which could not be removed when deleting
+containers. Containers in use by other runc processes are left alone.
+
+The collected containers are reported as a JSON array.`,
+	Flags: []cli.Flag{
+		cli.BoolFlag{
+			Name:  "dry-run",
+			Usage: "report the containers to be collected without removing them",
+		},
+	},
+	Action: func(context *cli.Context) error {
+		if err := checkArgs(context, 0, exactArgs); err != nil {
+			return err
+		}
+		factory, err := loadFactory(context)
+		if err != nil {
+			return err
+		}
+		results, err := factory.GarbageCollect(context.Bool("dry-run"))
+		if err != nil {
+			return err
+		}
+		if results == nil {
+			results = []libcontainer.GCResult{}
+		}
+		data, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			return err
+		}
+		os.Stdout.Write(data)
+		failed := 0
+		for _, r := range results {
+			if r.Error != "" {
+				failed++
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("failed to remove %d containers", failed)
+		}
+		return nil
+	},
+}
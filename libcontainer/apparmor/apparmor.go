@@ -3,11 +3,19 @@
 package apparmor
 
 import (
+	"bufio"
+	"bytes"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
 )
 
+// securityfsDir is the AppArmor interface of securityfs.
+const securityfsDir = "/sys/kernel/security/apparmor"
+
 // IsEnabled returns true if apparmor is enabled for the host.
 func IsEnabled() bool {
 	if _, err := os.Stat("/sys/kernel/security/apparmor"); err == nil && os.Getenv("container") == "" {
@@ -52,3 +60,90 @@ func ApplyProfile(name string) error {
 
 	return changeOnExec(name)
 }
+
+// IsLoaded returns true if the profile with the specified name is loaded.
+func IsLoaded(name string) (bool, error) {
+	f, err := os.Open(filepath.Join(securityfsDir, "profiles"))
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		// Each line is the name of a profile followed by its mode, e.g.
+		// "runc-default (enforce)".
+		line := s.Text()
+		if i := strings.LastIndex(line, " ("); i >= 0 {
+			line = line[:i]
+		}
+		if line == name {
+			return true, nil
+		}
+	}
+	return false, s.Err()
+}
+
+// LoadProfile loads the profile, which must define the single profile name,
+// into the kernel. A loaded profile is never replaced, loading fails if a
+// profile of the same name is loaded. Text profiles are compiled by
+// apparmor_parser. Profiles which were compiled ahead of time are written to
+// securityfs directly if apparmor_parser is not available.
+func LoadProfile(name string, profile []byte) error {
+	parser, perr := exec.LookPath("apparmor_parser")
+	if !isBinaryPolicy(profile) {
+		if perr != nil {
+			return fmt.Errorf("apparmor failed to load profile: apparmor_parser is required to compile it")
+		}
+		out, err := runParser(parser, profile, "-N")
+		if err != nil {
+			return err
+		}
+		if err := checkProfileNames(name, strings.Fields(string(out))); err != nil {
+			return err
+		}
+		_, err = runParser(parser, profile, "-Ka")
+		return err
+	}
+	names, err := binaryProfileNames(profile)
+	if err != nil {
+		return err
+	}
+	if err := checkProfileNames(name, names); err != nil {
+		return err
+	}
+	if perr == nil {
+		_, err = runParser(parser, profile, "-KaB")
+		return err
+	}
+	return writeSecurityfs(".load", profile)
+}
+
+// runParser runs apparmor_parser with args on the profile.
+func runParser(parser string, profile []byte, args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command(parser, args...)
+	cmd.Stdin = bytes.NewReader(profile)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("apparmor failed to load profile: %v: %s", err, stderr.String())
+	}
+	return out, nil
+}
+
+// UnloadProfile removes the profile with the specified name from the kernel.
+func UnloadProfile(name string) error {
+	return writeSecurityfs(".remove", []byte(name))
+}
+
+func writeSecurityfs(file string, data []byte) error {
+	f, err := os.OpenFile(filepath.Join(securityfsDir, file), os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := f.Write(data); err != nil {
+		return fmt.Errorf("apparmor failed to write %s: %v", file, err)
+	}
+	return nil
+}
@@ -18,3 +18,15 @@ func ApplyProfile(name string) error {
 	}
 	return nil
 }
+
+func IsLoaded(name string) (bool, error) {
+	return false, ErrApparmorNotEnabled
+}
+
+func LoadProfile(name string, profile []byte) error {
+	return ErrApparmorNotEnabled
+}
+
+func UnloadProfile(name string) error {
+	return ErrApparmorNotEnabled
+}
@@ -0,0 +1,59 @@
+package apparmor
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// binaryPolicyHeader is the start of a policy compiled by apparmor_parser.
+var binaryPolicyHeader = []byte("\x04\x08\x00version\x00")
+
+// binaryProfileMarker starts each profile of a compiled policy. It is the
+// name "profile" of the struct holding the profile, which is followed by the
+// name of the profile as a string.
+var binaryProfileMarker = []byte("\x04\x08\x00profile\x00\x07")
+
+// isBinaryPolicy returns true if policy was compiled by apparmor_parser.
+func isBinaryPolicy(policy []byte) bool {
+	return bytes.HasPrefix(policy, binaryPolicyHeader)
+}
+
+// binaryProfileNames returns the names of the profiles of a compiled policy.
+func binaryProfileNames(policy []byte) ([]string, error) {
+	var names []string
+	for {
+		i := bytes.Index(policy, binaryProfileMarker)
+		if i < 0 {
+			return names, nil
+		}
+		policy = policy[i+len(binaryProfileMarker):]
+		// A string has a type byte and a little-endian 16-bit size,
+		// which includes the terminating NUL.
+		if len(policy) < 3 || policy[0] != 0x05 {
+			return nil, fmt.Errorf("invalid compiled profile")
+		}
+		size := int(binary.LittleEndian.Uint16(policy[1:3]))
+		if size == 0 || len(policy) < 3+size || policy[2+size] != 0 {
+			return nil, fmt.Errorf("invalid compiled profile")
+		}
+		names = append(names, string(policy[3:2+size]))
+		policy = policy[3+size:]
+	}
+}
+
+// checkProfileNames checks that the profiles of a policy, whose names are
+// given, are the single profile name, and optionally its child profiles.
+func checkProfileNames(name string, names []string) error {
+	var top []string
+	for _, n := range names {
+		if !strings.Contains(n, "//") {
+			top = append(top, n)
+		}
+	}
+	if len(top) != 1 || top[0] != name {
+		return fmt.Errorf("apparmor profile must define the single profile %q, it defines %q", name, top)
+	}
+	return nil
+}
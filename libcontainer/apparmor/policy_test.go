@@ -0,0 +1,60 @@
+package apparmor
+
+import (
+	"testing"
+)
+
+// compiledProfile returns a minimal compiled policy of the named profiles.
+func compiledProfile(names ...string) []byte {
+	policy := append([]byte(nil), binaryPolicyHeader...)
+	policy = append(policy, 0x02, 0x05, 0x00, 0x00, 0x00)
+	for _, name := range names {
+		policy = append(policy, binaryProfileMarker...)
+		size := len(name) + 1
+		policy = append(policy, 0x05, byte(size), byte(size>>8))
+		policy = append(policy, name...)
+		policy = append(policy, 0x00, 0x08)
+	}
+	return policy
+}
+
+func TestBinaryProfileNames(t *testing.T) {
+	policy := compiledProfile("runc-test", "runc-test//hat")
+	if !isBinaryPolicy(policy) {
+		t.Fatal("expected a compiled policy")
+	}
+	names, err := binaryProfileNames(policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 2 || names[0] != "runc-test" || names[1] != "runc-test//hat" {
+		t.Fatalf("unexpected names %q", names)
+	}
+	if _, err := binaryProfileNames(policy[:len(policy)-8]); err == nil {
+		t.Fatal("expected an error for a truncated policy")
+	}
+	if isBinaryPolicy([]byte("profile runc-test {}")) {
+		t.Fatal("expected a text policy")
+	}
+}
+
+func TestCheckProfileNames(t *testing.T) {
+	for _, names := range [][]string{
+		{"runc-test"},
+		{"runc-test", "runc-test//hat"},
+	} {
+		if err := checkProfileNames("runc-test", names); err != nil {
+			t.Errorf("%q: %v", names, err)
+		}
+	}
+	for _, names := range [][]string{
+		nil,
+		{"other"},
+		{"runc-test", "other"},
+		{"runc-test", "runc-test"},
+	} {
+		if err := checkProfileNames("runc-test", names); err == nil {
+			t.Errorf("%q: expected an error", names)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package apparmor
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"text/template"
+)
+
+// profileDir is the directory of the includes of the default profile.
+const profileDir = "/etc/apparmor.d"
+
+const defaultProfileTemplate = `
+{{range $value := .Imports}}
+{{$value}}
+{{end}}
+
+profile {{.Name}} flags=(attach_disconnected,mediate_deleted) {
+{{range $value := .InnerImports}}
+  {{$value}}
+{{end}}
+
+  network,
+  capability,
+  file,
+  umount,
+
+  # Host (unconfined) processes may send signals to container processes.
+  signal (receive) peer=unconfined,
+  # Container processes may send signals amongst themselves.
+  signal (send,receive) peer={{.Name}},
+
+  deny @{PROC}/* w,   # deny write for all files directly in /proc (not in a subdir)
+  # deny write to files not in /proc/<number>/** or /proc/sys/**
+  deny @{PROC}/{[^1-9],[^1-9][^0-9],[^1-9s][^0-9y][^0-9s],[^1-9][^0-9][^0-9][^0-9/]*}/** w,
+  deny @{PROC}/sys/[^k]** w,  # deny /proc/sys except /proc/sys/k* (effectively /proc/sys/kernel)
+  deny @{PROC}/sys/kernel/{?,??,[^s][^h][^m]**} w,  # deny everything except shm* in /proc/sys/kernel/
+  deny @{PROC}/sysrq-trigger rwklx,
+  deny @{PROC}/kcore rwklx,
+
+  deny mount,
+
+  deny /sys/[^f]*/** wklx,
+  deny /sys/f[^s]*/** wklx,
+  deny /sys/fs/[^c]*/** wklx,
+  deny /sys/fs/c[^g]*/** wklx,
+  deny /sys/fs/cg[^r]*/** wklx,
+  deny /sys/firmware/** rwklx,
+  deny /sys/kernel/security/** rwklx,
+
+  # suppress ptrace denials when using 'ps' inside a container
+  ptrace (trace,read,tracedby,readby) peer={{.Name}},
+}
+`
+
+type profileData struct {
+	Name         string
+	Imports      []string
+	InnerImports []string
+}
+
+// DefaultProfile generates the default profile for containers with the given
+// name, similar to the default profile of most container engines. The
+// includes of the host's AppArmor configuration are only used if they exist.
+func DefaultProfile(name string) ([]byte, error) {
+	t, err := template.New("apparmor_profile").Parse(defaultProfileTemplate)
+	if err != nil {
+		return nil, err
+	}
+	data := profileData{Name: name}
+	if _, err := os.Stat(filepath.Join(profileDir, "tunables/global")); err == nil {
+		data.Imports = append(data.Imports, "#include <tunables/global>")
+	} else {
+		data.Imports = append(data.Imports, "@{PROC}=/proc/")
+	}
+	if _, err := os.Stat(filepath.Join(profileDir, "abstractions/base")); err == nil {
+		data.InnerImports = append(data.InnerImports, "#include <abstractions/base>")
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,22 @@
+package apparmor
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDefaultProfile(t *testing.T) {
+	profile, err := DefaultProfile("runc-default-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		"profile runc-default-test flags=(attach_disconnected,mediate_deleted) {",
+		"signal (send,receive) peer=runc-default-test,",
+		"deny mount,",
+	} {
+		if !strings.Contains(string(profile), s) {
+			t.Errorf("expected the profile to contain %q:\n%s", s, profile)
+		}
+	}
+}
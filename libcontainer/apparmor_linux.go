@@ -0,0 +1,148 @@
+// +build linux
+
+package libcontainer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/opencontainers/runc/libcontainer/apparmor"
+	"golang.org/x/sys/unix"
+)
+
+// appArmorRefsSuffix is the suffix of the files next to the state directories
+// which record the containers using an AppArmor profile loaded by runc.
+const appArmorRefsSuffix = ".apparmor"
+
+// appArmorRefsPath returns the file in the root directory of the factory
+// which records the containers using the AppArmor profile name.
+func appArmorRefsPath(root, name string) string {
+	return filepath.Join(root, fmt.Sprintf("%x%s", name, appArmorRefsSuffix))
+}
+
+// updateAppArmorRefs calls fn with the containers recorded as users of the
+// AppArmor profile name, while no other process can change them, and records
+// the ones it returns. The record is removed if none are left.
+func updateAppArmorRefs(root, name string, fn func(ids []string) ([]string, error)) error {
+	path := appArmorRefsPath(root, name)
+	for {
+		f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+		if err != nil {
+			return err
+		}
+		if err := flock(f, unix.LOCK_EX, 0); err != nil {
+			f.Close()
+			return err
+		}
+		// The process we waited for may have removed the record.
+		locked, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return err
+		}
+		if current, err := os.Stat(path); err != nil || !os.SameFile(locked, current) {
+			f.Close()
+			continue
+		}
+		err = updateLockedAppArmorRefs(f, fn)
+		f.Close()
+		return err
+	}
+}
+
+func updateLockedAppArmorRefs(f *os.File, fn func(ids []string) ([]string, error)) error {
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		return err
+	}
+	var ids []string
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &ids); err != nil {
+			return err
+		}
+	}
+	ids, err = fn(ids)
+	if err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return os.Remove(f.Name())
+	}
+	if data, err = json.Marshal(ids); err != nil {
+		return err
+	}
+	if err := f.Truncate(0); err != nil {
+		return err
+	}
+	_, err = f.WriteAt(data, 0)
+	return err
+}
+
+// liveAppArmorRefs returns the ids of the containers, other than id, whose
+// state directories in root still exist.
+func liveAppArmorRefs(root string, ids []string, id string) []string {
+	var live []string
+	for _, other := range ids {
+		if other == id {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(root, other)); err == nil {
+			live = append(live, other)
+		}
+	}
+	return live
+}
+
+// loadAppArmorProfile loads the AppArmor profile of the config if it is not
+// loaded yet, and records the container as a user of the profile if it was
+// loaded by runc. The profile is unloaded when the last container using it
+// is destroyed.
+func (c *linuxContainer) loadAppArmorProfile() error {
+	if c.config.AppArmorProfileContent == "" {
+		return nil
+	}
+	name := c.config.AppArmorProfile
+	root := filepath.Dir(c.root)
+	used := false
+	err := updateAppArmorRefs(root, name, func(ids []string) ([]string, error) {
+		loaded, err := apparmor.IsLoaded(name)
+		if err != nil {
+			return nil, err
+		}
+		if loaded && len(ids) == 0 {
+			// The profile was loaded by someone else, who owns it.
+			return nil, nil
+		}
+		if !loaded {
+			if err := apparmor.LoadProfile(name, []byte(c.config.AppArmorProfileContent)); err != nil {
+				return nil, err
+			}
+		}
+		used = true
+		return append(liveAppArmorRefs(root, ids, c.id), c.id), nil
+	})
+	if err != nil {
+		return err
+	}
+	if used {
+		c.appArmorProfile = name
+	}
+	return nil
+}
+
+// releaseAppArmorProfile removes the container id from the users of the
+// AppArmor profile name, which is unloaded if no other container uses it.
+func releaseAppArmorProfile(root, name, id string) error {
+	return updateAppArmorRefs(root, name, func(ids []string) ([]string, error) {
+		ids = liveAppArmorRefs(root, ids, id)
+		if len(ids) == 0 {
+			if err := apparmor.UnloadProfile(name); err != nil {
+				return nil, err
+			}
+		}
+		return ids, nil
+	})
+}
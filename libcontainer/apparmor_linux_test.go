@@ -0,0 +1,57 @@
+// +build linux
+
+package libcontainer
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestAppArmorRefs(t *testing.T) {
+	root, err := newTestRoot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	for _, id := range []string{"1", "2"} {
+		if err := os.Mkdir(filepath.Join(root, id), 0700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	add := func(id string) func([]string) ([]string, error) {
+		return func(ids []string) ([]string, error) {
+			return append(liveAppArmorRefs(root, ids, id), id), nil
+		}
+	}
+	// "3" has been destroyed without releasing the profile.
+	for _, id := range []string{"3", "1", "2"} {
+		if err := updateAppArmorRefs(root, "runc-test", add(id)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var got []string
+	if err := updateAppArmorRefs(root, "runc-test", func(ids []string) ([]string, error) {
+		got = ids
+		return liveAppArmorRefs(root, ids, "1"), nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"1", "2"}; !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected the users %v, got %v", expected, got)
+	}
+	if err := updateAppArmorRefs(root, "runc-test", func(ids []string) ([]string, error) {
+		got = ids
+		return liveAppArmorRefs(root, ids, "2"), nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"2"}; !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected the users %v, got %v", expected, got)
+	}
+	// The record of a profile without users is removed.
+	if _, err := os.Stat(appArmorRefsPath(root, "runc-test")); !os.IsNotExist(err) {
+		t.Fatalf("expected the record to be removed, got %v", err)
+	}
+}
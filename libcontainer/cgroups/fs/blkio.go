@@ -70,9 +70,35 @@ func (s *BlkioGroup) Set(path string, cgroup *configs.Cgroup) error {
 		}
 	}
 
+	// The io.latency and io.cost controllers only exist on the unified
+	// hierarchy, their settings are written where the cgroup provides their
+	// files and rejected otherwise.
+	for _, ld := range cgroup.Resources.BlkioLatencyTargetDevice {
+		if err := writeIoFile(path, "io.latency", ld.String()); err != nil {
+			return err
+		}
+	}
+	if cgroup.Resources.IoCostWeight != 0 {
+		if err := writeIoFile(path, "io.weight", "default "+strconv.FormatUint(uint64(cgroup.Resources.IoCostWeight), 10)); err != nil {
+			return err
+		}
+	}
+	for _, wd := range cgroup.Resources.IoCostWeightDevice {
+		if err := writeIoFile(path, "io.weight", wd.WeightString()); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
+func writeIoFile(path, file, data string) error {
+	if !cgroups.PathExists(filepath.Join(path, file)) {
+		return fmt.Errorf("%s is not supported by the blkio cgroup, it requires cgroup v2", file)
+	}
+	return writeFile(path, file, data)
+}
+
 func (s *BlkioGroup) Remove(d *cgroupData) error {
 	return removePath(d.path("blkio"))
 }
@@ -165,58 +191,110 @@ func getBlkioStat(path string) ([]cgroups.BlkioStatEntry, error) {
 }
 
 func (s *BlkioGroup) GetStats(path string, stats *cgroups.Stats) error {
+	if err := getBlkioStats(path, stats); err != nil {
+		return err
+	}
+	return setBlkioDeviceNames(&stats.BlkioStats, "/sys/dev/block")
+}
+
+func getBlkioStats(path string, stats *cgroups.Stats) error {
 	// Try to read CFQ stats available on all CFQ enabled kernels first
 	if blkioStats, err := getBlkioStat(filepath.Join(path, "blkio.io_serviced_recursive")); err == nil && blkioStats != nil {
 		return getCFQStats(path, stats)
 	}
+	// BFQ, which replaces CFQ on multi-queue kernels, provides the same
+	// files with a "blkio.bfq." prefix. Only some of them are available if
+	// the kernel is built without CONFIG_BFQ_CGROUP_DEBUG.
+	if blkioStats, err := getBlkioStat(filepath.Join(path, "blkio.bfq.io_serviced_recursive")); err == nil && blkioStats != nil {
+		return getBFQStats(path, stats)
+	}
 	return getStats(path, stats) // Use generic stats as fallback
 }
 
 func getCFQStats(path string, stats *cgroups.Stats) error {
-	var blkioStats []cgroups.BlkioStatEntry
-	var err error
-
-	if blkioStats, err = getBlkioStat(filepath.Join(path, "blkio.sectors_recursive")); err != nil {
-		return err
-	}
-	stats.BlkioStats.SectorsRecursive = blkioStats
-
-	if blkioStats, err = getBlkioStat(filepath.Join(path, "blkio.io_service_bytes_recursive")); err != nil {
-		return err
-	}
-	stats.BlkioStats.IoServiceBytesRecursive = blkioStats
-
-	if blkioStats, err = getBlkioStat(filepath.Join(path, "blkio.io_serviced_recursive")); err != nil {
-		return err
-	}
-	stats.BlkioStats.IoServicedRecursive = blkioStats
+	return getRecursiveStats(path, "blkio.", stats)
+}
 
-	if blkioStats, err = getBlkioStat(filepath.Join(path, "blkio.io_queued_recursive")); err != nil {
-		return err
-	}
-	stats.BlkioStats.IoQueuedRecursive = blkioStats
+func getBFQStats(path string, stats *cgroups.Stats) error {
+	return getRecursiveStats(path, "blkio.bfq.", stats)
+}
 
-	if blkioStats, err = getBlkioStat(filepath.Join(path, "blkio.io_service_time_recursive")); err != nil {
-		return err
+// getRecursiveStats reads the "*_recursive" stats files of the IO scheduler
+// whose files start with prefix.
+func getRecursiveStats(path, prefix string, stats *cgroups.Stats) error {
+	for _, f := range []struct {
+		file  string
+		stats *[]cgroups.BlkioStatEntry
+	}{
+		{"sectors_recursive", &stats.BlkioStats.SectorsRecursive},
+		{"io_service_bytes_recursive", &stats.BlkioStats.IoServiceBytesRecursive},
+		{"io_serviced_recursive", &stats.BlkioStats.IoServicedRecursive},
+		{"io_queued_recursive", &stats.BlkioStats.IoQueuedRecursive},
+		{"io_service_time_recursive", &stats.BlkioStats.IoServiceTimeRecursive},
+		{"io_wait_time_recursive", &stats.BlkioStats.IoWaitTimeRecursive},
+		{"io_merged_recursive", &stats.BlkioStats.IoMergedRecursive},
+		{"time_recursive", &stats.BlkioStats.IoTimeRecursive},
+	} {
+		blkioStats, err := getBlkioStat(filepath.Join(path, prefix+f.file))
+		if err != nil {
+			return err
+		}
+		*f.stats = blkioStats
 	}
-	stats.BlkioStats.IoServiceTimeRecursive = blkioStats
+	return nil
+}
 
-	if blkioStats, err = getBlkioStat(filepath.Join(path, "blkio.io_wait_time_recursive")); err != nil {
-		return err
+// setBlkioDeviceNames sets the name of the block device of each entry of
+// stats, looked up in sysDevBlock ("/sys/dev/block"). Entries whose device
+// cannot be found, e.g. because it has been removed, are left without name.
+func setBlkioDeviceNames(stats *cgroups.BlkioStats, sysDevBlock string) error {
+	names := make(map[string]string)
+	for _, entries := range [][]cgroups.BlkioStatEntry{
+		stats.IoServiceBytesRecursive,
+		stats.IoServicedRecursive,
+		stats.IoQueuedRecursive,
+		stats.IoServiceTimeRecursive,
+		stats.IoWaitTimeRecursive,
+		stats.IoMergedRecursive,
+		stats.IoTimeRecursive,
+		stats.SectorsRecursive,
+	} {
+		for i := range entries {
+			dev := fmt.Sprintf("%d:%d", entries[i].Major, entries[i].Minor)
+			name, ok := names[dev]
+			if !ok {
+				var err error
+				name, err = getBlockDeviceName(filepath.Join(sysDevBlock, dev))
+				if err != nil {
+					return err
+				}
+				names[dev] = name
+			}
+			entries[i].Name = name
+		}
 	}
-	stats.BlkioStats.IoWaitTimeRecursive = blkioStats
+	return nil
+}
 
-	if blkioStats, err = getBlkioStat(filepath.Join(path, "blkio.io_merged_recursive")); err != nil {
-		return err
+// getBlockDeviceName returns the DEVNAME of the block device at devPath,
+// or an empty string if there is no such device.
+func getBlockDeviceName(devPath string) (string, error) {
+	f, err := os.Open(filepath.Join(devPath, "uevent"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
 	}
-	stats.BlkioStats.IoMergedRecursive = blkioStats
+	defer f.Close()
 
-	if blkioStats, err = getBlkioStat(filepath.Join(path, "blkio.time_recursive")); err != nil {
-		return err
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if name := strings.TrimPrefix(sc.Text(), "DEVNAME="); name != sc.Text() {
+			return name, nil
+		}
 	}
-	stats.BlkioStats.IoTimeRecursive = blkioStats
-
-	return nil
+	return "", sc.Err()
 }
 
 func getStats(path string, stats *cgroups.Stats) error {
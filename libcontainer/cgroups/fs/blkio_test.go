@@ -3,7 +3,11 @@
 package fs
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/opencontainers/runc/libcontainer/cgroups"
@@ -634,3 +638,142 @@ func TestBlkioSetThrottleWriteIOpsDevice(t *testing.T) {
 		t.Fatal("Got the wrong value, set blkio.throttle.write_iops_device failed.")
 	}
 }
+
+func TestBFQBlkioStats(t *testing.T) {
+	helper := NewCgroupTestUtil("blkio", t)
+	defer helper.cleanup()
+	helper.writeFileContents(map[string]string{
+		"blkio.io_serviced_recursive":          "",
+		"blkio.bfq.io_service_bytes_recursive": serviceBytesRecursiveContents,
+		"blkio.bfq.io_serviced_recursive":      servicedRecursiveContents,
+		"blkio.bfq.sectors_recursive":          sectorsRecursiveContents,
+	})
+
+	blkio := &BlkioGroup{}
+	actualStats := *cgroups.NewStats()
+	if err := blkio.GetStats(helper.CgroupPath, &actualStats); err != nil {
+		t.Fatal(err)
+	}
+
+	// The debug stats are missing without CONFIG_BFQ_CGROUP_DEBUG.
+	expectedStats := cgroups.BlkioStats{}
+	appendBlkioStatEntry(&expectedStats.SectorsRecursive, 8, 0, 1024, "")
+
+	appendBlkioStatEntry(&expectedStats.IoServiceBytesRecursive, 8, 0, 100, "Read")
+	appendBlkioStatEntry(&expectedStats.IoServiceBytesRecursive, 8, 0, 200, "Write")
+	appendBlkioStatEntry(&expectedStats.IoServiceBytesRecursive, 8, 0, 300, "Sync")
+	appendBlkioStatEntry(&expectedStats.IoServiceBytesRecursive, 8, 0, 500, "Async")
+	appendBlkioStatEntry(&expectedStats.IoServiceBytesRecursive, 8, 0, 500, "Total")
+
+	appendBlkioStatEntry(&expectedStats.IoServicedRecursive, 8, 0, 10, "Read")
+	appendBlkioStatEntry(&expectedStats.IoServicedRecursive, 8, 0, 40, "Write")
+	appendBlkioStatEntry(&expectedStats.IoServicedRecursive, 8, 0, 20, "Sync")
+	appendBlkioStatEntry(&expectedStats.IoServicedRecursive, 8, 0, 30, "Async")
+	appendBlkioStatEntry(&expectedStats.IoServicedRecursive, 8, 0, 50, "Total")
+
+	expectBlkioStatsEquals(t, expectedStats, actualStats.BlkioStats)
+}
+
+func TestBlkioDeviceNames(t *testing.T) {
+	helper := NewCgroupTestUtil("blkio", t)
+	defer helper.cleanup()
+
+	sysDevBlock := filepath.Join(helper.CgroupPath, "dev", "block")
+	if err := os.MkdirAll(filepath.Join(sysDevBlock, "8:0"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(sysDevBlock, "8:0", "uevent"), []byte("MAJOR=8\nMINOR=0\nDEVNAME=sda\nDEVTYPE=disk\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := cgroups.BlkioStats{}
+	appendBlkioStatEntry(&stats.IoServiceBytesRecursive, 8, 0, 100, "Read")
+	appendBlkioStatEntry(&stats.IoServicedRecursive, 8, 0, 10, "Read")
+	appendBlkioStatEntry(&stats.IoServicedRecursive, 252, 0, 10, "Read")
+	if err := setBlkioDeviceNames(&stats, sysDevBlock); err != nil {
+		t.Fatal(err)
+	}
+
+	if name := stats.IoServiceBytesRecursive[0].Name; name != "sda" {
+		t.Fatalf("expected device name sda, got %q", name)
+	}
+	if name := stats.IoServicedRecursive[0].Name; name != "sda" {
+		t.Fatalf("expected device name sda, got %q", name)
+	}
+	if name := stats.IoServicedRecursive[1].Name; name != "" {
+		t.Fatalf("expected no name for a missing device, got %q", name)
+	}
+}
+
+func TestBlkioSetLatencyTargetDevice(t *testing.T) {
+	helper := NewCgroupTestUtil("blkio", t)
+	defer helper.cleanup()
+
+	helper.writeFileContents(map[string]string{
+		"io.latency": "",
+	})
+
+	ld := configs.NewLatencyDevice(8, 0, 10000)
+	helper.CgroupData.config.Resources.BlkioLatencyTargetDevice = []*configs.LatencyDevice{ld}
+	blkio := &BlkioGroup{}
+	if err := blkio.Set(helper.CgroupPath, helper.CgroupData.config); err != nil {
+		t.Fatal(err)
+	}
+
+	value, err := getCgroupParamString(helper.CgroupPath, "io.latency")
+	if err != nil {
+		t.Fatalf("Failed to parse io.latency - %s", err)
+	}
+	if value != "8:0 target=10000" {
+		t.Fatalf("Got the wrong value %q, set io.latency failed.", value)
+	}
+}
+
+func TestBlkioSetIoCostWeight(t *testing.T) {
+	helper := NewCgroupTestUtil("blkio", t)
+	defer helper.cleanup()
+
+	helper.writeFileContents(map[string]string{
+		"io.weight": "",
+	})
+
+	helper.CgroupData.config.Resources.IoCostWeight = 500
+	blkio := &BlkioGroup{}
+	if err := blkio.Set(helper.CgroupPath, helper.CgroupData.config); err != nil {
+		t.Fatal(err)
+	}
+
+	value, err := getCgroupParamString(helper.CgroupPath, "io.weight")
+	if err != nil {
+		t.Fatalf("Failed to parse io.weight - %s", err)
+	}
+	if value != "default 500" {
+		t.Fatalf("Got the wrong value %q, set io.weight failed.", value)
+	}
+}
+
+func TestBlkioSetIoLatencyUnsupported(t *testing.T) {
+	helper := NewCgroupTestUtil("blkio", t)
+	defer helper.cleanup()
+
+	helper.CgroupData.config.Resources.BlkioLatencyTargetDevice = []*configs.LatencyDevice{configs.NewLatencyDevice(8, 0, 10000)}
+	blkio := &BlkioGroup{}
+	err := blkio.Set(helper.CgroupPath, helper.CgroupData.config)
+	if err == nil {
+		t.Fatal("expected an error when io.latency is not available")
+	}
+	if !strings.Contains(err.Error(), "cgroup v2") {
+		t.Fatalf("expected the error to say io.latency requires cgroup v2, got %v", err)
+	}
+}
+
+func TestBlkioSetIoCostWeightUnsupported(t *testing.T) {
+	helper := NewCgroupTestUtil("blkio", t)
+	defer helper.cleanup()
+
+	helper.CgroupData.config.Resources.IoCostWeight = 500
+	blkio := &BlkioGroup{}
+	if err := blkio.Set(helper.CgroupPath, helper.CgroupData.config); err == nil {
+		t.Fatal("expected an error when io.weight is not available")
+	}
+}
@@ -111,6 +111,13 @@ func (s *CpuGroup) GetStats(path string, stats *cgroups.Stats) error {
 
 		case "throttled_time":
 			stats.CpuStats.ThrottlingData.ThrottledTime = v
+
+		// Only reported by kernels which support cpu.cfs_burst_us
+		case "nr_bursts":
+			stats.CpuStats.BurstData.BurstsPeriods = v
+
+		case "burst_time":
+			stats.CpuStats.BurstData.BurstTime = v
 		}
 	}
 	return nil
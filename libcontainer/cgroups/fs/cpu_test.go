@@ -132,6 +132,36 @@ func TestCpuStats(t *testing.T) {
 	expectThrottlingDataEquals(t, expectedStats, actualStats.CpuStats.ThrottlingData)
 }
 
+func TestCpuStatsBurst(t *testing.T) {
+	helper := NewCgroupTestUtil("cpu", t)
+	defer helper.cleanup()
+
+	const (
+		nrBursts  = 300
+		burstTime = 123456789
+	)
+
+	cpuStatContent := fmt.Sprintf("nr_periods 2000\nnr_throttled 200\nthrottled_time 1000\nnr_bursts %d\nburst_time %d\n",
+		nrBursts, burstTime)
+	helper.writeFileContents(map[string]string{
+		"cpu.stat": cpuStatContent,
+	})
+
+	cpu := &CpuGroup{}
+	actualStats := *cgroups.NewStats()
+	if err := cpu.GetStats(helper.CgroupPath, &actualStats); err != nil {
+		t.Fatal(err)
+	}
+
+	expectedStats := cgroups.BurstData{
+		BurstsPeriods: nrBursts,
+		BurstTime:     burstTime,
+	}
+	if actualStats.CpuStats.BurstData != expectedStats {
+		t.Fatalf("Expected burst data %+v, got %+v", expectedStats, actualStats.CpuStats.BurstData)
+	}
+}
+
 func TestNoCpuStatFile(t *testing.T) {
 	helper := NewCgroupTestUtil("cpu", t)
 	defer helper.cleanup()
@@ -5,6 +5,7 @@ package fs
 import (
 	"fmt"
 	"io/ioutil"
+	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
@@ -12,11 +13,13 @@ import (
 	"github.com/opencontainers/runc/libcontainer/cgroups"
 	"github.com/opencontainers/runc/libcontainer/configs"
 	"github.com/opencontainers/runc/libcontainer/system"
+	"golang.org/x/sys/unix"
 )
 
 const (
-	cgroupCpuacctStat   = "cpuacct.stat"
-	nanosecondsInSecond = 1000000000
+	cgroupCpuacctStat     = "cpuacct.stat"
+	cgroupCpuacctUsageAll = "cpuacct.usage_all"
+	nanosecondsInSecond   = 1000000000
 )
 
 var clockTicks = uint64(system.GetClockTicks())
@@ -61,10 +64,23 @@ func (s *CpuacctGroup) GetStats(path string, stats *cgroups.Stats) error {
 		return err
 	}
 
+	percpuUserUsage, percpuKernelUsage, err := getPercpuUsageBreakdown(path)
+	if err != nil {
+		return err
+	}
+
+	schedStats, err := getSchedStats(path, "/proc")
+	if err != nil {
+		return err
+	}
+
 	stats.CpuStats.CpuUsage.TotalUsage = totalUsage
 	stats.CpuStats.CpuUsage.PercpuUsage = percpuUsage
+	stats.CpuStats.CpuUsage.PercpuUsageInUsermode = percpuUserUsage
+	stats.CpuStats.CpuUsage.PercpuUsageInKernelmode = percpuKernelUsage
 	stats.CpuStats.CpuUsage.UsageInUsermode = userModeUsage
 	stats.CpuStats.CpuUsage.UsageInKernelmode = kernelModeUsage
+	stats.CpuStats.SchedStats = schedStats
 	return nil
 }
 
@@ -119,3 +135,101 @@ func getPercpuUsage(path string) ([]uint64, error) {
 	}
 	return percpuUsage, nil
 }
+
+// Returns user and kernel usage breakdown per CPU in nanoseconds, or nil if
+// the kernel does not provide cpuacct.usage_all.
+func getPercpuUsageBreakdown(path string) ([]uint64, []uint64, error) {
+	// Expected format:
+	// cpu user system
+	// 0 <user usage in ns> <system usage in ns>
+	// 1 <user usage in ns> <system usage in ns>
+	// ...
+	data, err := ioutil.ReadFile(filepath.Join(path, cgroupCpuacctUsageAll))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil, nil
+		}
+		return nil, nil, err
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if fields := strings.Fields(lines[0]); len(fields) != 3 || fields[0] != "cpu" || fields[1] != "user" || fields[2] != "system" {
+		return nil, nil, fmt.Errorf("unexpected header %q in %q", lines[0], cgroupCpuacctUsageAll)
+	}
+
+	var userUsage, kernelUsage []uint64
+	for _, line := range lines[1:] {
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			return nil, nil, fmt.Errorf("unexpected line %q in %q", line, cgroupCpuacctUsageAll)
+		}
+		user, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Unable to convert param value to uint64: %s", err)
+		}
+		kernel, err := strconv.ParseUint(fields[2], 10, 64)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Unable to convert param value to uint64: %s", err)
+		}
+		userUsage = append(userUsage, user)
+		kernelUsage = append(kernelUsage, kernel)
+	}
+	return userUsage, kernelUsage, nil
+}
+
+// Returns the scheduler statistics summed up over the tasks of the cgroup,
+// read from <procDir>/<tid>/schedstat.
+func getSchedStats(path, procDir string) (cgroups.SchedStats, error) {
+	stats := cgroups.SchedStats{}
+
+	tasks, err := readTasks(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return stats, nil
+		}
+		return stats, err
+	}
+	for _, tid := range tasks {
+		// Expected format:
+		// <run time in ns> <wait time in ns> <number of timeslices>
+		data, err := ioutil.ReadFile(filepath.Join(procDir, strconv.Itoa(tid), "schedstat"))
+		if err != nil {
+			// The task has exited in the meantime, or the kernel
+			// was built without schedstat support.
+			if pathErr, ok := err.(*os.PathError); ok && (os.IsNotExist(err) || pathErr.Err == unix.ESRCH) {
+				continue
+			}
+			return stats, err
+		}
+		fields := strings.Fields(string(data))
+		if len(fields) != 3 {
+			return stats, fmt.Errorf("unexpected format of schedstat of task %d: %q", tid, string(data))
+		}
+		var values [3]uint64
+		for i, field := range fields {
+			if values[i], err = strconv.ParseUint(field, 10, 64); err != nil {
+				return stats, fmt.Errorf("Unable to convert param value to uint64: %s", err)
+			}
+		}
+		stats.RunTime += values[0]
+		stats.WaitTime += values[1]
+		stats.Timeslices += values[2]
+	}
+	return stats, nil
+}
+
+// Returns the IDs of the tasks, i.e. threads, in the cgroup.
+func readTasks(path string) ([]int, error) {
+	data, err := ioutil.ReadFile(filepath.Join(path, "tasks"))
+	if err != nil {
+		return nil, err
+	}
+	var tasks []int
+	for _, field := range strings.Fields(string(data)) {
+		tid, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, fmt.Errorf("failure - parsing tasks of %s: %v", path, err)
+		}
+		tasks = append(tasks, tid)
+	}
+	return tasks, nil
+}
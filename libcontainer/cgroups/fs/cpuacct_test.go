@@ -0,0 +1,98 @@
+// +build linux
+
+package fs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/opencontainers/runc/libcontainer/cgroups"
+)
+
+func TestCpuacctPercpuUsageBreakdown(t *testing.T) {
+	helper := NewCgroupTestUtil("cpuacct", t)
+	defer helper.cleanup()
+
+	helper.writeFileContents(map[string]string{
+		"cpuacct.usage_all": "cpu user system\n0 962250696038415 637727786389114\n1 981956408513304 638197595421064\n",
+	})
+
+	user, kernel, err := getPercpuUsageBreakdown(helper.CgroupPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedUser := []uint64{962250696038415, 981956408513304}
+	expectedKernel := []uint64{637727786389114, 638197595421064}
+	if !reflect.DeepEqual(user, expectedUser) || !reflect.DeepEqual(kernel, expectedKernel) {
+		t.Fatalf("Expected user %v and kernel %v usage, got %v and %v", expectedUser, expectedKernel, user, kernel)
+	}
+}
+
+func TestCpuacctNoUsageAllFile(t *testing.T) {
+	helper := NewCgroupTestUtil("cpuacct", t)
+	defer helper.cleanup()
+
+	user, kernel, err := getPercpuUsageBreakdown(helper.CgroupPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user != nil || kernel != nil {
+		t.Fatalf("Expected no usage, got %v and %v", user, kernel)
+	}
+}
+
+func TestCpuacctInvalidUsageAllFile(t *testing.T) {
+	helper := NewCgroupTestUtil("cpuacct", t)
+	defer helper.cleanup()
+
+	helper.writeFileContents(map[string]string{
+		"cpuacct.usage_all": "cpu user\n0 962250696038415\n",
+	})
+
+	if _, _, err := getPercpuUsageBreakdown(helper.CgroupPath); err == nil {
+		t.Fatal("Expected failure")
+	}
+}
+
+func TestCpuacctSchedStats(t *testing.T) {
+	helper := NewCgroupTestUtil("cpuacct", t)
+	defer helper.cleanup()
+
+	procDir, err := ioutil.TempDir("", "proc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(procDir)
+
+	// Task 30 has exited and has no schedstat anymore
+	helper.writeFileContents(map[string]string{
+		"tasks": "10\n20\n30\n",
+	})
+	for tid, schedstat := range map[string]string{
+		"10": "1000 200 5\n",
+		"20": "3000 400 7\n",
+	} {
+		if err := os.MkdirAll(filepath.Join(procDir, tid), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(procDir, tid, "schedstat"), []byte(schedstat), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats, err := getSchedStats(helper.CgroupPath, procDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := cgroups.SchedStats{
+		RunTime:    4000,
+		WaitTime:   600,
+		Timeslices: 12,
+	}
+	if stats != expected {
+		t.Fatalf("Expected sched stats %+v, got %+v", expected, stats)
+	}
+}
@@ -8,6 +8,8 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 
 	"github.com/opencontainers/runc/libcontainer/cgroups"
 	"github.com/opencontainers/runc/libcontainer/configs"
@@ -48,9 +50,48 @@ func (s *CpusetGroup) Remove(d *cgroupData) error {
 }
 
 func (s *CpusetGroup) GetStats(path string, stats *cgroups.Stats) error {
+	mems, err := getCgroupParamString(path, "cpuset.mems")
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	nodes, err := parseCpusetList(mems)
+	if err != nil {
+		return fmt.Errorf("failed to parse cpuset.mems - %v", err)
+	}
+	stats.CPUSetStats.Mems = nodes
 	return nil
 }
 
+// parseCpusetList parses a list of CPUs or memory nodes in the format of
+// cpuset.cpus and cpuset.mems, e.g. "0-2,5".
+func parseCpusetList(s string) ([]uint16, error) {
+	var list []uint16
+	if s == "" {
+		return list, nil
+	}
+	for _, r := range strings.Split(s, ",") {
+		bounds := strings.SplitN(r, "-", 2)
+		start, err := strconv.ParseUint(bounds[0], 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("invalid range %q", r)
+		}
+		end := start
+		if len(bounds) == 2 {
+			end, err = strconv.ParseUint(bounds[1], 10, 16)
+			if err != nil || end < start {
+				return nil, fmt.Errorf("invalid range %q", r)
+			}
+		}
+		for i := start; i <= end; i++ {
+			list = append(list, uint16(i))
+		}
+	}
+	return list, nil
+}
+
 func (s *CpusetGroup) ApplyDir(dir string, cgroup *configs.Cgroup, pid int) error {
 	// This might happen if we have no cpuset cgroup mounted.
 	// Just do nothing and don't fail.
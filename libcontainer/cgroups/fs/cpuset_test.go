@@ -3,7 +3,10 @@
 package fs
 
 import (
+	"reflect"
 	"testing"
+
+	"github.com/opencontainers/runc/libcontainer/cgroups"
 )
 
 func TestCpusetSetCpus(t *testing.T) {
@@ -63,3 +66,31 @@ func TestCpusetSetMems(t *testing.T) {
 		t.Fatal("Got the wrong value, set cpuset.mems failed.")
 	}
 }
+
+func TestCpusetStats(t *testing.T) {
+	helper := NewCgroupTestUtil("cpuset", t)
+	defer helper.cleanup()
+
+	helper.writeFileContents(map[string]string{
+		"cpuset.mems": "0-2,5\n",
+	})
+
+	cpuset := &CpusetGroup{}
+	actualStats := *cgroups.NewStats()
+	if err := cpuset.GetStats(helper.CgroupPath, &actualStats); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []uint16{0, 1, 2, 5}
+	if !reflect.DeepEqual(expected, actualStats.CPUSetStats.Mems) {
+		t.Fatalf("Expected mems %v, got %v", expected, actualStats.CPUSetStats.Mems)
+	}
+}
+
+func TestParseCpusetList(t *testing.T) {
+	for _, s := range []string{"1-", "a", "3-1", "0,,1"} {
+		if _, err := parseCpusetList(s); err == nil {
+			t.Errorf("Expected %q to be rejected", s)
+		}
+	}
+}
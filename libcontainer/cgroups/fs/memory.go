@@ -17,6 +17,14 @@ import (
 const (
 	cgroupMemorySwapLimit = "memory.memsw.limit_in_bytes"
 	cgroupMemoryLimit     = "memory.limit_in_bytes"
+	cgroupMemorySoftLimit = "memory.soft_limit_in_bytes"
+
+	// Memory controls of the unified hierarchy, which are only used if the
+	// kernel provides them for the cgroup
+	cgroupMemoryHigh     = "memory.high"
+	cgroupMemoryLow      = "memory.low"
+	cgroupMemoryMin      = "memory.min"
+	cgroupMemoryOomGroup = "memory.oom.group"
 )
 
 type MemoryGroup struct {
@@ -126,11 +134,47 @@ func (s *MemoryGroup) Set(path string, cgroup *configs.Cgroup) error {
 	}
 
 	if cgroup.Resources.MemoryReservation != 0 {
-		if err := writeFile(path, "memory.soft_limit_in_bytes", strconv.FormatInt(cgroup.Resources.MemoryReservation, 10)); err != nil {
+		if err := writeFile(path, cgroupMemorySoftLimit, strconv.FormatInt(cgroup.Resources.MemoryReservation, 10)); err != nil {
+			return err
+		}
+	}
+
+	if cgroup.Resources.MemoryHigh != 0 {
+		if err := setMemoryHigh(path, cgroup.Resources); err != nil {
+			return err
+		}
+	}
+
+	for _, p := range []struct {
+		file  string
+		value int64
+	}{
+		{cgroupMemoryMin, cgroup.Resources.MemoryMin},
+		{cgroupMemoryLow, cgroup.Resources.MemoryLow},
+	} {
+		if p.value == 0 {
+			continue
+		}
+		if !cgroups.PathExists(filepath.Join(path, p.file)) {
+			return fmt.Errorf("%s is not supported by the memory cgroup, it requires cgroup v2", p.file)
+		}
+		if err := writeFile(path, p.file, memoryValue(p.value)); err != nil {
 			return err
 		}
 	}
 
+	if cgroups.PathExists(filepath.Join(path, cgroupMemoryOomGroup)) {
+		oomGroup := "0"
+		if cgroup.Resources.MemoryOomGroup {
+			oomGroup = "1"
+		}
+		if err := writeFile(path, cgroupMemoryOomGroup, oomGroup); err != nil {
+			return err
+		}
+	} else if cgroup.Resources.MemoryOomGroup {
+		return fmt.Errorf("%s is not supported by the memory cgroup, it requires cgroup v2", cgroupMemoryOomGroup)
+	}
+
 	if cgroup.Resources.KernelMemoryTCP != 0 {
 		if err := writeFile(path, "memory.kmem.tcp.limit_in_bytes", strconv.FormatInt(cgroup.Resources.KernelMemoryTCP, 10)); err != nil {
 			return err
@@ -154,6 +198,30 @@ func (s *MemoryGroup) Set(path string, cgroup *configs.Cgroup) error {
 	return nil
 }
 
+// memoryValue formats a memory control of the unified hierarchy, where -1
+// stands for "max".
+func memoryValue(v int64) string {
+	if v == -1 {
+		return "max"
+	}
+	return strconv.FormatInt(v, 10)
+}
+
+// setMemoryHigh sets the throttle limit of memory usage. Without a native
+// memory.high, the soft limit is set instead. Unlike memory.high, it is only
+// enforced under global memory pressure, when the memory of the cgroup above
+// it is reclaimed first. The hard limit is never lowered to force reclaim, as
+// that may OOM-kill the processes of the cgroup.
+func setMemoryHigh(path string, r *configs.Resources) error {
+	if cgroups.PathExists(filepath.Join(path, cgroupMemoryHigh)) {
+		return writeFile(path, cgroupMemoryHigh, memoryValue(r.MemoryHigh))
+	}
+	if r.MemoryReservation != 0 {
+		return fmt.Errorf("memory high and memory reservation cannot both be set, as both use %s", cgroupMemorySoftLimit)
+	}
+	return writeFile(path, cgroupMemorySoftLimit, strconv.FormatInt(r.MemoryHigh, 10))
+}
+
 func (s *MemoryGroup) Remove(d *cgroupData) error {
 	return removePath(d.path("memory"))
 }
@@ -200,6 +268,12 @@ func (s *MemoryGroup) GetStats(path string, stats *cgroups.Stats) error {
 	}
 	stats.MemoryStats.KernelTCPUsage = kernelTCPUsage
 
+	pageUsageByNUMA, err := getPageUsageByNUMA(path)
+	if err != nil {
+		return err
+	}
+	stats.MemoryStats.PageUsageByNUMA = pageUsageByNUMA
+
 	useHierarchy := strings.Join([]string{"memory", "use_hierarchy"}, ".")
 	value, err := getCgroupParamUint(path, useHierarchy)
 	if err != nil {
@@ -211,12 +285,94 @@ func (s *MemoryGroup) GetStats(path string, stats *cgroups.Stats) error {
 	return nil
 }
 
+// getPageUsageByNUMA parses memory.numa_stat, where each line has the
+// number of pages of a type of memory in total and per NUMA node, e.g.
+// "total=44611 N0=32631 N1=7501".
+func getPageUsageByNUMA(path string) (cgroups.PageUsageByNUMA, error) {
+	stats := cgroups.PageUsageByNUMA{}
+
+	file, err := os.Open(filepath.Join(path, "memory.numa_stat"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return stats, nil
+		}
+		return stats, err
+	}
+	defer file.Close()
+
+	sc := bufio.NewScanner(file)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		var field *cgroups.PageStats
+		switch strings.SplitN(fields[0], "=", 2)[0] {
+		case "total":
+			field = &stats.Total
+		case "file":
+			field = &stats.File
+		case "anon":
+			field = &stats.Anon
+		case "unevictable":
+			field = &stats.Unevictable
+		case "hierarchical_total":
+			field = &stats.Hierarchical.Total
+		case "hierarchical_file":
+			field = &stats.Hierarchical.File
+		case "hierarchical_anon":
+			field = &stats.Hierarchical.Anon
+		case "hierarchical_unevictable":
+			field = &stats.Hierarchical.Unevictable
+		default:
+			continue
+		}
+		if err := parsePageStats(fields, field); err != nil {
+			return stats, fmt.Errorf("failed to parse memory.numa_stat (%q) - %v", sc.Text(), err)
+		}
+	}
+	return stats, sc.Err()
+}
+
+// parsePageStats parses the fields of a line of memory.numa_stat, the total
+// followed by the number of pages on each NUMA node.
+func parsePageStats(fields []string, stats *cgroups.PageStats) error {
+	stats.Nodes = make(map[uint16]uint64)
+	for i, f := range fields {
+		kv := strings.SplitN(f, "=", 2)
+		if len(kv) != 2 {
+			return ErrNotValidFormat
+		}
+		pages, err := strconv.ParseUint(kv[1], 10, 64)
+		if err != nil {
+			return err
+		}
+		if i == 0 {
+			stats.Total = pages
+			continue
+		}
+		if !strings.HasPrefix(kv[0], "N") {
+			return ErrNotValidFormat
+		}
+		node, err := strconv.ParseUint(kv[0][1:], 10, 16)
+		if err != nil {
+			return err
+		}
+		stats.Nodes[uint16(node)] = pages
+	}
+	return nil
+}
+
 func memoryAssigned(cgroup *configs.Cgroup) bool {
 	return cgroup.Resources.Memory != 0 ||
 		cgroup.Resources.MemoryReservation != 0 ||
 		cgroup.Resources.MemorySwap > 0 ||
 		cgroup.Resources.KernelMemory > 0 ||
 		cgroup.Resources.KernelMemoryTCP > 0 ||
+		cgroup.Resources.MemoryHigh != 0 ||
+		cgroup.Resources.MemoryLow != 0 ||
+		cgroup.Resources.MemoryMin != 0 ||
+		cgroup.Resources.MemoryOomGroup ||
 		cgroup.Resources.OomKillDisable ||
 		(cgroup.Resources.MemorySwappiness != nil && int64(*cgroup.Resources.MemorySwappiness) != -1)
 }
@@ -3,6 +3,7 @@
 package fs
 
 import (
+	"reflect"
 	"strconv"
 	"testing"
 
@@ -453,3 +454,236 @@ func TestMemorySetOomControl(t *testing.T) {
 		t.Fatalf("Got the wrong value, set memory.oom_control failed.")
 	}
 }
+
+func TestMemorySetMemoryHigh(t *testing.T) {
+	helper := NewCgroupTestUtil("memory", t)
+	defer helper.cleanup()
+
+	helper.writeFileContents(map[string]string{
+		"memory.high": "max",
+	})
+
+	helper.CgroupData.config.Resources.MemoryHigh = 134217728 // 128M
+	memory := &MemoryGroup{}
+	if err := memory.Set(helper.CgroupPath, helper.CgroupData.config); err != nil {
+		t.Fatal(err)
+	}
+
+	value, err := getCgroupParamString(helper.CgroupPath, "memory.high")
+	if err != nil {
+		t.Fatalf("Failed to parse memory.high - %s", err)
+	}
+	if value != "134217728" {
+		t.Fatalf("Got the wrong value %q, set memory.high failed.", value)
+	}
+
+	helper.CgroupData.config.Resources.MemoryHigh = -1
+	if err := memory.Set(helper.CgroupPath, helper.CgroupData.config); err != nil {
+		t.Fatal(err)
+	}
+
+	value, err = getCgroupParamString(helper.CgroupPath, "memory.high")
+	if err != nil {
+		t.Fatalf("Failed to parse memory.high - %s", err)
+	}
+	if value != "max" {
+		t.Fatalf("Got the wrong value %q, set memory.high failed.", value)
+	}
+}
+
+func TestMemorySetMemoryHighSoftLimit(t *testing.T) {
+	helper := NewCgroupTestUtil("memory", t)
+	defer helper.cleanup()
+
+	const (
+		memoryHigh = 1024
+		limit      = 8192
+	)
+
+	// The usage is above memory high, but the limit is left as it is.
+	helper.writeFileContents(map[string]string{
+		"memory.soft_limit_in_bytes": "0",
+		"memory.usage_in_bytes":      memoryUsageContents,
+		"memory.max_usage_in_bytes":  memoryMaxUsageContents,
+		"memory.failcnt":             memoryFailcnt,
+		"memory.limit_in_bytes":      strconv.Itoa(limit),
+	})
+
+	helper.CgroupData.config.Resources.MemoryHigh = memoryHigh
+	memory := &MemoryGroup{}
+	if err := memory.Set(helper.CgroupPath, helper.CgroupData.config); err != nil {
+		t.Fatal(err)
+	}
+
+	value, err := getCgroupParamUint(helper.CgroupPath, "memory.soft_limit_in_bytes")
+	if err != nil {
+		t.Fatalf("Failed to parse memory.soft_limit_in_bytes - %s", err)
+	}
+	if value != memoryHigh {
+		t.Fatal("Got the wrong value, set memory.soft_limit_in_bytes failed.")
+	}
+
+	value, err = getCgroupParamUint(helper.CgroupPath, "memory.limit_in_bytes")
+	if err != nil {
+		t.Fatalf("Failed to parse memory.limit_in_bytes - %s", err)
+	}
+	if value != limit {
+		t.Fatal("Got the wrong value, memory.limit_in_bytes was changed.")
+	}
+}
+
+func TestMemorySetMemoryHighWithReservation(t *testing.T) {
+	helper := NewCgroupTestUtil("memory", t)
+	defer helper.cleanup()
+
+	helper.writeFileContents(map[string]string{
+		"memory.soft_limit_in_bytes": "0",
+	})
+
+	helper.CgroupData.config.Resources.MemoryReservation = 2048
+	helper.CgroupData.config.Resources.MemoryHigh = 1024
+	memory := &MemoryGroup{}
+	if err := memory.Set(helper.CgroupPath, helper.CgroupData.config); err == nil {
+		t.Fatal("Expected memory high and memory reservation to conflict without memory.high")
+	}
+}
+
+func TestMemorySetMemoryProtection(t *testing.T) {
+	helper := NewCgroupTestUtil("memory", t)
+	defer helper.cleanup()
+
+	helper.writeFileContents(map[string]string{
+		"memory.min": "0",
+		"memory.low": "0",
+	})
+
+	helper.CgroupData.config.Resources.MemoryMin = 1048576
+	helper.CgroupData.config.Resources.MemoryLow = -1
+	memory := &MemoryGroup{}
+	if err := memory.Set(helper.CgroupPath, helper.CgroupData.config); err != nil {
+		t.Fatal(err)
+	}
+
+	for file, expected := range map[string]string{
+		"memory.min": "1048576",
+		"memory.low": "max",
+	} {
+		value, err := getCgroupParamString(helper.CgroupPath, file)
+		if err != nil {
+			t.Fatalf("Failed to parse %s - %s", file, err)
+		}
+		if value != expected {
+			t.Fatalf("Got the wrong value %q, set %s failed.", value, file)
+		}
+	}
+}
+
+func TestMemorySetMemoryProtectionUnsupported(t *testing.T) {
+	helper := NewCgroupTestUtil("memory", t)
+	defer helper.cleanup()
+
+	helper.CgroupData.config.Resources.MemoryLow = 1048576
+	memory := &MemoryGroup{}
+	if err := memory.Set(helper.CgroupPath, helper.CgroupData.config); err == nil {
+		t.Fatal("Expected memory.low to be rejected without kernel support")
+	}
+}
+
+func TestMemorySetOomGroup(t *testing.T) {
+	helper := NewCgroupTestUtil("memory", t)
+	defer helper.cleanup()
+
+	helper.writeFileContents(map[string]string{
+		"memory.oom.group": "0",
+	})
+
+	helper.CgroupData.config.Resources.MemoryOomGroup = true
+	memory := &MemoryGroup{}
+	if err := memory.Set(helper.CgroupPath, helper.CgroupData.config); err != nil {
+		t.Fatal(err)
+	}
+
+	value, err := getCgroupParamUint(helper.CgroupPath, "memory.oom.group")
+	if err != nil {
+		t.Fatalf("Failed to parse memory.oom.group - %s", err)
+	}
+	if value != 1 {
+		t.Fatal("Got the wrong value, set memory.oom.group failed.")
+	}
+}
+
+func TestMemorySetOomGroupUnsupported(t *testing.T) {
+	helper := NewCgroupTestUtil("memory", t)
+	defer helper.cleanup()
+
+	helper.CgroupData.config.Resources.MemoryOomGroup = true
+	memory := &MemoryGroup{}
+	if err := memory.Set(helper.CgroupPath, helper.CgroupData.config); err == nil {
+		t.Fatal("Expected memory.oom.group to be rejected without kernel support")
+	}
+}
+
+func TestMemoryStatsPageUsageByNUMA(t *testing.T) {
+	helper := NewCgroupTestUtil("memory", t)
+	defer helper.cleanup()
+	helper.writeFileContents(map[string]string{
+		"memory.stat":               memoryStatContents,
+		"memory.usage_in_bytes":     memoryUsageContents,
+		"memory.limit_in_bytes":     memoryLimitContents,
+		"memory.max_usage_in_bytes": memoryMaxUsageContents,
+		"memory.failcnt":            memoryFailcnt,
+		"memory.use_hierarchy":      memoryUseHierarchyContents,
+		"memory.numa_stat": "total=44611 N0=32631 N1=11980\n" +
+			"file=44428 N0=32614 N1=11814\n" +
+			"anon=183 N0=17 N1=166\n" +
+			"unevictable=0 N0=0 N1=0\n" +
+			"hierarchical_total=768133 N0=509113 N1=259020\n" +
+			"hierarchical_file=756804 N0=500902 N1=255902\n" +
+			"hierarchical_anon=11329 N0=8211 N1=3118\n" +
+			"hierarchical_unevictable=0 N0=0 N1=0\n",
+	})
+
+	memory := &MemoryGroup{}
+	actualStats := *cgroups.NewStats()
+	if err := memory.GetStats(helper.CgroupPath, &actualStats); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := cgroups.PageUsageByNUMA{
+		PageUsageByNUMAInner: cgroups.PageUsageByNUMAInner{
+			Total:       cgroups.PageStats{Total: 44611, Nodes: map[uint16]uint64{0: 32631, 1: 11980}},
+			File:        cgroups.PageStats{Total: 44428, Nodes: map[uint16]uint64{0: 32614, 1: 11814}},
+			Anon:        cgroups.PageStats{Total: 183, Nodes: map[uint16]uint64{0: 17, 1: 166}},
+			Unevictable: cgroups.PageStats{Total: 0, Nodes: map[uint16]uint64{0: 0, 1: 0}},
+		},
+		Hierarchical: cgroups.PageUsageByNUMAInner{
+			Total:       cgroups.PageStats{Total: 768133, Nodes: map[uint16]uint64{0: 509113, 1: 259020}},
+			File:        cgroups.PageStats{Total: 756804, Nodes: map[uint16]uint64{0: 500902, 1: 255902}},
+			Anon:        cgroups.PageStats{Total: 11329, Nodes: map[uint16]uint64{0: 8211, 1: 3118}},
+			Unevictable: cgroups.PageStats{Total: 0, Nodes: map[uint16]uint64{0: 0, 1: 0}},
+		},
+	}
+	if !reflect.DeepEqual(expected, actualStats.MemoryStats.PageUsageByNUMA) {
+		t.Fatalf("Expected page usage by NUMA %+v, got %+v", expected, actualStats.MemoryStats.PageUsageByNUMA)
+	}
+}
+
+func TestMemoryStatsBadNumaStatFile(t *testing.T) {
+	helper := NewCgroupTestUtil("memory", t)
+	defer helper.cleanup()
+	helper.writeFileContents(map[string]string{
+		"memory.stat":               memoryStatContents,
+		"memory.usage_in_bytes":     memoryUsageContents,
+		"memory.limit_in_bytes":     memoryLimitContents,
+		"memory.max_usage_in_bytes": memoryMaxUsageContents,
+		"memory.failcnt":            memoryFailcnt,
+		"memory.use_hierarchy":      memoryUseHierarchyContents,
+		"memory.numa_stat":          "total=44611 0=32631\n",
+	})
+
+	memory := &MemoryGroup{}
+	actualStats := *cgroups.NewStats()
+	if err := memory.GetStats(helper.CgroupPath, &actualStats); err == nil {
+		t.Fatal("Expected failure")
+	}
+}
@@ -20,6 +20,12 @@ type CpuUsage struct {
 	// Total CPU time consumed per core.
 	// Units: nanoseconds.
 	PercpuUsage []uint64 `json:"percpu_usage,omitempty"`
+	// CPU time consumed per core in kernel mode.
+	// Units: nanoseconds.
+	PercpuUsageInKernelmode []uint64 `json:"percpu_usage_in_kernelmode,omitempty"`
+	// CPU time consumed per core in user mode.
+	// Units: nanoseconds.
+	PercpuUsageInUsermode []uint64 `json:"percpu_usage_in_usermode,omitempty"`
 	// Time spent by tasks of the cgroup in kernel mode.
 	// Units: nanoseconds.
 	UsageInKernelmode uint64 `json:"usage_in_kernelmode"`
@@ -28,9 +34,30 @@ type CpuUsage struct {
 	UsageInUsermode uint64 `json:"usage_in_usermode"`
 }
 
+type BurstData struct {
+	// Number of periods in which the container used its burst.
+	BurstsPeriods uint64 `json:"bursts_periods,omitempty"`
+	// Aggregate time the container ran beyond its quota, in nanoseconds.
+	BurstTime uint64 `json:"burst_time,omitempty"`
+}
+
+// SchedStats are the scheduler statistics from /proc/<pid>/schedstat,
+// aggregated over the tasks which are currently in the cgroup. The time of
+// the tasks which have already exited is not accounted.
+type SchedStats struct {
+	// Time spent running on a CPU, in nanoseconds.
+	RunTime uint64 `json:"run_time,omitempty"`
+	// Time spent waiting on a run queue, in nanoseconds.
+	WaitTime uint64 `json:"wait_time,omitempty"`
+	// Number of timeslices run on a CPU.
+	Timeslices uint64 `json:"timeslices,omitempty"`
+}
+
 type CpuStats struct {
 	CpuUsage       CpuUsage       `json:"cpu_usage,omitempty"`
 	ThrottlingData ThrottlingData `json:"throttling_data,omitempty"`
+	BurstData      BurstData      `json:"burst_data,omitempty"`
+	SchedStats     SchedStats     `json:"sched_stats,omitempty"`
 }
 
 type MemoryData struct {
@@ -53,10 +80,63 @@ type MemoryStats struct {
 	KernelTCPUsage MemoryData `json:"kernel_tcp_usage,omitempty"`
 	// if true, memory usage is accounted for throughout a hierarchy of cgroups.
 	UseHierarchy bool `json:"use_hierarchy"`
+	// usage of memory per NUMA node, in pages
+	PageUsageByNUMA PageUsageByNUMA `json:"page_usage_by_numa,omitempty"`
 
 	Stats map[string]uint64 `json:"stats,omitempty"`
 }
 
+type PageStats struct {
+	// number of pages on all NUMA nodes
+	Total uint64 `json:"total,omitempty"`
+	// number of pages per NUMA node
+	Nodes map[uint16]uint64 `json:"nodes,omitempty"`
+}
+
+type PageUsageByNUMAInner struct {
+	Total       PageStats `json:"total,omitempty"`
+	File        PageStats `json:"file,omitempty"`
+	Anon        PageStats `json:"anon,omitempty"`
+	Unevictable PageStats `json:"unevictable,omitempty"`
+}
+
+type PageUsageByNUMA struct {
+	// Embedding is used as types can't be recursive.
+	PageUsageByNUMAInner
+	// usage including the descendants of the cgroup
+	Hierarchical PageUsageByNUMAInner `json:"hierarchical,omitempty"`
+}
+
+// RemotePages returns the pages of s which are on NUMA nodes other than
+// mems, i.e. the memory which is remote to the nodes a cgroup may allocate
+// from. If mems is empty, no page is considered to be remote.
+func (s PageStats) RemotePages(mems []uint16) PageStats {
+	remote := PageStats{}
+	if len(mems) == 0 {
+		return remote
+	}
+	local := make(map[uint16]bool, len(mems))
+	for _, node := range mems {
+		local[node] = true
+	}
+	for node, pages := range s.Nodes {
+		if local[node] || pages == 0 {
+			continue
+		}
+		if remote.Nodes == nil {
+			remote.Nodes = make(map[uint16]uint64)
+		}
+		remote.Nodes[node] = pages
+		remote.Total += pages
+	}
+	return remote
+}
+
+type CPUSetStats struct {
+	// NUMA nodes the cgroup may allocate memory from
+	Mems []uint16 `json:"mems,omitempty"`
+}
+
 type PidsStats struct {
 	// number of pids in the cgroup
 	Current uint64 `json:"current,omitempty"`
@@ -67,6 +147,8 @@ type PidsStats struct {
 type BlkioStatEntry struct {
 	Major uint64 `json:"major,omitempty"`
 	Minor uint64 `json:"minor,omitempty"`
+	// name of the block device, e.g. "sda", if it could be resolved
+	Name  string `json:"name,omitempty"`
 	Op    string `json:"op,omitempty"`
 	Value uint64 `json:"value,omitempty"`
 }
@@ -95,6 +177,7 @@ type HugetlbStats struct {
 type Stats struct {
 	CpuStats    CpuStats    `json:"cpu_stats,omitempty"`
 	MemoryStats MemoryStats `json:"memory_stats,omitempty"`
+	CPUSetStats CPUSetStats `json:"cpuset_stats,omitempty"`
 	PidsStats   PidsStats   `json:"pids_stats,omitempty"`
 	BlkioStats  BlkioStats  `json:"blkio_stats,omitempty"`
 	// the map is in the format "size of hugepage: stats of the hugepage"
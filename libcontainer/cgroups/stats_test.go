@@ -0,0 +1,26 @@
+// +build linux
+
+package cgroups
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRemotePages(t *testing.T) {
+	usage := PageStats{
+		Total: 700,
+		Nodes: map[uint16]uint64{0: 100, 1: 200, 2: 400, 3: 0},
+	}
+
+	remote := usage.RemotePages([]uint16{0, 1})
+	expected := PageStats{Total: 400, Nodes: map[uint16]uint64{2: 400}}
+	if !reflect.DeepEqual(expected, remote) {
+		t.Fatalf("Expected remote pages %+v, got %+v", expected, remote)
+	}
+
+	// Without cpuset.mems all nodes are local
+	if remote := usage.RemotePages(nil); !reflect.DeepEqual(PageStats{}, remote) {
+		t.Fatalf("Expected no remote pages, got %+v", remote)
+	}
+}
@@ -18,6 +18,10 @@ func UseSystemd() bool {
 	return false
 }
 
+func UseSystemdUser() bool {
+	return false
+}
+
 func (m *Manager) Apply(pid int) error {
 	return fmt.Errorf("Systemd not supported")
 }
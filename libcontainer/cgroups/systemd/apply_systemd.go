@@ -3,11 +3,14 @@
 package systemd
 
 import (
+	"bufio"
 	"errors"
 	"fmt"
+	"io"
 	"math"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -19,12 +22,14 @@ import (
 	"github.com/opencontainers/runc/libcontainer/cgroups/fs"
 	"github.com/opencontainers/runc/libcontainer/configs"
 	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
 )
 
 type Manager struct {
-	mu      sync.Mutex
-	Cgroups *configs.Cgroup
-	Paths   map[string]string
+	mu       sync.Mutex
+	Cgroups  *configs.Cgroup
+	Rootless bool // use the user's systemd instance and ignore permission-related errors
+	Paths    map[string]string
 }
 
 type subsystem interface {
@@ -73,6 +78,7 @@ const (
 var (
 	connLock                        sync.Mutex
 	theConn                         *systemdDbus.Conn
+	theUserConn                     *systemdDbus.Conn
 	hasStartTransientUnit           bool
 	hasStartTransientSliceUnit      bool
 	hasTransientDefaultDependencies bool
@@ -212,18 +218,46 @@ func UseSystemd() bool {
 	return hasStartTransientUnit
 }
 
+// UseSystemdUser returns whether the systemd instance of the calling user
+// can be used to manage cgroups, connecting to it on the session bus.
+func UseSystemdUser() bool {
+	if !systemdUtil.IsRunningSystemd() {
+		return false
+	}
+
+	connLock.Lock()
+	defer connLock.Unlock()
+
+	if theUserConn == nil {
+		conn, err := systemdDbus.NewUserConnection()
+		if err != nil {
+			return false
+		}
+		theUserConn = conn
+	}
+	return true
+}
+
+// conn returns the connection to the systemd instance managing the unit.
+func (m *Manager) conn() *systemdDbus.Conn {
+	if m.Rootless {
+		return theUserConn
+	}
+	return theConn
+}
+
 func (m *Manager) Apply(pid int) error {
 	var (
 		c          = m.Cgroups
 		unitName   = getUnitName(c)
-		slice      = "system.slice"
+		slice      = defaultSlice(m.Rootless)
 		properties []systemdDbus.Property
 	)
 
 	if c.Paths != nil {
 		paths := make(map[string]string)
 		for name, path := range c.Paths {
-			_, err := getSubsystemPath(m.Cgroups, name)
+			_, err := getSubsystemPath(m.Cgroups, name, m.Rootless)
 			if err != nil {
 				// Don't fail if a cgroup hierarchy was not found, just skip this subsystem
 				if cgroups.IsNotFound(err) {
@@ -246,7 +280,7 @@ func (m *Manager) Apply(pid int) error {
 	// if we create a slice, the parent is defined via a Wants=
 	if strings.HasSuffix(unitName, ".slice") {
 		// This was broken until systemd v229, but has been back-ported on RHEL environments >= 219
-		if !hasStartTransientSliceUnit {
+		if !hasStartTransientSliceUnit && !m.Rootless {
 			return fmt.Errorf("systemd version does not support ability to start a slice as transient unit")
 		}
 		properties = append(properties, systemdDbus.PropWants(slice))
@@ -261,13 +295,15 @@ func (m *Manager) Apply(pid int) error {
 	}
 
 	// Check if we can delegate. This is only supported on systemd versions 218 and above.
+	// The user instance is not probed, but a rootless container cannot use its
+	// cgroup without delegation, so we always ask for it.
 	if strings.HasSuffix(unitName, ".slice") {
 		if hasDelegateSlice {
 			// systemd 237 and above no longer allows delegation on a slice
 			properties = append(properties, newProp("Delegate", true))
 		}
 	} else {
-		if hasDelegateScope {
+		if hasDelegateScope || m.Rootless {
 			properties = append(properties, newProp("Delegate", true))
 		}
 	}
@@ -279,62 +315,27 @@ func (m *Manager) Apply(pid int) error {
 		newProp("CPUAccounting", true),
 		newProp("BlockIOAccounting", true))
 
-	if hasTransientDefaultDependencies {
+	if hasTransientDefaultDependencies || m.Rootless {
 		properties = append(properties,
 			newProp("DefaultDependencies", false))
 	}
 
-	if c.Resources.Memory != 0 {
-		properties = append(properties,
-			newProp("MemoryLimit", uint64(c.Resources.Memory)))
-	}
-
-	if c.Resources.CpuShares != 0 {
-		properties = append(properties,
-			newProp("CPUShares", c.Resources.CpuShares))
-	}
-
-	// cpu.cfs_quota_us and cpu.cfs_period_us are controlled by systemd.
-	if c.Resources.CpuQuota != 0 && c.Resources.CpuPeriod != 0 {
-		// corresponds to USEC_INFINITY in systemd
-		// if USEC_INFINITY is provided, CPUQuota is left unbound by systemd
-		// always setting a property value ensures we can apply a quota and remove it later
-		cpuQuotaPerSecUSec := uint64(math.MaxUint64)
-		if c.Resources.CpuQuota > 0 {
-			// systemd converts CPUQuotaPerSecUSec (microseconds per CPU second) to CPUQuota
-			// (integer percentage of CPU) internally.  This means that if a fractional percent of
-			// CPU is indicated by Resources.CpuQuota, we need to round up to the nearest
-			// 10ms (1% of a second) such that child cgroups can set the cpu.cfs_quota_us they expect.
-			cpuQuotaPerSecUSec = uint64(c.Resources.CpuQuota*1000000) / c.Resources.CpuPeriod
-			if cpuQuotaPerSecUSec%10000 != 0 {
-				cpuQuotaPerSecUSec = ((cpuQuotaPerSecUSec / 10000) + 1) * 10000
-			}
-		}
-		properties = append(properties,
-			newProp("CPUQuotaPerSecUSec", cpuQuotaPerSecUSec))
-	}
-
-	if c.Resources.BlkioWeight != 0 {
-		properties = append(properties,
-			newProp("BlockIOWeight", uint64(c.Resources.BlkioWeight)))
-	}
+	properties = append(properties, genResourcesProperties(c)...)
 
-	if c.Resources.PidsLimit > 0 {
-		properties = append(properties,
-			newProp("TasksAccounting", true),
-			newProp("TasksMax", uint64(c.Resources.PidsLimit)))
-	}
+	// Properties from the container's annotations go last, so that they can
+	// override the ones derived from the resources.
+	properties = append(properties, c.SystemdProps...)
 
 	// We have to set kernel memory here, as we can't change it once
 	// processes have been attached to the cgroup.
 	if c.Resources.KernelMemory != 0 {
-		if err := setKernelMemory(c); err != nil {
+		if err := setKernelMemory(c, m.Rootless); err != nil {
 			return err
 		}
 	}
 
 	statusChan := make(chan string, 1)
-	if _, err := theConn.StartTransientUnit(unitName, "replace", properties, statusChan); err == nil {
+	if _, err := m.conn().StartTransientUnit(unitName, "replace", properties, statusChan); err == nil {
 		select {
 		case <-statusChan:
 		case <-time.After(time.Second):
@@ -344,13 +345,13 @@ func (m *Manager) Apply(pid int) error {
 		return err
 	}
 
-	if err := joinCgroups(c, pid); err != nil {
+	if err := joinCgroups(c, pid, m.Rootless); err != nil {
 		return err
 	}
 
 	paths := make(map[string]string)
 	for _, s := range subsystems {
-		subsystemPath, err := getSubsystemPath(m.Cgroups, s.Name())
+		subsystemPath, err := getSubsystemPath(m.Cgroups, s.Name(), m.Rootless)
 		if err != nil {
 			// Don't fail if a cgroup hierarchy was not found, just skip this subsystem
 			if cgroups.IsNotFound(err) {
@@ -358,6 +359,10 @@ func (m *Manager) Apply(pid int) error {
 			}
 			return err
 		}
+		// Skip the hierarchies a rootless container could not join.
+		if m.Rootless && !cgroups.PathExists(subsystemPath) {
+			continue
+		}
 		paths[s.Name()] = subsystemPath
 	}
 	m.Paths = paths
@@ -370,7 +375,7 @@ func (m *Manager) Destroy() error {
 	}
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	theConn.StopUnit(getUnitName(m.Cgroups), "replace", nil)
+	m.conn().StopUnit(getUnitName(m.Cgroups), "replace", nil)
 	if err := cgroups.RemovePaths(m.Paths); err != nil {
 		return err
 	}
@@ -385,8 +390,8 @@ func (m *Manager) GetPaths() map[string]string {
 	return paths
 }
 
-func join(c *configs.Cgroup, subsystem string, pid int) (string, error) {
-	path, err := getSubsystemPath(c, subsystem)
+func join(c *configs.Cgroup, subsystem string, pid int, rootless bool) (string, error) {
+	path, err := getSubsystemPath(c, subsystem, rootless)
 	if err != nil {
 		return "", err
 	}
@@ -399,24 +404,33 @@ func join(c *configs.Cgroup, subsystem string, pid int) (string, error) {
 	return path, nil
 }
 
-func joinCgroups(c *configs.Cgroup, pid int) error {
+func joinCgroups(c *configs.Cgroup, pid int, rootless bool) error {
 	for _, sys := range subsystems {
 		name := sys.Name()
 		switch name {
 		case "name=systemd":
 			// let systemd handle this
 		case "cpuset":
-			path, err := getSubsystemPath(c, name)
+			path, err := getSubsystemPath(c, name, rootless)
 			if err != nil && !cgroups.IsNotFound(err) {
 				return err
 			}
 			s := &fs.CpusetGroup{}
 			if err := s.ApplyDir(path, c, pid); err != nil {
+				if isIgnorableError(rootless, err) {
+					continue
+				}
 				return err
 			}
 		default:
-			_, err := join(c, name, pid)
+			_, err := join(c, name, pid, rootless)
 			if err != nil {
+				// The user instance delegates only its own hierarchy,
+				// so a rootless container may not be able to join the
+				// others.
+				if isIgnorableError(rootless, err) {
+					continue
+				}
 				// Even if it's `not found` error, we'll return err
 				// because devices cgroup is hard requirement for
 				// container security.
@@ -469,20 +483,26 @@ func ExpandSlice(slice string) (string, error) {
 	return path, nil
 }
 
-func getSubsystemPath(c *configs.Cgroup, subsystem string) (string, error) {
+func getSubsystemPath(c *configs.Cgroup, subsystem string, rootless bool) (string, error) {
 	mountpoint, err := cgroups.FindCgroupMountpoint(c.Path, subsystem)
 	if err != nil {
 		return "", err
 	}
 
-	initPath, err := cgroups.GetInitCgroup(subsystem)
+	var initPath string
+	if rootless {
+		// the slices of the user instance are below its own cgroup
+		initPath, err = getUserManagerCgroup()
+	} else {
+		initPath, err = cgroups.GetInitCgroup(subsystem)
+	}
 	if err != nil {
 		return "", err
 	}
 	// if pid 1 is systemd 226 or later, it will be in init.scope, not the root
 	initPath = strings.TrimSuffix(filepath.Clean(initPath), "init.scope")
 
-	slice := "system.slice"
+	slice := defaultSlice(rootless)
 	if c.Parent != "" {
 		slice = c.Parent
 	}
@@ -496,7 +516,7 @@ func getSubsystemPath(c *configs.Cgroup, subsystem string) (string, error) {
 }
 
 func (m *Manager) Freeze(state configs.FreezerState) error {
-	path, err := getSubsystemPath(m.Cgroups, "freezer")
+	path, err := getSubsystemPath(m.Cgroups, "freezer", m.Rootless)
 	if err != nil {
 		return err
 	}
@@ -515,7 +535,7 @@ func (m *Manager) Freeze(state configs.FreezerState) error {
 }
 
 func (m *Manager) GetPids() ([]int, error) {
-	path, err := getSubsystemPath(m.Cgroups, "devices")
+	path, err := getSubsystemPath(m.Cgroups, "devices", m.Rootless)
 	if err != nil {
 		return nil, err
 	}
@@ -523,7 +543,7 @@ func (m *Manager) GetPids() ([]int, error) {
 }
 
 func (m *Manager) GetAllPids() ([]int, error) {
-	path, err := getSubsystemPath(m.Cgroups, "devices")
+	path, err := getSubsystemPath(m.Cgroups, "devices", m.Rootless)
 	if err != nil {
 		return nil, err
 	}
@@ -547,20 +567,231 @@ func (m *Manager) GetStats() (*cgroups.Stats, error) {
 	return stats, nil
 }
 
+// genResourcesProperties returns the properties of the container's unit
+// which correspond to the resources of c.
+func genResourcesProperties(c *configs.Cgroup) []systemdDbus.Property {
+	var properties []systemdDbus.Property
+
+	if c.Resources.Memory != 0 {
+		properties = append(properties,
+			newProp("MemoryLimit", uint64(c.Resources.Memory)))
+	}
+
+	if c.Resources.CpuShares != 0 {
+		properties = append(properties,
+			newProp("CPUShares", c.Resources.CpuShares))
+	}
+
+	// cpu.cfs_quota_us and cpu.cfs_period_us are controlled by systemd.
+	if c.Resources.CpuQuota != 0 && c.Resources.CpuPeriod != 0 {
+		// corresponds to USEC_INFINITY in systemd
+		// if USEC_INFINITY is provided, CPUQuota is left unbound by systemd
+		// always setting a property value ensures we can apply a quota and remove it later
+		cpuQuotaPerSecUSec := uint64(math.MaxUint64)
+		if c.Resources.CpuQuota > 0 {
+			// systemd converts CPUQuotaPerSecUSec (microseconds per CPU second) to CPUQuota
+			// (integer percentage of CPU) internally.  This means that if a fractional percent of
+			// CPU is indicated by Resources.CpuQuota, we need to round up to the nearest
+			// 10ms (1% of a second) such that child cgroups can set the cpu.cfs_quota_us they expect.
+			cpuQuotaPerSecUSec = uint64(c.Resources.CpuQuota*1000000) / c.Resources.CpuPeriod
+			if cpuQuotaPerSecUSec%10000 != 0 {
+				cpuQuotaPerSecUSec = ((cpuQuotaPerSecUSec / 10000) + 1) * 10000
+			}
+		}
+		properties = append(properties,
+			newProp("CPUQuotaPerSecUSec", cpuQuotaPerSecUSec))
+	}
+
+	if c.Resources.BlkioWeight != 0 {
+		properties = append(properties,
+			newProp("BlockIOWeight", uint64(c.Resources.BlkioWeight)))
+	}
+
+	if c.Resources.PidsLimit > 0 {
+		properties = append(properties,
+			newProp("TasksAccounting", true),
+			newProp("TasksMax", uint64(c.Resources.PidsLimit)))
+	}
+
+	return properties
+}
+
+// genUpdateProperties returns the properties of the container's unit which
+// are set when its resources are updated to the ones of c, and whether the
+// device rules of c could be expressed as properties. Unlike when the unit
+// is created, a limit may have to be lifted and the device rules replaced.
+func genUpdateProperties(c *configs.Cgroup) ([]systemdDbus.Property, bool) {
+	properties := genResourcesProperties(c)
+	if c.Resources.PidsLimit == -1 {
+		properties = append(properties,
+			newProp("TasksAccounting", true),
+			newProp("TasksMax", uint64(math.MaxUint64)))
+	}
+	deviceProperties, ok := genDeviceProperties(c.Resources.Devices)
+	properties = append(properties, deviceProperties...)
+	return properties, ok
+}
+
+// deviceAllow is the type of the entries of the DeviceAllow property.
+type deviceAllow struct {
+	Path  string
+	Perms string
+}
+
+// genDeviceProperties returns the DevicePolicy and DeviceAllow properties
+// corresponding to the device rules, if they can be expressed that way. That
+// is the case if all devices are denied first and then character or block
+// devices are allowed. systemd refers to single devices by their /dev/char
+// and /dev/block paths, to all the devices of a driver by its name in
+// /proc/devices, e.g. char-pts, and to all the devices of a type as char-*
+// or block-*.
+func genDeviceProperties(rules []*configs.Device) ([]systemdDbus.Property, bool) {
+	if len(rules) == 0 {
+		return nil, false
+	}
+	first := rules[0]
+	if first.Allow || first.Type != 'a' || first.Major != configs.Wildcard || first.Minor != configs.Wildcard {
+		return nil, false
+	}
+	allow := []deviceAllow{}
+	for _, rule := range rules[1:] {
+		if !rule.Allow {
+			return nil, false
+		}
+		var kind string
+		switch rule.Type {
+		case 'c':
+			kind = "char"
+		case 'b':
+			kind = "block"
+		default:
+			return nil, false
+		}
+		var path string
+		switch {
+		case rule.Major == configs.Wildcard && rule.Minor == configs.Wildcard:
+			path = kind + "-*"
+		case rule.Major == configs.Wildcard:
+			return nil, false
+		case rule.Minor == configs.Wildcard:
+			name, err := findDeviceGroup(kind, rule.Major)
+			if err != nil || name == "" {
+				return nil, false
+			}
+			path = kind + "-" + name
+		default:
+			path = fmt.Sprintf("/dev/%s/%d:%d", kind, rule.Major, rule.Minor)
+		}
+		allow = append(allow, deviceAllow{
+			Path:  path,
+			Perms: rule.Permissions,
+		})
+	}
+	return []systemdDbus.Property{
+		newProp("DevicePolicy", "strict"),
+		// systemd only adds to the DeviceAllow list of a running unit
+		// unless it is given an empty one, which resets it. Without it
+		// the devices removed since the last update would stay allowed.
+		newProp("DeviceAllow", []deviceAllow{}),
+		newProp("DeviceAllow", allow),
+	}, true
+}
+
+// findDeviceGroup returns the name of the driver of the devices of kind,
+// "char" or "block", with the given major number, as listed in
+// /proc/devices. It returns an empty name if there is none.
+func findDeviceGroup(kind string, major int64) (string, error) {
+	f, err := os.Open("/proc/devices")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return parseDeviceGroup(f, kind, major)
+}
+
+func parseDeviceGroup(r io.Reader, kind string, major int64) (string, error) {
+	header := "Character devices:"
+	if kind == "block" {
+		header = "Block devices:"
+	}
+	inSection := false
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if strings.HasSuffix(line, "devices:") {
+			inSection = line == header
+			continue
+		}
+		fields := strings.Fields(line)
+		if !inSection || len(fields) != 2 {
+			continue
+		}
+		if n, err := strconv.ParseInt(fields[0], 10, 64); err == nil && n == major {
+			return fields[1], nil
+		}
+	}
+	return "", s.Err()
+}
+
+// withoutSystemdResources returns a copy of c without the resources which
+// systemd has already applied from the properties of the unit, so that only
+// the remaining ones are written to the cgroup filesystem.
+func withoutSystemdResources(c *configs.Cgroup, devices bool) *configs.Cgroup {
+	cgroup := *c
+	resources := *c.Resources
+	resources.CpuShares = 0
+	resources.BlkioWeight = 0
+	resources.PidsLimit = 0
+	// The memory limit and the swap limit have to be written in the right
+	// order, which is only known to the fs memory subsystem.
+	if resources.MemorySwap == 0 {
+		resources.Memory = 0
+	}
+	if devices {
+		resources.Devices = nil
+	}
+	cgroup.Resources = &resources
+	return &cgroup
+}
+
 func (m *Manager) Set(container *configs.Config) error {
 	// If Paths are set, then we are just joining cgroups paths
 	// and there is no need to set any values.
 	if m.Cgroups.Paths != nil {
 		return nil
 	}
+	// Update the unit first, so that systemd keeps the limits when it
+	// reloads. Whatever it cannot express is written to the cgroups directly.
+	cgroup := container.Cgroups
+	properties, devices := genUpdateProperties(container.Cgroups)
+	if err := m.conn().SetUnitProperties(getUnitName(container.Cgroups), true, properties...); err != nil {
+		logrus.Warnf("Setting properties of unit %s failed, writing to cgroups directly: %v", getUnitName(container.Cgroups), err)
+	} else {
+		cgroup = withoutSystemdResources(container.Cgroups, devices)
+	}
+	var unapplied []string
+	if m.Rootless {
+		unapplied = rootlessUnappliedLimits(container.Cgroups.Resources, m.GetPaths())
+		if len(unapplied) > 0 {
+			logrus.Warnf("The systemd user instance does not delegate the %s cgroup(s), their limits are not applied", strings.Join(unapplied, ", "))
+		}
+	}
 	for _, sys := range subsystems {
+		if contains(unapplied, sys.Name()) {
+			continue
+		}
 		// Get the subsystem path, but don't error out for not found cgroups.
-		path, err := getSubsystemPath(container.Cgroups, sys.Name())
+		path, err := getSubsystemPath(container.Cgroups, sys.Name(), m.Rootless)
 		if err != nil && !cgroups.IsNotFound(err) {
 			return err
 		}
 
-		if err := sys.Set(path, container.Cgroups); err != nil {
+		if err := sys.Set(path, cgroup); err != nil {
+			// As with the fs driver, a rootless container is not
+			// expected to be able to restrict its devices.
+			if m.Rootless && sys.Name() == "devices" {
+				continue
+			}
 			return err
 		}
 	}
@@ -573,6 +804,99 @@ func (m *Manager) Set(container *configs.Config) error {
 	return nil
 }
 
+// defaultSlice returns the slice in which units are created if the
+// container's cgroup has no parent.
+func defaultSlice(rootless bool) string {
+	if rootless {
+		return "user.slice"
+	}
+	return "system.slice"
+}
+
+// rootlessUnappliedLimits returns the subsystems for which limits are set in
+// r, but which a rootless container could not join, because the systemd user
+// instance does not delegate them. paths are the joined subsystems.
+func rootlessUnappliedLimits(r *configs.Resources, paths map[string]string) []string {
+	limits := []struct {
+		subsystem string
+		set       bool
+	}{
+		{"cpu", r.CpuShares != 0 || r.CpuQuota != 0 || r.CpuPeriod != 0 || r.CpuRtRuntime != 0 || r.CpuRtPeriod != 0},
+		{"cpuset", r.CpusetCpus != "" || r.CpusetMems != ""},
+		{"memory", r.Memory != 0 || r.MemorySwap != 0 || r.MemoryReservation != 0 || r.KernelMemory != 0},
+		{"pids", r.PidsLimit != 0},
+		{"blkio", r.BlkioWeight != 0 || len(r.BlkioWeightDevice) > 0 || len(r.BlkioThrottleReadBpsDevice) > 0 ||
+			len(r.BlkioThrottleWriteBpsDevice) > 0 || len(r.BlkioThrottleReadIOPSDevice) > 0 || len(r.BlkioThrottleWriteIOPSDevice) > 0},
+		{"hugetlb", len(r.HugetlbLimit) > 0},
+	}
+	var unapplied []string
+	for _, l := range limits {
+		if l.set && paths[l.subsystem] == "" {
+			unapplied = append(unapplied, l.subsystem)
+		}
+	}
+	return unapplied
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	userManagerCgroupLock sync.Mutex
+	userManagerCgroup     string
+)
+
+// getUserManagerCgroup returns the cgroup of the user's systemd instance,
+// which runs in the init.scope of its own hierarchy. It is cached, as it is
+// needed for the path of every subsystem.
+func getUserManagerCgroup() (string, error) {
+	userManagerCgroupLock.Lock()
+	defer userManagerCgroupLock.Unlock()
+	if userManagerCgroup != "" {
+		return userManagerCgroup, nil
+	}
+	cgroup, err := queryUserManagerCgroup()
+	if err != nil {
+		return "", err
+	}
+	userManagerCgroup = cgroup
+	return cgroup, nil
+}
+
+// queryUserManagerCgroup asks the user's systemd instance for its cgroup.
+var queryUserManagerCgroup = func() (string, error) {
+	prop, err := theUserConn.GetUnitTypeProperty("init.scope", "Scope", "ControlGroup")
+	if err != nil {
+		return "", err
+	}
+	cgroup, ok := prop.Value.Value().(string)
+	if !ok || cgroup == "" {
+		return "", fmt.Errorf("unable to get the cgroup of the systemd user instance")
+	}
+	return cgroup, nil
+}
+
+// isIgnorableError returns whether err is a permission error which is
+// expected when managing the cgroups of a rootless container.
+func isIgnorableError(rootless bool, err error) bool {
+	if !rootless {
+		return false
+	}
+	if os.IsPermission(err) {
+		return true
+	}
+	if perr, ok := err.(*os.PathError); ok {
+		return perr.Err == unix.EROFS || perr.Err == unix.EPERM || perr.Err == unix.EACCES
+	}
+	return false
+}
+
 func getUnitName(c *configs.Cgroup) string {
 	// by default, we create a scope unless the user explicitly asks for a slice.
 	if !strings.HasSuffix(c.Name, ".slice") {
@@ -581,8 +905,8 @@ func getUnitName(c *configs.Cgroup) string {
 	return c.Name
 }
 
-func setKernelMemory(c *configs.Cgroup) error {
-	path, err := getSubsystemPath(c, "memory")
+func setKernelMemory(c *configs.Cgroup, rootless bool) error {
+	path, err := getSubsystemPath(c, "memory", rootless)
 	if err != nil && !cgroups.IsNotFound(err) {
 		return err
 	}
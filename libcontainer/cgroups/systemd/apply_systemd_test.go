@@ -0,0 +1,416 @@
+// +build linux,!static_build
+
+package systemd
+
+import (
+	"errors"
+	"math"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	systemdDbus "github.com/coreos/go-systemd/dbus"
+	"github.com/opencontainers/runc/libcontainer/configs"
+	"golang.org/x/sys/unix"
+)
+
+// propertyValues returns the values of properties by their names.
+func propertyValues(properties []systemdDbus.Property) map[string]interface{} {
+	values := make(map[string]interface{})
+	for _, p := range properties {
+		values[p.Name] = p.Value.Value()
+	}
+	return values
+}
+
+func TestGenResourcesProperties(t *testing.T) {
+	testCases := []struct {
+		name      string
+		resources configs.Resources
+		expected  map[string]interface{}
+	}{
+		{
+			name:      "empty",
+			resources: configs.Resources{},
+			expected:  map[string]interface{}{},
+		},
+		{
+			name: "limits",
+			resources: configs.Resources{
+				Memory:      1 << 20,
+				CpuShares:   512,
+				BlkioWeight: 100,
+				PidsLimit:   10,
+			},
+			expected: map[string]interface{}{
+				"MemoryLimit":     uint64(1 << 20),
+				"CPUShares":       uint64(512),
+				"BlockIOWeight":   uint64(100),
+				"TasksAccounting": true,
+				"TasksMax":        uint64(10),
+			},
+		},
+		{
+			name: "cpu quota rounded up",
+			resources: configs.Resources{
+				CpuQuota:  5500,
+				CpuPeriod: 100000,
+			},
+			expected: map[string]interface{}{
+				"CPUQuotaPerSecUSec": uint64(60000),
+			},
+		},
+		{
+			name: "unlimited cpu quota",
+			resources: configs.Resources{
+				CpuQuota:  -1,
+				CpuPeriod: 100000,
+			},
+			expected: map[string]interface{}{
+				"CPUQuotaPerSecUSec": uint64(math.MaxUint64),
+			},
+		},
+		{
+			// A new unit has no tasks limit and keeps its devices.
+			name: "unlimited pids and devices",
+			resources: configs.Resources{
+				PidsLimit: -1,
+				Devices: []*configs.Device{
+					{Type: 'a', Major: configs.Wildcard, Minor: configs.Wildcard, Permissions: "rwm"},
+				},
+			},
+			expected: map[string]interface{}{},
+		},
+	}
+	for _, tc := range testCases {
+		resources := tc.resources
+		properties := genResourcesProperties(&configs.Cgroup{Resources: &resources})
+		if got := propertyValues(properties); !reflect.DeepEqual(got, tc.expected) {
+			t.Errorf("%s: expected properties %v, got %v", tc.name, tc.expected, got)
+		}
+	}
+}
+
+func TestGenUpdateProperties(t *testing.T) {
+	c := &configs.Cgroup{
+		Resources: &configs.Resources{
+			PidsLimit: -1,
+			Devices: []*configs.Device{
+				{Type: 'a', Major: configs.Wildcard, Minor: configs.Wildcard, Permissions: "rwm"},
+				{Type: 'c', Major: 1, Minor: 3, Permissions: "rwm", Allow: true},
+			},
+		},
+	}
+	properties, ok := genUpdateProperties(c)
+	if !ok {
+		t.Fatal("expected the device rules to be expressed as properties")
+	}
+	values := propertyValues(properties)
+	if values["TasksMax"] != uint64(math.MaxUint64) {
+		t.Errorf("expected the tasks limit to be lifted, got %v", values["TasksMax"])
+	}
+	if values["DevicePolicy"] != "strict" {
+		t.Errorf("expected the strict device policy, got %v", values["DevicePolicy"])
+	}
+}
+
+func TestGenDeviceProperties(t *testing.T) {
+	deny := &configs.Device{Type: 'a', Major: configs.Wildcard, Minor: configs.Wildcard, Permissions: "rwm"}
+	testCases := []struct {
+		name     string
+		rules    []*configs.Device
+		ok       bool
+		expected []deviceAllow
+	}{
+		{
+			name: "no rules",
+		},
+		{
+			name:     "deny all",
+			rules:    []*configs.Device{deny},
+			ok:       true,
+			expected: []deviceAllow{},
+		},
+		{
+			name: "single devices",
+			rules: []*configs.Device{
+				deny,
+				{Type: 'c', Major: 1, Minor: 3, Permissions: "rwm", Allow: true},
+				{Type: 'b', Major: 8, Minor: 0, Permissions: "r", Allow: true},
+			},
+			ok: true,
+			expected: []deviceAllow{
+				{Path: "/dev/char/1:3", Perms: "rwm"},
+				{Path: "/dev/block/8:0", Perms: "r"},
+			},
+		},
+		{
+			// The rules which specconv adds to every config.
+			name: "wildcards",
+			rules: []*configs.Device{
+				deny,
+				{Type: 'c', Major: configs.Wildcard, Minor: configs.Wildcard, Permissions: "m", Allow: true},
+				{Type: 'b', Major: configs.Wildcard, Minor: configs.Wildcard, Permissions: "m", Allow: true},
+				{Type: 'c', Major: 5, Minor: 0, Permissions: "rwm", Allow: true},
+			},
+			ok: true,
+			expected: []deviceAllow{
+				{Path: "char-*", Perms: "m"},
+				{Path: "block-*", Perms: "m"},
+				{Path: "/dev/char/5:0", Perms: "rwm"},
+			},
+		},
+		{
+			name: "wildcard major",
+			rules: []*configs.Device{
+				deny,
+				{Type: 'c', Major: configs.Wildcard, Minor: 3, Permissions: "rwm", Allow: true},
+			},
+		},
+		{
+			name: "not denied first",
+			rules: []*configs.Device{
+				{Type: 'c', Major: 1, Minor: 3, Permissions: "rwm", Allow: true},
+			},
+		},
+		{
+			name: "deny after allow",
+			rules: []*configs.Device{
+				deny,
+				{Type: 'c', Major: 1, Minor: 3, Permissions: "rwm", Allow: true},
+				{Type: 'c', Major: 1, Minor: 3, Permissions: "rwm"},
+			},
+		},
+		{
+			name: "allow all",
+			rules: []*configs.Device{
+				deny,
+				{Type: 'a', Major: configs.Wildcard, Minor: configs.Wildcard, Permissions: "rwm", Allow: true},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		properties, ok := genDeviceProperties(tc.rules)
+		if ok != tc.ok {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.ok, ok)
+			continue
+		}
+		if !ok {
+			if properties != nil {
+				t.Errorf("%s: expected no properties, got %v", tc.name, properties)
+			}
+			continue
+		}
+		values := propertyValues(properties)
+		if values["DevicePolicy"] != "strict" {
+			t.Errorf("%s: expected the strict device policy, got %v", tc.name, values["DevicePolicy"])
+		}
+		if got := values["DeviceAllow"]; !reflect.DeepEqual(got, tc.expected) {
+			t.Errorf("%s: expected DeviceAllow %v, got %v", tc.name, tc.expected, got)
+		}
+	}
+}
+
+func TestGenDevicePropertiesResetsDeviceAllow(t *testing.T) {
+	rules := []*configs.Device{
+		{Type: 'a', Major: configs.Wildcard, Minor: configs.Wildcard, Permissions: "rwm"},
+		{Type: 'c', Major: 1, Minor: 3, Permissions: "rwm", Allow: true},
+	}
+	properties, ok := genDeviceProperties(rules)
+	if !ok {
+		t.Fatal("expected the rules to be expressed as properties")
+	}
+	var names []string
+	for _, p := range properties {
+		names = append(names, p.Name)
+	}
+	expected := []string{"DevicePolicy", "DeviceAllow", "DeviceAllow"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Fatalf("expected the properties %v, got %v", expected, names)
+	}
+	if got := properties[1].Value.Value(); !reflect.DeepEqual(got, []deviceAllow{}) {
+		t.Errorf("expected DeviceAllow to be reset first, got %v", got)
+	}
+	if got := properties[2].Value.Value(); !reflect.DeepEqual(got, []deviceAllow{{Path: "/dev/char/1:3", Perms: "rwm"}}) {
+		t.Errorf("expected DeviceAllow to be set after the reset, got %v", got)
+	}
+}
+
+func TestParseDeviceGroup(t *testing.T) {
+	const devices = `Character devices:
+  1 mem
+  5 /dev/tty
+136 pts
+
+Block devices:
+  8 sd
+259 blkext
+`
+	testCases := []struct {
+		kind     string
+		major    int64
+		expected string
+	}{
+		{"char", 136, "pts"},
+		{"char", 1, "mem"},
+		{"block", 8, "sd"},
+		{"block", 136, ""},
+		{"char", 8, ""},
+	}
+	for _, tc := range testCases {
+		name, err := parseDeviceGroup(strings.NewReader(devices), tc.kind, tc.major)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if name != tc.expected {
+			t.Errorf("%s %d: expected %q, got %q", tc.kind, tc.major, tc.expected, name)
+		}
+	}
+}
+
+func TestWithoutSystemdResources(t *testing.T) {
+	devices := []*configs.Device{
+		{Type: 'a', Major: configs.Wildcard, Minor: configs.Wildcard, Permissions: "rwm"},
+	}
+	testCases := []struct {
+		name      string
+		resources configs.Resources
+		devices   bool
+		expected  configs.Resources
+	}{
+		{
+			name: "applied by systemd",
+			resources: configs.Resources{
+				Memory:      1 << 20,
+				CpuShares:   512,
+				CpuQuota:    5000,
+				BlkioWeight: 100,
+				PidsLimit:   10,
+				Devices:     devices,
+			},
+			devices: true,
+			expected: configs.Resources{
+				CpuQuota: 5000,
+			},
+		},
+		{
+			name: "memory with swap and devices",
+			resources: configs.Resources{
+				Memory:     1 << 20,
+				MemorySwap: 2 << 20,
+				Devices:    devices,
+			},
+			expected: configs.Resources{
+				Memory:     1 << 20,
+				MemorySwap: 2 << 20,
+				Devices:    devices,
+			},
+		},
+	}
+	for _, tc := range testCases {
+		resources := tc.resources
+		c := &configs.Cgroup{Name: "test", Resources: &resources}
+		got := withoutSystemdResources(c, tc.devices)
+		if !reflect.DeepEqual(*got.Resources, tc.expected) {
+			t.Errorf("%s: expected resources %+v, got %+v", tc.name, tc.expected, *got.Resources)
+		}
+		if got.Name != c.Name {
+			t.Errorf("%s: expected the cgroup to be copied, got name %q", tc.name, got.Name)
+		}
+		if !reflect.DeepEqual(resources, tc.resources) {
+			t.Errorf("%s: expected the original resources to be unchanged", tc.name)
+		}
+	}
+}
+
+func TestRootlessUnappliedLimits(t *testing.T) {
+	testCases := []struct {
+		name      string
+		resources configs.Resources
+		paths     map[string]string
+		expected  []string
+	}{
+		{
+			name:      "no limits",
+			resources: configs.Resources{},
+			paths:     map[string]string{},
+		},
+		{
+			name: "delegated",
+			resources: configs.Resources{
+				Memory:    1 << 20,
+				PidsLimit: 10,
+			},
+			paths: map[string]string{
+				"memory": "/sys/fs/cgroup/memory/user.slice/test.scope",
+				"pids":   "/sys/fs/cgroup/pids/user.slice/test.scope",
+			},
+		},
+		{
+			name: "not delegated",
+			resources: configs.Resources{
+				Memory:     1 << 20,
+				PidsLimit:  10,
+				CpuShares:  512,
+				CpusetCpus: "0",
+			},
+			paths: map[string]string{
+				"cpu": "/sys/fs/cgroup/cpu/user.slice/test.scope",
+			},
+			expected: []string{"cpuset", "memory", "pids"},
+		},
+	}
+	for _, tc := range testCases {
+		resources := tc.resources
+		got := rootlessUnappliedLimits(&resources, tc.paths)
+		if !reflect.DeepEqual(got, tc.expected) {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.expected, got)
+		}
+	}
+}
+
+func TestGetUserManagerCgroupCached(t *testing.T) {
+	oldQuery := queryUserManagerCgroup
+	defer func() {
+		queryUserManagerCgroup = oldQuery
+		userManagerCgroup = ""
+	}()
+	calls := 0
+	queryUserManagerCgroup = func() (string, error) {
+		calls++
+		return "/user.slice/user-1000.slice/user@1000.service/init.scope", nil
+	}
+	userManagerCgroup = ""
+	for i := 0; i < 3; i++ {
+		cgroup, err := getUserManagerCgroup()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cgroup != "/user.slice/user-1000.slice/user@1000.service/init.scope" {
+			t.Fatalf("unexpected cgroup %q", cgroup)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("expected the user instance to be queried once, got %d queries", calls)
+	}
+}
+
+func TestIsIgnorableError(t *testing.T) {
+	testCases := []struct {
+		rootless bool
+		err      error
+		expected bool
+	}{
+		{false, &os.PathError{Op: "open", Path: "/sys/fs/cgroup/memory", Err: unix.EACCES}, false},
+		{true, &os.PathError{Op: "open", Path: "/sys/fs/cgroup/memory", Err: unix.EACCES}, true},
+		{true, &os.PathError{Op: "open", Path: "/sys/fs/cgroup/memory", Err: unix.EROFS}, true},
+		{true, &os.PathError{Op: "open", Path: "/sys/fs/cgroup/memory", Err: unix.ENOENT}, false},
+		{true, errors.New("other"), false},
+	}
+	for _, tc := range testCases {
+		if got := isIgnorableError(tc.rootless, tc.err); got != tc.expected {
+			t.Errorf("rootless %v, %v: expected %v, got %v", tc.rootless, tc.err, tc.expected, got)
+		}
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"time"
 
 	units "github.com/docker/go-units"
+	"golang.org/x/sys/unix"
 )
 
 const (
@@ -21,6 +22,19 @@ const (
 	CgroupProcesses  = "cgroup.procs"
 )
 
+// cgroup2SuperMagic is the filesystem type of cgroup2, CGROUP2_SUPER_MAGIC.
+const cgroup2SuperMagic = 0x63677270
+
+// IsCgroup2UnifiedMode returns true if the host mounts the unified cgroup2
+// hierarchy at /sys/fs/cgroup instead of the v1 hierarchies.
+func IsCgroup2UnifiedMode() bool {
+	var st unix.Statfs_t
+	if err := unix.Statfs("/sys/fs/cgroup", &st); err != nil {
+		return false
+	}
+	return st.Type == cgroup2SuperMagic
+}
+
 // https://www.kernel.org/doc/Documentation/cgroup-v1/cgroups.txt
 func FindCgroupMountpoint(cgroupPath, subsystem string) (string, error) {
 	mnt, _, err := FindCgroupMountpointAndRoot(cgroupPath, subsystem)
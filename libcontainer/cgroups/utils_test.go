@@ -421,3 +421,14 @@ func TestFindCgroupMountpointAndRoot(t *testing.T) {
 		}
 	}
 }
+
+func TestIsCgroup2UnifiedMode(t *testing.T) {
+	// A unified host has no v1 hierarchies.
+	mounts, err := GetCgroupMounts(false)
+	if err != nil && !IsCgroup2UnifiedMode() {
+		t.Fatal(err)
+	}
+	if IsCgroup2UnifiedMode() == (len(mounts) > 0) {
+		t.Fatalf("expected the unified mode to be reported without v1 hierarchies, got %d", len(mounts))
+	}
+}
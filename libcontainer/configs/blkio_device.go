@@ -59,3 +59,24 @@ func NewThrottleDevice(major, minor int64, rate uint64) *ThrottleDevice {
 func (td *ThrottleDevice) String() string {
 	return fmt.Sprintf("%d:%d %d", td.Major, td.Minor, td.Rate)
 }
+
+// LatencyDevice struct holds a `major:minor target=latency` pair
+type LatencyDevice struct {
+	blockIODevice
+	// Target is the IO latency target for the device, in microseconds
+	Target uint64 `json:"target"`
+}
+
+// NewLatencyDevice returns a configured LatencyDevice pointer
+func NewLatencyDevice(major, minor int64, target uint64) *LatencyDevice {
+	ld := &LatencyDevice{}
+	ld.Major = major
+	ld.Minor = minor
+	ld.Target = target
+	return ld
+}
+
+// String formats the struct to be writable to the cgroup specific file
+func (ld *LatencyDevice) String() string {
+	return fmt.Sprintf("%d:%d target=%d", ld.Major, ld.Minor, ld.Target)
+}
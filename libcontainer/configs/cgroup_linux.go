@@ -1,5 +1,9 @@
 package configs
 
+import (
+	systemdDbus "github.com/coreos/go-systemd/dbus"
+)
+
 type FreezerState string
 
 const (
@@ -29,6 +33,11 @@ type Cgroup struct {
 
 	// Resources contains various cgroups settings to apply
 	*Resources
+
+	// SystemdProps are any additional properties for the systemd unit of the
+	// container, which are set in addition to the ones derived from Resources.
+	// They are only used by the systemd cgroup driver.
+	SystemdProps []systemdDbus.Property `json:"-"`
 }
 
 type Resources struct {
@@ -51,6 +60,18 @@ type Resources struct {
 	// Total memory usage (memory + swap); set `-1` to enable unlimited swap
 	MemorySwap int64 `json:"memory_swap"`
 
+	// Memory usage throttle limit (in bytes); set `-1` for no limit. Above it
+	// the processes of the cgroup are throttled and put under heavy reclaim
+	// pressure. On cgroup v1 it is emulated with the soft limit, which is only
+	// enforced under global memory pressure.
+	MemoryHigh int64 `json:"memory_high,omitempty"`
+
+	// Best-effort memory protection (in bytes); set `-1` to protect all memory
+	MemoryLow int64 `json:"memory_low,omitempty"`
+
+	// Hard memory protection (in bytes); set `-1` to protect all memory
+	MemoryMin int64 `json:"memory_min,omitempty"`
+
 	// Kernel memory limit (in bytes)
 	KernelMemory int64 `json:"kernel_memory"`
 
@@ -102,6 +123,17 @@ type Resources struct {
 	// IO write rate limit per cgroup per device, IO per second.
 	BlkioThrottleWriteIOPSDevice []*ThrottleDevice `json:"blkio_throttle_write_iops_device"`
 
+	// IO latency target per cgroup per device, in microseconds (io.latency).
+	// It requires cgroup v2.
+	BlkioLatencyTargetDevice []*LatencyDevice `json:"blkio_latency_target_device,omitempty"`
+
+	// Specifies per cgroup weight for the io.cost controller, range is from 1 to 10000 (io.weight).
+	// It requires cgroup v2.
+	IoCostWeight uint16 `json:"io_cost_weight,omitempty"`
+
+	// Weight per cgroup per device for the io.cost controller, can override IoCostWeight.
+	IoCostWeightDevice []*WeightDevice `json:"io_cost_weight_device,omitempty"`
+
 	// set the freeze value for the process
 	Freezer FreezerState `json:"freezer"`
 
@@ -111,6 +143,10 @@ type Resources struct {
 	// Whether to disable OOM Killer
 	OomKillDisable bool `json:"oom_kill_disable"`
 
+	// Whether the OOM killer kills all processes of the cgroup at once
+	// instead of a single one
+	MemoryOomGroup bool `json:"memory_oom_group,omitempty"`
+
 	// Tuning swappiness behaviour per cgroup
 	MemorySwappiness *uint64 `json:"memory_swappiness"`
 
@@ -18,6 +18,59 @@ type Rlimit struct {
 	Soft uint64 `json:"soft"`
 }
 
+// TimeOffset is the offset of a clock in a time namespace.
+type TimeOffset struct {
+	Secs     int64  `json:"secs"`
+	Nanosecs uint32 `json:"nanosecs"`
+}
+
+// Scheduler is the scheduling policy and attributes of a process, as set by
+// sched_setattr(2).
+type Scheduler struct {
+	// Policy is one of SCHED_OTHER, SCHED_FIFO, SCHED_RR, SCHED_BATCH,
+	// SCHED_IDLE or SCHED_DEADLINE.
+	Policy string `json:"policy"`
+
+	// Nice is the nice value of a SCHED_OTHER or SCHED_BATCH process.
+	Nice int32 `json:"nice,omitempty"`
+
+	// Priority is the static priority of a SCHED_FIFO or SCHED_RR process,
+	// between 1 and 99.
+	Priority int32 `json:"priority,omitempty"`
+
+	// Flags are SCHED_FLAG_* flags, e.g. SCHED_FLAG_RESET_ON_FORK.
+	Flags []string `json:"flags,omitempty"`
+
+	// Runtime, Deadline and Period are the parameters, in nanoseconds, of a
+	// SCHED_DEADLINE process.
+	Runtime  uint64 `json:"runtime,omitempty"`
+	Deadline uint64 `json:"deadline,omitempty"`
+	Period   uint64 `json:"period,omitempty"`
+}
+
+// IOPriority is the I/O scheduling class and priority of a process, as set by
+// ioprio_set(2).
+type IOPriority struct {
+	// Class is one of IOPRIO_CLASS_RT, IOPRIO_CLASS_BE or IOPRIO_CLASS_IDLE.
+	Class string `json:"class"`
+
+	// Priority is the level within the class, between 0 (highest) and 7.
+	Priority int `json:"priority,omitempty"`
+}
+
+// Execution domains of personality(2).
+const (
+	PerLinux   = 0x0000
+	PerLinux32 = 0x0008
+)
+
+// LinuxPersonality is the execution domain of the processes of a container.
+type LinuxPersonality struct {
+	// Domain is PerLinux or PerLinux32. With PerLinux32 uname(2) reports a
+	// 32-bit machine, e.g. i686 instead of x86_64.
+	Domain int `json:"domain"`
+}
+
 // IDMap represents UID/GID Mappings for User Namespaces.
 type IDMap struct {
 	ContainerID int `json:"container_id"`
@@ -90,6 +143,11 @@ type Config struct {
 	// Path to a directory containing the container's root filesystem.
 	Rootfs string `json:"rootfs"`
 
+	// RootfsOverlay, if set, describes an overlay filesystem which is assembled
+	// from image layers and mounted on top of Rootfs before the process is
+	// jailed into it. Rootfs is then only used as the mount point.
+	RootfsOverlay *Overlay `json:"rootfs_overlay,omitempty"`
+
 	// Readonlyfs will remount the container's rootfs as readonly where only externally mounted
 	// bind mounts are writtable.
 	Readonlyfs bool `json:"readonlyfs"`
@@ -131,6 +189,11 @@ type Config struct {
 	// change at the time the process is execed
 	AppArmorProfile string `json:"apparmor_profile,omitempty"`
 
+	// AppArmorProfileContent is the profile named AppArmorProfile. It is
+	// loaded when the container is created if no profile of that name is
+	// loaded yet, and then unloaded when the container is destroyed.
+	AppArmorProfileContent string `json:"apparmor_profile_content,omitempty"`
+
 	// ProcessLabel specifies the label to apply to the process running in the container.  It is
 	// commonly used by selinux
 	ProcessLabel string `json:"process_label,omitempty"`
@@ -146,12 +209,28 @@ type Config struct {
 	// More information about kernel oom score calculation here: https://lwn.net/Articles/317814/
 	OomScoreAdj *int `json:"oom_score_adj,omitempty"`
 
+	// Scheduler sets the scheduling policy of the container's init process.
+	// If it is unset the process inherits the policy of runc.
+	Scheduler *Scheduler `json:"scheduler,omitempty"`
+
+	// IOPriority sets the I/O priority of the container's init process.
+	IOPriority *IOPriority `json:"io_priority,omitempty"`
+
+	// CPUAffinity is the initial list of CPUs the container's init process
+	// may run on, in the format of cpuset.cpus, e.g. "0-3,7".
+	CPUAffinity string `json:"cpu_affinity,omitempty"`
+
 	// UidMappings is an array of User ID mappings for User Namespaces
 	UidMappings []IDMap `json:"uid_mappings"`
 
 	// GidMappings is an array of Group ID mappings for User Namespaces
 	GidMappings []IDMap `json:"gid_mappings"`
 
+	// TimeOffsets specifies the offsets of the clocks in the time namespace of
+	// the container from the host's clocks, keyed by clock name ("monotonic"
+	// or "boottime"). They require a new time namespace.
+	TimeOffsets map[string]TimeOffset `json:"time_offsets,omitempty"`
+
 	// MaskPaths specifies paths within the container's rootfs to mask over with a bind
 	// mount pointing to /dev/null as to prevent reads of the file.
 	MaskPaths []string `json:"mask_paths"`
@@ -172,6 +251,13 @@ type Config struct {
 	// NoNewPrivileges controls whether processes in the container can gain additional privileges.
 	NoNewPrivileges bool `json:"no_new_privileges,omitempty"`
 
+	// Landlock restricts the filesystem access of the processes in the container.
+	// It requires NoNewPrivileges.
+	Landlock *Landlock `json:"landlock,omitempty"`
+
+	// Personality sets the execution domain of the processes in the container.
+	Personality *LinuxPersonality `json:"personality,omitempty"`
+
 	// Hooks are a collection of actions to perform at various container lifecycle events.
 	// CommandHooks are serialized to JSON, but other hooks are not.
 	Hooks *Hooks
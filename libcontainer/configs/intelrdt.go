@@ -1,6 +1,11 @@
 package configs
 
 type IntelRdt struct {
+	// The identity for RDT Class of Service. If set, the container joins the
+	// group of this name instead of a group named after the container ID,
+	// so that several containers can share a single CLOS.
+	ClosID string `json:"closID,omitempty"`
+
 	// The schema for L3 cache id and capacity bitmask (CBM)
 	// Format: "L3:<cache_id0>=<cbm0>;<cache_id1>=<cbm1>;..."
 	L3CacheSchema string `json:"l3_cache_schema,omitempty"`
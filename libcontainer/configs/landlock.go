@@ -0,0 +1,26 @@
+package configs
+
+// Landlock is a Landlock ruleset which restricts the filesystem access of the
+// processes of a container to the paths of its rules. Applying it requires
+// no_new_privs, unless the process has CAP_SYS_ADMIN.
+type Landlock struct {
+	// Rules are the paths the processes may access, each with the access
+	// rights it grants beneath the path.
+	Rules []*LandlockRule `json:"rules"`
+
+	// DisableBestEffort makes applying the ruleset fail if the kernel does
+	// not support Landlock or some of the access rights of the rules.
+	// Otherwise the unsupported rights are dropped, and the ruleset is not
+	// applied at all if Landlock is unavailable.
+	DisableBestEffort bool `json:"disable_best_effort,omitempty"`
+}
+
+// LandlockRule grants access rights beneath a path.
+type LandlockRule struct {
+	// Path is the path in the container, after pivot_root.
+	Path string `json:"path"`
+
+	// Access are the names of the access rights, e.g. "execute",
+	// "read_file" or "make_dir", without the LANDLOCK_ACCESS_FS_ prefix.
+	Access []string `json:"access"`
+}
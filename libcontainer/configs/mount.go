@@ -4,6 +4,14 @@ const (
 	// EXT_COPYUP is a directive to copy up the contents of a directory when
 	// a tmpfs is mounted over it.
 	EXT_COPYUP = 1 << iota
+
+	// EXT_CHOWN is a directive to recursively change the owner of the source
+	// of a bind mount to the container's root user before it is mounted.
+	EXT_CHOWN
+
+	// EXT_IDMAP is a directive to create an idmapped bind mount, so that the
+	// ownership of the source is mapped into the container's user namespace.
+	EXT_IDMAP
 )
 
 type Mount struct {
@@ -22,6 +30,10 @@ type Mount struct {
 	// Propagation Flags
 	PropagationFlags []int `json:"propagation_flags"`
 
+	// RecursiveFlags are mount flags (MS_RDONLY, MS_NOSUID, MS_NODEV and
+	// MS_NOEXEC) which are applied to the mount and all of its submounts.
+	RecursiveFlags int `json:"recursive_flags,omitempty"`
+
 	// Mount data applied to the mount.
 	Data string `json:"data"`
 
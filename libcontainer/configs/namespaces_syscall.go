@@ -4,6 +4,9 @@ package configs
 
 import "golang.org/x/sys/unix"
 
+// cloneNewTime is CLONE_NEWTIME, which is missing in x/sys/unix.
+const cloneNewTime = 0x80
+
 func (n *Namespace) Syscall() int {
 	return namespaceInfo[n.Type]
 }
@@ -16,6 +19,7 @@ var namespaceInfo = map[NamespaceType]int{
 	NEWUTS:    unix.CLONE_NEWUTS,
 	NEWPID:    unix.CLONE_NEWPID,
 	NEWCGROUP: unix.CLONE_NEWCGROUP,
+	NEWTIME:   cloneNewTime,
 }
 
 // CloneFlags parses the container's Namespaces options to set the correct
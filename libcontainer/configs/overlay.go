@@ -0,0 +1,33 @@
+package configs
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Overlay describes an overlay filesystem which is assembled from a list of
+// image layers and mounted as the container's root filesystem.
+type Overlay struct {
+	// LowerDirs are the read-only layers of the overlay, ordered from the
+	// top-most layer to the bottom-most one (the same order as the overlay
+	// lowerdir= mount option).
+	LowerDirs []string `json:"lower_dirs"`
+
+	// UpperDir is the writable layer of the overlay. If it is empty the
+	// overlay is mounted read-only.
+	UpperDir string `json:"upper_dir,omitempty"`
+
+	// WorkDir is the overlay work directory. It is required if UpperDir is
+	// set and must be on the same filesystem as UpperDir.
+	WorkDir string `json:"work_dir,omitempty"`
+}
+
+// MountData returns the mount data for the overlay, in the format
+// understood by both the kernel overlay filesystem and fuse-overlayfs.
+func (o *Overlay) MountData() string {
+	data := fmt.Sprintf("lowerdir=%s", strings.Join(o.LowerDirs, ":"))
+	if o.UpperDir != "" {
+		data += fmt.Sprintf(",upperdir=%s,workdir=%s", o.UpperDir, o.WorkDir)
+	}
+	return data
+}
@@ -10,18 +10,15 @@ import (
 // rootlessEUID makes sure that the config can be applied when runc
 // is being executed as a non-root user (euid != 0) in the current user namespace.
 func (v *ConfigValidator) rootlessEUID(config *configs.Config) error {
-	if err := rootlessEUIDMappings(config); err != nil {
-		return err
-	}
-	if err := rootlessEUIDMount(config); err != nil {
-		return err
-	}
+	var errs fieldErrors
+	errs.add("", rootlessEUIDMappings(config))
+	errs.add("", rootlessEUIDMount(config))
 
 	// XXX: We currently can't verify the user config at all, because
 	//      configs.Config doesn't store the user-related configs. So this
 	//      has to be verified by setupUser() in init_linux.go.
 
-	return nil
+	return errs.err()
 }
 
 func hasIDMapping(id int, mappings []configs.IDMap) bool {
@@ -35,16 +32,17 @@ func hasIDMapping(id int, mappings []configs.IDMap) bool {
 
 func rootlessEUIDMappings(config *configs.Config) error {
 	if !config.Namespaces.Contains(configs.NEWUSER) {
-		return fmt.Errorf("rootless container requires user namespaces")
+		return newFieldError("Namespaces", "rootless container requires user namespaces")
 	}
 
+	var errs fieldErrors
 	if len(config.UidMappings) == 0 {
-		return fmt.Errorf("rootless containers requires at least one UID mapping")
+		errs.addf("UidMappings", "rootless containers requires at least one UID mapping")
 	}
 	if len(config.GidMappings) == 0 {
-		return fmt.Errorf("rootless containers requires at least one GID mapping")
+		errs.addf("GidMappings", "rootless containers requires at least one GID mapping")
 	}
-	return nil
+	return errs.err()
 }
 
 // mount verifies that the user isn't trying to set up any mounts they don't have
@@ -55,7 +53,9 @@ func rootlessEUIDMount(config *configs.Config) error {
 	//      convinced that's a good idea. The kernel is the best arbiter of
 	//      access control.
 
-	for _, mount := range config.Mounts {
+	var errs fieldErrors
+	for i, mount := range config.Mounts {
+		field := fmt.Sprintf("Mounts[%d].Data", i)
 		// Check that the options list doesn't contain any uid= or gid= entries
 		// that don't resolve to root.
 		for _, opt := range strings.Split(mount.Data, ",") {
@@ -67,7 +67,7 @@ func rootlessEUIDMount(config *configs.Config) error {
 					continue
 				}
 				if !hasIDMapping(uid, config.UidMappings) {
-					return fmt.Errorf("cannot specify uid= mount options for unmapped uid in rootless containers")
+					errs.addf(field, "cannot specify uid= mount options for unmapped uid in rootless containers")
 				}
 			}
 
@@ -79,11 +79,11 @@ func rootlessEUIDMount(config *configs.Config) error {
 					continue
 				}
 				if !hasIDMapping(gid, config.GidMappings) {
-					return fmt.Errorf("cannot specify gid= mount options for unmapped gid in rootless containers")
+					errs.addf(field, "cannot specify gid= mount options for unmapped gid in rootless containers")
 				}
 			}
 		}
 	}
 
-	return nil
+	return errs.err()
 }
@@ -4,10 +4,13 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
+	"github.com/opencontainers/runc/libcontainer/apparmor"
 	"github.com/opencontainers/runc/libcontainer/configs"
 	"github.com/opencontainers/runc/libcontainer/intelrdt"
+	"github.com/opencontainers/runc/libcontainer/landlock"
 	selinux "github.com/opencontainers/selinux/go-selinux"
 )
 
@@ -23,36 +26,132 @@ type ConfigValidator struct {
 }
 
 func (v *ConfigValidator) Validate(config *configs.Config) error {
-	if err := v.rootfs(config); err != nil {
-		return err
-	}
-	if err := v.network(config); err != nil {
-		return err
-	}
-	if err := v.hostname(config); err != nil {
-		return err
+	for _, c := range v.checks(config) {
+		if errs := checkErrors(c.name, c.fn(config)); len(errs) > 0 {
+			return errs[0].Err
+		}
 	}
-	if err := v.security(config); err != nil {
-		return err
+	return nil
+}
+
+// CheckError is an error found by one of the checks of the validator.
+type CheckError struct {
+	// Check is the name of the check, e.g. "rootfs" or "sysctl".
+	Check string
+	// Field is the setting of the config which causes the error, e.g.
+	// Mounts[3].Extensions or Sysctl["kernel.msgmax"]. It is empty when
+	// the error is not caused by the config.
+	Field string
+	Err   error
+}
+
+func (e *CheckError) Error() string {
+	return e.Err.Error()
+}
+
+// ValidateAll runs all the checks of the validator, instead of stopping at the
+// first error like Validate, and returns the errors they found. Unlike
+// Validate, each check reports all the settings it finds invalid.
+func (v *ConfigValidator) ValidateAll(config *configs.Config) []*CheckError {
+	var errs []*CheckError
+	for _, c := range v.checks(config) {
+		errs = append(errs, checkErrors(c.name, c.fn(config))...)
 	}
-	if err := v.usernamespace(config); err != nil {
-		return err
+	return errs
+}
+
+// checkErrors splits the error returned by the check name into the errors of
+// the settings it found invalid.
+func checkErrors(name string, err error) []*CheckError {
+	switch err := err.(type) {
+	case nil:
+		return nil
+	case *fieldError:
+		return []*CheckError{{Check: name, Field: err.field, Err: err.err}}
+	case fieldErrors:
+		errs := make([]*CheckError, 0, len(err))
+		for _, e := range err {
+			errs = append(errs, &CheckError{Check: name, Field: e.field, Err: e.err})
+		}
+		return errs
 	}
-	if err := v.cgroupnamespace(config); err != nil {
-		return err
+	return []*CheckError{{Check: name, Err: err}}
+}
+
+// fieldError is an error caused by a setting of the config.
+type fieldError struct {
+	field string
+	err   error
+}
+
+func newFieldError(field, format string, a ...interface{}) *fieldError {
+	return &fieldError{field: field, err: fmt.Errorf(format, a...)}
+}
+
+func (e *fieldError) Error() string {
+	return e.err.Error()
+}
+
+// fieldErrors are the errors of a check which keeps going after the first
+// setting it finds invalid. Its message is the one of the first error.
+type fieldErrors []*fieldError
+
+func (e fieldErrors) Error() string {
+	return e[0].Error()
+}
+
+// add appends the error of field, it splits errors of several settings.
+func (e *fieldErrors) add(field string, err error) {
+	switch err := err.(type) {
+	case nil:
+	case *fieldError:
+		*e = append(*e, err)
+	case fieldErrors:
+		*e = append(*e, err...)
+	default:
+		*e = append(*e, &fieldError{field: field, err: err})
 	}
-	if err := v.sysctl(config); err != nil {
-		return err
+}
+
+func (e *fieldErrors) addf(field, format string, a ...interface{}) {
+	*e = append(*e, newFieldError(field, format, a...))
+}
+
+// err returns the errors as an error, nil if there are none.
+func (e fieldErrors) err() error {
+	if len(e) == 0 {
+		return nil
 	}
-	if err := v.intelrdt(config); err != nil {
-		return err
+	return e
+}
+
+type check struct {
+	name string
+	fn   func(*configs.Config) error
+}
+
+// checks returns the checks which apply to config, in the order they are run.
+func (v *ConfigValidator) checks(config *configs.Config) []check {
+	checks := []check{
+		{"rootfs", v.rootfs},
+		{"rootfsOverlay", v.rootfsOverlay},
+		{"mounts", v.mounts},
+		{"network", v.network},
+		{"hostname", v.hostname},
+		{"security", v.security},
+		{"usernamespace", v.usernamespace},
+		{"cgroupnamespace", v.cgroupnamespace},
+		{"timenamespace", v.timenamespace},
+		{"scheduling", v.scheduling},
+		{"personality", v.personality},
+		{"landlock", v.landlock},
+		{"sysctl", v.sysctl},
+		{"intelrdt", v.intelrdt},
 	}
 	if config.RootlessEUID {
-		if err := v.rootlessEUID(config); err != nil {
-			return err
-		}
+		checks = append(checks, check{"rootlessEUID", v.rootlessEUID})
 	}
-	return nil
+	return checks
 }
 
 // rootfs validates if the rootfs is an absolute path and is not a symlink
@@ -60,27 +159,89 @@ func (v *ConfigValidator) Validate(config *configs.Config) error {
 func (v *ConfigValidator) rootfs(config *configs.Config) error {
 	if _, err := os.Stat(config.Rootfs); err != nil {
 		if os.IsNotExist(err) {
-			return fmt.Errorf("rootfs (%s) does not exist", config.Rootfs)
+			return newFieldError("Rootfs", "rootfs (%s) does not exist", config.Rootfs)
 		}
-		return err
+		return &fieldError{field: "Rootfs", err: err}
 	}
 	cleaned, err := filepath.Abs(config.Rootfs)
 	if err != nil {
-		return err
+		return &fieldError{field: "Rootfs", err: err}
 	}
 	if cleaned, err = filepath.EvalSymlinks(cleaned); err != nil {
-		return err
+		return &fieldError{field: "Rootfs", err: err}
 	}
 	if filepath.Clean(config.Rootfs) != cleaned {
-		return fmt.Errorf("%s is not an absolute path or is a symlink", config.Rootfs)
+		return newFieldError("Rootfs", "%s is not an absolute path or is a symlink", config.Rootfs)
 	}
 	return nil
 }
 
+// rootfsOverlay validates that the layers of an overlay rootfs are absolute
+// paths and that a work directory is given together with the upper directory.
+func (v *ConfigValidator) rootfsOverlay(config *configs.Config) error {
+	overlay := config.RootfsOverlay
+	if overlay == nil {
+		return nil
+	}
+	var errs fieldErrors
+	if len(overlay.LowerDirs) == 0 {
+		errs.addf("RootfsOverlay.LowerDirs", "rootfs overlay requires at least one lower directory")
+	}
+	for i, dir := range overlay.LowerDirs {
+		field := fmt.Sprintf("RootfsOverlay.LowerDirs[%d]", i)
+		if !filepath.IsAbs(dir) {
+			errs.addf(field, "rootfs overlay lower directory %q is not an absolute path", dir)
+		}
+		if strings.ContainsAny(dir, ":,") {
+			errs.addf(field, "rootfs overlay lower directory %q must not contain ':' or ','", dir)
+		}
+	}
+	if overlay.UpperDir != "" && overlay.WorkDir == "" {
+		errs.addf("RootfsOverlay.WorkDir", "rootfs overlay upper and work directories must be specified together")
+	}
+	if overlay.UpperDir == "" && overlay.WorkDir != "" {
+		errs.addf("RootfsOverlay.UpperDir", "rootfs overlay upper and work directories must be specified together")
+	}
+	for _, d := range []struct {
+		field string
+		dir   string
+	}{
+		{"RootfsOverlay.UpperDir", overlay.UpperDir},
+		{"RootfsOverlay.WorkDir", overlay.WorkDir},
+	} {
+		if d.dir != "" && !filepath.IsAbs(d.dir) {
+			errs.addf(d.field, "rootfs overlay directory %q is not an absolute path", d.dir)
+		}
+		if strings.ContainsAny(d.dir, ":,") {
+			errs.addf(d.field, "rootfs overlay directory %q must not contain ':' or ','", d.dir)
+		}
+	}
+	return errs.err()
+}
+
+// mounts validates that the ownership options of the mounts are only used
+// on bind mounts, and that idmapped mounts have a user namespace to map into.
+func (v *ConfigValidator) mounts(config *configs.Config) error {
+	var errs fieldErrors
+	for i, m := range config.Mounts {
+		if m.Extensions&(configs.EXT_CHOWN|configs.EXT_IDMAP) == 0 {
+			continue
+		}
+		if m.Device != "bind" {
+			errs.addf(fmt.Sprintf("Mounts[%d].Extensions", i), "mount %q: ownership options are only supported for bind mounts", m.Destination)
+			continue
+		}
+		if m.Extensions&configs.EXT_IDMAP == configs.EXT_IDMAP && !config.Namespaces.Contains(configs.NEWUSER) {
+			errs.addf(fmt.Sprintf("Mounts[%d].Extensions", i), "mount %q: idmapped mounts require a USER namespace", m.Destination)
+		}
+	}
+	return errs.err()
+}
+
 func (v *ConfigValidator) network(config *configs.Config) error {
 	if !config.Namespaces.Contains(configs.NEWNET) {
 		if len(config.Networks) > 0 || len(config.Routes) > 0 {
-			return fmt.Errorf("unable to apply network settings without a private NET namespace")
+			return newFieldError("Namespaces", "unable to apply network settings without a private NET namespace")
 		}
 	}
 	return nil
@@ -88,33 +249,50 @@ func (v *ConfigValidator) network(config *configs.Config) error {
 
 func (v *ConfigValidator) hostname(config *configs.Config) error {
 	if config.Hostname != "" && !config.Namespaces.Contains(configs.NEWUTS) {
-		return fmt.Errorf("unable to set hostname without a private UTS namespace")
+		return newFieldError("Hostname", "unable to set hostname without a private UTS namespace")
 	}
 	return nil
 }
 
 func (v *ConfigValidator) security(config *configs.Config) error {
+	var errs fieldErrors
 	// restrict sys without mount namespace
-	if (len(config.MaskPaths) > 0 || len(config.ReadonlyPaths) > 0) &&
-		!config.Namespaces.Contains(configs.NEWNS) {
-		return fmt.Errorf("unable to restrict sys entries without a private MNT namespace")
+	if !config.Namespaces.Contains(configs.NEWNS) {
+		if len(config.MaskPaths) > 0 {
+			errs.addf("MaskPaths", "unable to restrict sys entries without a private MNT namespace")
+		}
+		if len(config.ReadonlyPaths) > 0 {
+			errs.addf("ReadonlyPaths", "unable to restrict sys entries without a private MNT namespace")
+		}
 	}
 	if config.ProcessLabel != "" && !selinux.GetEnabled() {
-		return fmt.Errorf("selinux label is specified in config, but selinux is disabled or not supported")
+		errs.addf("ProcessLabel", "selinux label is specified in config, but selinux is disabled or not supported")
 	}
-
-	return nil
+	if config.AppArmorProfileContent != "" {
+		if config.AppArmorProfile == "" {
+			errs.addf("AppArmorProfile", "apparmor profile content is specified in config, but the profile name is not")
+		}
+		if !apparmor.IsEnabled() {
+			errs.addf("AppArmorProfileContent", "apparmor profile content is specified in config, but apparmor is disabled or not supported")
+		}
+	}
+	return errs.err()
 }
 
 func (v *ConfigValidator) usernamespace(config *configs.Config) error {
 	if config.Namespaces.Contains(configs.NEWUSER) {
 		if _, err := os.Stat("/proc/self/ns/user"); os.IsNotExist(err) {
-			return fmt.Errorf("USER namespaces aren't enabled in the kernel")
+			return newFieldError("Namespaces", "USER namespaces aren't enabled in the kernel")
 		}
 	} else {
-		if config.UidMappings != nil || config.GidMappings != nil {
-			return fmt.Errorf("User namespace mappings specified, but USER namespace isn't enabled in the config")
+		var errs fieldErrors
+		if config.UidMappings != nil {
+			errs.addf("UidMappings", "User namespace mappings specified, but USER namespace isn't enabled in the config")
+		}
+		if config.GidMappings != nil {
+			errs.addf("GidMappings", "User namespace mappings specified, but USER namespace isn't enabled in the config")
 		}
+		return errs.err()
 	}
 	return nil
 }
@@ -122,12 +300,170 @@ func (v *ConfigValidator) usernamespace(config *configs.Config) error {
 func (v *ConfigValidator) cgroupnamespace(config *configs.Config) error {
 	if config.Namespaces.Contains(configs.NEWCGROUP) {
 		if _, err := os.Stat("/proc/self/ns/cgroup"); os.IsNotExist(err) {
-			return fmt.Errorf("cgroup namespaces aren't enabled in the kernel")
+			return newFieldError("Namespaces", "cgroup namespaces aren't enabled in the kernel")
 		}
 	}
 	return nil
 }
 
+func (v *ConfigValidator) timenamespace(config *configs.Config) error {
+	var errs fieldErrors
+	if config.Namespaces.Contains(configs.NEWTIME) {
+		if _, err := os.Stat("/proc/self/ns/time"); os.IsNotExist(err) {
+			errs.addf("Namespaces", "time namespaces aren't enabled in the kernel")
+		}
+	}
+	if len(config.TimeOffsets) == 0 {
+		return errs.err()
+	}
+	if !config.Namespaces.Contains(configs.NEWTIME) {
+		errs.addf("TimeOffsets", "time offsets specified, but time namespace isn't enabled in the config")
+	} else if config.Namespaces.PathOf(configs.NEWTIME) != "" {
+		errs.addf("TimeOffsets", "time offsets cannot be set when joining an existing time namespace")
+	}
+	clocks := make([]string, 0, len(config.TimeOffsets))
+	for clock := range config.TimeOffsets {
+		clocks = append(clocks, clock)
+	}
+	sort.Strings(clocks)
+	for _, clock := range clocks {
+		field := fmt.Sprintf("TimeOffsets[%q]", clock)
+		if clock != "monotonic" && clock != "boottime" {
+			errs.addf(field, "time offset of unknown clock %q", clock)
+		}
+		if config.TimeOffsets[clock].Nanosecs >= 1e9 {
+			errs.addf(field, "time offset of clock %q has more than 999999999 nanoseconds", clock)
+		}
+	}
+	return errs.err()
+}
+
+// scheduling validates the scheduling policy, I/O priority and CPU affinity
+// of the init process.
+func (v *ConfigValidator) scheduling(config *configs.Config) error {
+	return Scheduling(config.Scheduler, config.IOPriority, config.CPUAffinity)
+}
+
+// Scheduling validates the scheduling policy, I/O priority and CPU affinity
+// a process is started with. It is used for the init process as well as for
+// processes executed in a running container. The error describes the first
+// invalid setting.
+func Scheduling(scheduler *configs.Scheduler, ioPriority *configs.IOPriority, cpuAffinity string) error {
+	var errs fieldErrors
+	if s := scheduler; s != nil {
+		if s.Nice < -20 || s.Nice > 19 {
+			errs.addf("Scheduler", "scheduler nice value %d is out of range [-20, 19]", s.Nice)
+		}
+		switch s.Policy {
+		case "SCHED_FIFO", "SCHED_RR":
+			if s.Priority < 1 || s.Priority > 99 {
+				errs.addf("Scheduler", "scheduler priority %d of %s is out of range [1, 99]", s.Priority, s.Policy)
+			}
+		case "SCHED_OTHER", "SCHED_BATCH", "SCHED_IDLE", "SCHED_DEADLINE":
+			if s.Priority != 0 {
+				errs.addf("Scheduler", "scheduler priority can only be set with SCHED_FIFO or SCHED_RR")
+			}
+		default:
+			errs.addf("Scheduler", "unknown scheduling policy %q", s.Policy)
+		}
+		if s.Policy == "SCHED_DEADLINE" {
+			period := s.Period
+			if period == 0 {
+				period = s.Deadline
+			}
+			if s.Runtime == 0 || s.Runtime > s.Deadline || s.Deadline > period {
+				errs.addf("Scheduler", "SCHED_DEADLINE requires 0 < runtime <= deadline <= period")
+			}
+		} else if s.Runtime != 0 || s.Deadline != 0 || s.Period != 0 {
+			errs.addf("Scheduler", "scheduler runtime, deadline and period can only be set with SCHED_DEADLINE")
+		}
+		for _, f := range s.Flags {
+			switch f {
+			case "SCHED_FLAG_RESET_ON_FORK", "SCHED_FLAG_RECLAIM", "SCHED_FLAG_DL_OVERRUN",
+				"SCHED_FLAG_KEEP_POLICY", "SCHED_FLAG_KEEP_PARAMS":
+			default:
+				errs.addf("Scheduler", "unknown scheduling flag %q", f)
+			}
+		}
+		// The kernel only admits SCHED_DEADLINE tasks which may run on
+		// all CPUs of their root domain.
+		if s.Policy == "SCHED_DEADLINE" && cpuAffinity != "" {
+			errs.addf("CPUAffinity", "SCHED_DEADLINE cannot be combined with a cpu affinity")
+		}
+	}
+	if p := ioPriority; p != nil {
+		switch p.Class {
+		case "IOPRIO_CLASS_RT", "IOPRIO_CLASS_BE", "IOPRIO_CLASS_IDLE":
+		default:
+			errs.addf("IOPriority", "unknown io priority class %q", p.Class)
+		}
+		if p.Priority < 0 || p.Priority > 7 {
+			errs.addf("IOPriority", "io priority %d is out of range [0, 7]", p.Priority)
+		}
+	}
+	return errs.err()
+}
+
+// personality validates the execution domain of the container. The 32-bit
+// domain is only useful to run 32-bit binaries, whose system calls are killed
+// by seccomp unless a 32-bit architecture is allowed.
+func (v *ConfigValidator) personality(config *configs.Config) error {
+	if config.Personality == nil {
+		return nil
+	}
+	switch config.Personality.Domain {
+	case configs.PerLinux:
+	case configs.PerLinux32:
+		if config.Seccomp == nil {
+			return nil
+		}
+		for _, arch := range config.Seccomp.Architectures {
+			switch arch {
+			case "x86", "arm", "mips", "mipsel", "ppc", "s390":
+				return nil
+			}
+		}
+		return newFieldError("Personality", "the LINUX32 personality requires a 32-bit architecture in the seccomp config")
+	default:
+		return newFieldError("Personality", "unknown personality domain %#x", config.Personality.Domain)
+	}
+	return nil
+}
+
+// landlock validates the rules of the Landlock ruleset. Whether the kernel
+// supports them is only known when they are applied, which is best-effort
+// by default.
+func (v *ConfigValidator) landlock(config *configs.Config) error {
+	if config.Landlock == nil {
+		return nil
+	}
+	var errs fieldErrors
+	// Without no_new_privs, restricting init requires CAP_SYS_ADMIN, which
+	// it may have dropped by then.
+	if !config.NoNewPrivileges {
+		errs.addf("NoNewPrivileges", "landlock requires noNewPrivileges to be set")
+	}
+	if config.Landlock.DisableBestEffort && !landlock.IsEnabled() {
+		errs.addf("Landlock.DisableBestEffort", "landlock is required by the config, but it is not supported by the kernel")
+	}
+	for i, r := range config.Landlock.Rules {
+		field := fmt.Sprintf("Landlock.Rules[%d]", i)
+		if r == nil {
+			errs.addf(field, "landlock rule is nil")
+			continue
+		}
+		if !filepath.IsAbs(r.Path) {
+			errs.addf(field, "landlock rule path %q is not absolute", r.Path)
+		}
+		if len(r.Access) == 0 {
+			errs.addf(field, "landlock rule for %s grants no access", r.Path)
+		} else if _, err := landlock.ParseAccess(r.Access); err != nil {
+			errs.add(field, err)
+		}
+	}
+	return errs.err()
+}
+
 // sysctl validates that the specified sysctl keys are valid or not.
 // /proc/sys isn't completely namespaced and depending on which namespaces
 // are specified, a subset of sysctls are permitted.
@@ -143,25 +479,28 @@ func (v *ConfigValidator) sysctl(config *configs.Config) error {
 		"kernel.shm_rmid_forced": true,
 	}
 
+	keys := make([]string, 0, len(config.Sysctl))
 	for s := range config.Sysctl {
+		keys = append(keys, s)
+	}
+	sort.Strings(keys)
+
+	var errs fieldErrors
+	for _, s := range keys {
+		field := fmt.Sprintf("Sysctl[%q]", s)
 		if validSysctlMap[s] || strings.HasPrefix(s, "fs.mqueue.") {
-			if config.Namespaces.Contains(configs.NEWIPC) {
-				continue
-			} else {
-				return fmt.Errorf("sysctl %q is not allowed in the hosts ipc namespace", s)
+			if !config.Namespaces.Contains(configs.NEWIPC) {
+				errs.addf(field, "sysctl %q is not allowed in the hosts ipc namespace", s)
 			}
+			continue
 		}
 		if strings.HasPrefix(s, "net.") {
-			if config.Namespaces.Contains(configs.NEWNET) {
-				if path := config.Namespaces.PathOf(configs.NEWNET); path != "" {
-					if err := checkHostNs(s, path); err != nil {
-						return err
-					}
-				}
-				continue
-			} else {
-				return fmt.Errorf("sysctl %q is not allowed in the hosts network namespace", s)
+			if !config.Namespaces.Contains(configs.NEWNET) {
+				errs.addf(field, "sysctl %q is not allowed in the hosts network namespace", s)
+			} else if path := config.Namespaces.PathOf(configs.NEWNET); path != "" {
+				errs.add(field, checkHostNs(s, path))
 			}
+			continue
 		}
 		if config.Namespaces.Contains(configs.NEWUTS) {
 			switch s {
@@ -170,39 +509,90 @@ func (v *ConfigValidator) sysctl(config *configs.Config) error {
 				continue
 			case "kernel.hostname":
 				// This is namespaced but there's a conflicting (dedicated) OCI field for it.
-				return fmt.Errorf("sysctl %q is not allowed as it conflicts with the OCI %q field", s, "hostname")
+				errs.addf(field, "sysctl %q is not allowed as it conflicts with the OCI %q field", s, "hostname")
+				continue
 			}
 		}
-		return fmt.Errorf("sysctl %q is not in a separate kernel namespace", s)
+		errs.addf(field, "sysctl %q is not in a separate kernel namespace", s)
 	}
-
-	return nil
+	return errs.err()
 }
 
 func (v *ConfigValidator) intelrdt(config *configs.Config) error {
 	if config.IntelRdt != nil {
+		if intelrdt.IsMonitoringOnly(config.IntelRdt) {
+			if !intelrdt.IsCmtEnabled() && !intelrdt.IsMbmEnabled() {
+				return newFieldError("IntelRdt", "intelRdt is specified in config without schemata, but Intel RDT/CMT and Intel RDT/MBM are not enabled")
+			}
+			return nil
+		}
+
 		if !intelrdt.IsCatEnabled() && !intelrdt.IsMbaEnabled() {
-			return fmt.Errorf("intelRdt is specified in config, but Intel RDT is not supported or enabled")
+			return newFieldError("IntelRdt", "intelRdt is specified in config, but Intel RDT is not supported or enabled")
+		}
+
+		var errs fieldErrors
+		if config.IntelRdt.ClosID != "" {
+			errs.add("IntelRdt.ClosID", v.intelrdtClosID(config.IntelRdt))
+			// Joining an existing group as is
+			if config.IntelRdt.L3CacheSchema == "" && config.IntelRdt.MemBwSchema == "" {
+				return errs.err()
+			}
 		}
 
 		if !intelrdt.IsCatEnabled() && config.IntelRdt.L3CacheSchema != "" {
-			return fmt.Errorf("intelRdt.l3CacheSchema is specified in config, but Intel RDT/CAT is not enabled")
+			errs.addf("IntelRdt.L3CacheSchema", "intelRdt.l3CacheSchema is specified in config, but Intel RDT/CAT is not enabled")
 		}
 		if !intelrdt.IsMbaEnabled() && config.IntelRdt.MemBwSchema != "" {
-			return fmt.Errorf("intelRdt.memBwSchema is specified in config, but Intel RDT/MBA is not enabled")
+			errs.addf("IntelRdt.MemBwSchema", "intelRdt.memBwSchema is specified in config, but Intel RDT/MBA is not enabled")
 		}
 
 		if intelrdt.IsCatEnabled() && config.IntelRdt.L3CacheSchema == "" {
-			return fmt.Errorf("Intel RDT/CAT is enabled and intelRdt is specified in config, but intelRdt.l3CacheSchema is empty")
+			errs.addf("IntelRdt.L3CacheSchema", "Intel RDT/CAT is enabled and intelRdt is specified in config, but intelRdt.l3CacheSchema is empty")
 		}
 		if intelrdt.IsMbaEnabled() && config.IntelRdt.MemBwSchema == "" {
-			return fmt.Errorf("Intel RDT/MBA is enabled and intelRdt is specified in config, but intelRdt.memBwSchema is empty")
+			errs.addf("IntelRdt.MemBwSchema", "Intel RDT/MBA is enabled and intelRdt is specified in config, but intelRdt.memBwSchema is empty")
 		}
+		return errs.err()
 	}
 
 	return nil
 }
 
+// intelrdtClosID checks that the group named by closID can be shared, i.e.
+// that the schemata of the container do not conflict with the ones of an
+// existing group.
+func (v *ConfigValidator) intelrdtClosID(c *configs.IntelRdt) error {
+	closID := c.ClosID
+	if closID == "." || closID == ".." || strings.Contains(closID, "/") {
+		return fmt.Errorf("invalid intelRdt.closID %q", closID)
+	}
+	switch closID {
+	case "info", "mon_groups", "mon_data":
+		return fmt.Errorf("intelRdt.closID %q is reserved by the resource control filesystem", closID)
+	}
+
+	schemata, err := intelrdt.GetSchemata(closID)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return err
+		}
+		if c.L3CacheSchema == "" && c.MemBwSchema == "" {
+			return fmt.Errorf("intelRdt.closID %q does not exist and no schemata are specified in config", closID)
+		}
+		return nil
+	}
+	for _, schema := range []string{c.L3CacheSchema, c.MemBwSchema} {
+		if schema == "" {
+			continue
+		}
+		if err := intelrdt.CheckSchemaConflict(schemata, schema); err != nil {
+			return fmt.Errorf("intelRdt.closID %q is already in use with different schemata: %v", closID, err)
+		}
+	}
+	return nil
+}
+
 func isSymbolicLink(path string) (bool, error) {
 	fi, err := os.Lstat(path)
 	if err != nil {
@@ -2,6 +2,7 @@ package validate_test
 
 import (
 	"os"
+	"reflect"
 	"testing"
 
 	"github.com/opencontainers/runc/libcontainer/configs"
@@ -181,6 +182,198 @@ func TestValidateUsernamespaceWithoutUserNS(t *testing.T) {
 	}
 }
 
+func TestValidateTimeOffsets(t *testing.T) {
+	if _, err := os.Stat("/proc/self/ns/time"); os.IsNotExist(err) {
+		t.Skip("timens is unsupported")
+	}
+	config := &configs.Config{
+		Rootfs: "/var",
+		Namespaces: configs.Namespaces(
+			[]configs.Namespace{
+				{Type: configs.NEWTIME},
+			},
+		),
+		TimeOffsets: map[string]configs.TimeOffset{
+			"monotonic": {Secs: 86400},
+			"boottime":  {Secs: -10, Nanosecs: 500},
+		},
+	}
+
+	validator := validate.New()
+	if err := validator.Validate(config); err != nil {
+		t.Errorf("expected error to not occur %+v", err)
+	}
+
+	config.TimeOffsets["realtime"] = configs.TimeOffset{Secs: 1}
+	if err := validator.Validate(config); err == nil {
+		t.Error("expected error to occur for an unknown clock")
+	}
+}
+
+func TestValidateTimeOffsetsWithoutTimeNS(t *testing.T) {
+	config := &configs.Config{
+		Rootfs: "/var",
+		TimeOffsets: map[string]configs.TimeOffset{
+			"monotonic": {Secs: 86400},
+		},
+	}
+
+	validator := validate.New()
+	if err := validator.Validate(config); err == nil {
+		t.Error("Expected error to occur but it was nil")
+	}
+}
+
+func TestValidateScheduler(t *testing.T) {
+	validator := validate.New()
+	for _, s := range []*configs.Scheduler{
+		{Policy: "SCHED_IDLE"},
+		{Policy: "SCHED_BATCH", Nice: 10},
+		{Policy: "SCHED_FIFO", Priority: 50, Flags: []string{"SCHED_FLAG_RESET_ON_FORK"}},
+		{Policy: "SCHED_DEADLINE", Runtime: 1000000, Deadline: 5000000, Period: 10000000},
+		{Policy: "SCHED_DEADLINE", Runtime: 1000000, Deadline: 5000000},
+	} {
+		config := &configs.Config{Rootfs: "/var", Scheduler: s}
+		if err := validator.Validate(config); err != nil {
+			t.Errorf("expected error to not occur for %+v: %+v", s, err)
+		}
+	}
+	for _, s := range []*configs.Scheduler{
+		{Policy: "SCHED_UNKNOWN"},
+		{Policy: "SCHED_OTHER", Nice: 20},
+		{Policy: "SCHED_OTHER", Priority: 1},
+		{Policy: "SCHED_RR"},
+		{Policy: "SCHED_FIFO", Priority: 100},
+		{Policy: "SCHED_BATCH", Runtime: 1000000},
+		{Policy: "SCHED_DEADLINE", Runtime: 5000000, Deadline: 1000000},
+		{Policy: "SCHED_IDLE", Flags: []string{"SCHED_FLAG_UNKNOWN"}},
+	} {
+		config := &configs.Config{Rootfs: "/var", Scheduler: s}
+		if err := validator.Validate(config); err == nil {
+			t.Errorf("expected error to occur for %+v", s)
+		}
+	}
+}
+
+func TestValidateSchedulerDeadlineWithCPUAffinity(t *testing.T) {
+	validator := validate.New()
+	config := &configs.Config{
+		Rootfs:      "/var",
+		Scheduler:   &configs.Scheduler{Policy: "SCHED_DEADLINE", Runtime: 1000000, Deadline: 5000000},
+		CPUAffinity: "0-1",
+	}
+	if err := validator.Validate(config); err == nil {
+		t.Error("expected error to occur")
+	}
+	config.Scheduler = &configs.Scheduler{Policy: "SCHED_FIFO", Priority: 50}
+	if err := validator.Validate(config); err != nil {
+		t.Errorf("expected error to not occur %+v", err)
+	}
+}
+
+func TestValidateIOPriority(t *testing.T) {
+	validator := validate.New()
+	config := &configs.Config{
+		Rootfs:     "/var",
+		IOPriority: &configs.IOPriority{Class: "IOPRIO_CLASS_IDLE"},
+	}
+	if err := validator.Validate(config); err != nil {
+		t.Errorf("expected error to not occur %+v", err)
+	}
+	for _, p := range []*configs.IOPriority{
+		{Class: "IOPRIO_CLASS_UNKNOWN"},
+		{Class: "IOPRIO_CLASS_BE", Priority: 8},
+		{Class: "IOPRIO_CLASS_RT", Priority: -1},
+	} {
+		config.IOPriority = p
+		if err := validator.Validate(config); err == nil {
+			t.Errorf("expected error to occur for %+v", p)
+		}
+	}
+}
+
+func TestValidatePersonality(t *testing.T) {
+	config := &configs.Config{
+		Rootfs:      "/var",
+		Personality: &configs.LinuxPersonality{Domain: configs.PerLinux32},
+	}
+
+	validator := validate.New()
+	if err := validator.Validate(config); err != nil {
+		t.Errorf("expected error to not occur %+v", err)
+	}
+
+	config.Seccomp = &configs.Seccomp{Architectures: []string{"amd64"}}
+	if err := validator.Validate(config); err == nil {
+		t.Error("expected error to occur without a 32-bit seccomp architecture")
+	}
+	config.Seccomp.Architectures = append(config.Seccomp.Architectures, "x86")
+	if err := validator.Validate(config); err != nil {
+		t.Errorf("expected error to not occur %+v", err)
+	}
+
+	config.Personality.Domain = 0x1234
+	if err := validator.Validate(config); err == nil {
+		t.Error("expected error to occur for an unknown domain")
+	}
+}
+
+func TestValidateLandlock(t *testing.T) {
+	config := &configs.Config{
+		Rootfs:          "/var",
+		NoNewPrivileges: true,
+		Landlock: &configs.Landlock{
+			Rules: []*configs.LandlockRule{
+				{Path: "/usr", Access: []string{"execute", "read_file", "read_dir"}},
+				{Path: "/tmp", Access: []string{"read_file", "write_file", "make_reg"}},
+			},
+		},
+	}
+
+	validator := validate.New()
+	if err := validator.Validate(config); err != nil {
+		t.Errorf("expected error to not occur %+v", err)
+	}
+	for _, r := range []*configs.LandlockRule{
+		{Path: "usr", Access: []string{"read_file"}},
+		{Path: "/usr"},
+		{Path: "/usr", Access: []string{"read_everything"}},
+	} {
+		config.Landlock.Rules = []*configs.LandlockRule{r}
+		if err := validator.Validate(config); err == nil {
+			t.Errorf("expected error to occur for %+v", r)
+		}
+	}
+}
+
+func TestValidateLandlockWithoutNoNewPrivileges(t *testing.T) {
+	config := &configs.Config{
+		Rootfs: "/var",
+		Landlock: &configs.Landlock{
+			Rules: []*configs.LandlockRule{
+				{Path: "/usr", Access: []string{"execute", "read_file", "read_dir"}},
+			},
+		},
+	}
+
+	validator := validate.New()
+	if err := validator.Validate(config); err == nil {
+		t.Error("expected error to occur")
+	}
+}
+
+func TestValidateAppArmorProfileContentWithoutName(t *testing.T) {
+	config := &configs.Config{
+		Rootfs:                 "/var",
+		AppArmorProfileContent: "profile test {}",
+	}
+
+	validator := validate.New()
+	if err := validator.Validate(config); err == nil {
+		t.Error("Expected error to occur but it was nil")
+	}
+}
+
 func TestValidateSysctl(t *testing.T) {
 	sysctl := map[string]string{
 		"fs.mqueue.ctl": "ctl",
@@ -265,3 +458,185 @@ func TestValidateSysctlWithoutNETNamespace(t *testing.T) {
 		t.Error("Expected error to occur but it was nil")
 	}
 }
+
+func TestValidateRootfsOverlay(t *testing.T) {
+	config := &configs.Config{
+		Rootfs: "/var",
+		RootfsOverlay: &configs.Overlay{
+			LowerDirs: []string{"/layers/2", "/layers/1"},
+			UpperDir:  "/layers/upper",
+			WorkDir:   "/layers/work",
+		},
+	}
+
+	validator := validate.New()
+	err := validator.Validate(config)
+	if err != nil {
+		t.Errorf("Expected error to not occur: %+v", err)
+	}
+}
+
+func TestValidateRootfsOverlayWithoutWorkDir(t *testing.T) {
+	config := &configs.Config{
+		Rootfs: "/var",
+		RootfsOverlay: &configs.Overlay{
+			LowerDirs: []string{"/layers/1"},
+			UpperDir:  "/layers/upper",
+		},
+	}
+
+	validator := validate.New()
+	err := validator.Validate(config)
+	if err == nil {
+		t.Error("Expected error to occur but it was nil")
+	}
+}
+
+func TestValidateRootfsOverlayWithRelativeLowerDir(t *testing.T) {
+	config := &configs.Config{
+		Rootfs: "/var",
+		RootfsOverlay: &configs.Overlay{
+			LowerDirs: []string{"layers/1"},
+		},
+	}
+
+	validator := validate.New()
+	err := validator.Validate(config)
+	if err == nil {
+		t.Error("Expected error to occur but it was nil")
+	}
+}
+
+func TestValidateRootfsOverlayWithSeparatorInUpperDir(t *testing.T) {
+	config := &configs.Config{
+		Rootfs: "/var",
+		RootfsOverlay: &configs.Overlay{
+			LowerDirs: []string{"/layers/1"},
+			UpperDir:  "/layers/upper,x",
+			WorkDir:   "/layers/work",
+		},
+	}
+
+	validator := validate.New()
+	err := validator.Validate(config)
+	if err == nil {
+		t.Error("Expected error to occur but it was nil")
+	}
+}
+
+func TestValidateIdmapMountWithoutUSERNamespace(t *testing.T) {
+	config := &configs.Config{
+		Rootfs: "/var",
+		Mounts: []*configs.Mount{
+			{
+				Source:      "/var/lib/data",
+				Destination: "/data",
+				Device:      "bind",
+				Extensions:  configs.EXT_IDMAP,
+			},
+		},
+	}
+
+	validator := validate.New()
+	err := validator.Validate(config)
+	if err == nil {
+		t.Error("Expected error to occur but it was nil")
+	}
+}
+
+func TestValidateChownNonBindMount(t *testing.T) {
+	config := &configs.Config{
+		Rootfs: "/var",
+		Mounts: []*configs.Mount{
+			{
+				Source:      "tmpfs",
+				Destination: "/data",
+				Device:      "tmpfs",
+				Extensions:  configs.EXT_CHOWN,
+			},
+		},
+	}
+
+	validator := validate.New()
+	err := validator.Validate(config)
+	if err == nil {
+		t.Error("Expected error to occur but it was nil")
+	}
+}
+
+func TestValidateAll(t *testing.T) {
+	config := &configs.Config{
+		Rootfs:   "/var",
+		Hostname: "runc",
+		Networks: []*configs.Network{{Type: "loopback"}},
+	}
+
+	validator := &validate.ConfigValidator{}
+	errs := validator.ValidateAll(config)
+	if len(errs) != 2 {
+		t.Fatalf("Expected 2 errors, got %v", errs)
+	}
+	if errs[0].Check != "network" || errs[1].Check != "hostname" {
+		t.Errorf("Expected the network and hostname checks to fail, got %q and %q", errs[0].Check, errs[1].Check)
+	}
+	if errs[0].Field != "Namespaces" || errs[1].Field != "Hostname" {
+		t.Errorf("Expected the errors of Namespaces and Hostname, got %q and %q", errs[0].Field, errs[1].Field)
+	}
+	if err := validator.Validate(config); err == nil || err.Error() != errs[0].Error() {
+		t.Errorf("Expected Validate to return the first error %v, got %v", errs[0].Err, err)
+	}
+}
+
+func TestValidateAllFields(t *testing.T) {
+	config := &configs.Config{
+		Rootfs: "/var",
+		Mounts: []*configs.Mount{
+			{Destination: "/a", Device: "bind", Extensions: configs.EXT_IDMAP},
+			{Destination: "/b", Device: "tmpfs"},
+			{Destination: "/c", Device: "tmpfs", Extensions: configs.EXT_CHOWN},
+		},
+		Sysctl: map[string]string{
+			"net.ipv4.ip_forward": "1",
+			"kernel.msgmax":       "8192",
+		},
+	}
+
+	validator := &validate.ConfigValidator{}
+	errs := validator.ValidateAll(config)
+	var fields []string
+	for _, err := range errs {
+		fields = append(fields, err.Field)
+	}
+	expected := []string{
+		"Mounts[0].Extensions",
+		"Mounts[2].Extensions",
+		`Sysctl["kernel.msgmax"]`,
+		`Sysctl["net.ipv4.ip_forward"]`,
+	}
+	if !reflect.DeepEqual(fields, expected) {
+		t.Fatalf("Expected the errors of %q, got %q", expected, fields)
+	}
+	if err := validator.Validate(config); err == nil || err.Error() != errs[0].Error() {
+		t.Errorf("Expected Validate to return the first error %v, got %v", errs[0].Err, err)
+	}
+}
+
+func TestValidateMemoryOomGroup(t *testing.T) {
+	config := &configs.Config{
+		Rootfs: "/var",
+		Cgroups: &configs.Cgroup{
+			Resources: &configs.Resources{
+				MemoryMin:      1024,
+				MemoryLow:      2048,
+				MemoryOomGroup: true,
+			},
+		},
+	}
+
+	// Whether the memory cgroup of the container supports them is only
+	// known when they are applied.
+	validator := validate.New()
+	if err := validator.Validate(config); err != nil {
+		t.Errorf("Expected error to not occur: %+v", err)
+	}
+}
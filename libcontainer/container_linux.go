@@ -14,6 +14,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"reflect"
+	"sort"
 	"strings"
 	"sync"
 	"syscall" // only for SysProcAttr and Signal
@@ -21,6 +22,7 @@ import (
 
 	"github.com/opencontainers/runc/libcontainer/cgroups"
 	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runc/libcontainer/configs/validate"
 	"github.com/opencontainers/runc/libcontainer/criurpc"
 	"github.com/opencontainers/runc/libcontainer/intelrdt"
 	"github.com/opencontainers/runc/libcontainer/system"
@@ -52,12 +54,19 @@ type linuxContainer struct {
 	criuVersion          int
 	state                containerState
 	created              time.Time
+	stateLock            *os.File
+	stateLockMode        StateLockMode
+	stateLockTimeout     time.Duration
+	appArmorProfile      string
 }
 
 // State represents a running container's state
 type State struct {
 	BaseState
 
+	// StateVersion is the schema version of the state file.
+	StateVersion int `json:"state_version"`
+
 	// Platform specific fields below here
 
 	// Specified if the container was started under the rootless mode.
@@ -77,6 +86,10 @@ type State struct {
 
 	// Intel RDT "resource control" filesystem path
 	IntelRdtPath string `json:"intel_rdt_path"`
+
+	// AppArmorProfile is the name of the AppArmor profile which was loaded
+	// for the container, and is unloaded when it is destroyed.
+	AppArmorProfile string `json:"apparmor_profile,omitempty"`
 }
 
 // Container is a libcontainer container object.
@@ -133,6 +146,69 @@ type Container interface {
 	// errors:
 	// Systemerror - System error.
 	NotifyMemoryPressure(level PressureLevel) (<-chan struct{}, error)
+
+	// AddMount bind mounts m.Source at m.Destination inside the mount namespace
+	// of a RUNNING or CREATED container and records the mount in the container's
+	// configuration.
+	//
+	// errors:
+	// ContainerNotExists - Container no longer exists,
+	// ContainerNotRunning - Container not running or created,
+	// ConfigInvalid - m is not a bind mount,
+	// Systemerror - System error.
+	AddMount(m *configs.Mount) error
+
+	// RemoveMount unmounts the mount at dest inside the mount namespace of a
+	// RUNNING or CREATED container and removes it from the container's
+	// configuration.
+	//
+	// errors:
+	// ContainerNotExists - Container no longer exists,
+	// ContainerNotRunning - Container not running or created,
+	// Systemerror - System error.
+	RemoveMount(dest string) error
+
+	// AddDevice allows access to dev in the devices cgroup of a RUNNING or
+	// CREATED container, creates the device node at dev.Path inside the
+	// container and records the device in the container's configuration.
+	//
+	// errors:
+	// ContainerNotExists - Container no longer exists,
+	// ContainerNotRunning - Container not running or created,
+	// ConfigInvalid - a device already exists at dev.Path,
+	// Systemerror - System error.
+	AddDevice(dev *configs.Device) error
+
+	// RemoveDevice removes the device node at path from a RUNNING or CREATED
+	// container, denies access to it in the devices cgroup and removes it from
+	// the container's configuration.
+	//
+	// errors:
+	// ContainerNotExists - Container no longer exists,
+	// ContainerNotRunning - Container not running or created,
+	// Systemerror - System error.
+	RemoveDevice(path string) error
+
+	// ReleaseStateLock releases the lock on the container's state which was
+	// taken by the factory when loading or creating the container. Long
+	// running callers should release it once they no longer change the state,
+	// so that other processes can operate on the container.
+	// Releasing a container which is not locked does nothing.
+	//
+	// errors:
+	// Systemerror - System error.
+	ReleaseStateLock() error
+
+	// AcquireStateLock takes the lock on the container's state again after
+	// it was released with ReleaseStateLock, in the mode in which the
+	// factory locked it. Acquiring a container which is locked, or which was
+	// loaded without a lock, does nothing.
+	//
+	// errors:
+	// ContainerNotExists - Container no longer exists,
+	// ContainerLocked - Timed out waiting for the lock,
+	// Systemerror - System error.
+	AcquireStateLock() error
 }
 
 // ID returns the container's unique ID
@@ -145,6 +221,31 @@ func (c *linuxContainer) Config() configs.Config {
 	return *c.config
 }
 
+func (c *linuxContainer) ReleaseStateLock() error {
+	c.m.Lock()
+	defer c.m.Unlock()
+	err := closeStateLock(c.stateLock)
+	c.stateLock = nil
+	if err != nil {
+		return newSystemErrorWithCause(err, "releasing container state lock")
+	}
+	return nil
+}
+
+func (c *linuxContainer) AcquireStateLock() error {
+	c.m.Lock()
+	defer c.m.Unlock()
+	if c.stateLock != nil || c.stateLockMode == NoStateLock {
+		return nil
+	}
+	stateLock, err := lockStateDir(c.root, c.id, c.stateLockMode, c.stateLockTimeout)
+	if err != nil {
+		return err
+	}
+	c.stateLock = stateLock
+	return nil
+}
+
 func (c *linuxContainer) Status() (Status, error) {
 	c.m.Lock()
 	defer c.m.Unlock()
@@ -332,6 +433,14 @@ type openResult struct {
 }
 
 func (c *linuxContainer) start(process *Process) error {
+	if process.Init {
+		if err := chownMountSources(c.config); err != nil {
+			return newSystemErrorWithCause(err, "changing owner of mount sources")
+		}
+		if err := c.loadAppArmorProfile(); err != nil {
+			return newSystemErrorWithCause(err, "loading apparmor profile")
+		}
+	}
 	parent, err := c.newParentProcess(process)
 	if err != nil {
 		return newSystemErrorWithCause(err, "creating new parent process")
@@ -361,7 +470,7 @@ func (c *linuxContainer) start(process *Process) error {
 				return err
 			}
 			for i, hook := range c.config.Hooks.Poststart {
-				if err := hook.Run(s); err != nil {
+				if err := c.runHook(hook, s); err != nil {
 					if err := ignoreTerminateErrors(parent.terminate()); err != nil {
 						logrus.Warn(err)
 					}
@@ -446,7 +555,7 @@ func (c *linuxContainer) newParentProcess(p *Process) (parentProcess, error) {
 		return nil, newSystemErrorWithCause(err, "creating new command template")
 	}
 	if !p.Init {
-		return c.newSetnsProcess(p, cmd, parentPipe, childPipe)
+		return c.newSetnsProcess(p, cmd, parentPipe, childPipe, initSetns)
 	}
 
 	// We only set up fifoFd if we're not doing a `runc exec`. The historic
@@ -504,13 +613,17 @@ func (c *linuxContainer) newInitProcess(p *Process, cmd *exec.Cmd, parentPipe, c
 	if err != nil {
 		return nil, err
 	}
+	config, err := c.newInitConfig(p)
+	if err != nil {
+		return nil, err
+	}
 	init := &initProcess{
 		cmd:             cmd,
 		childPipe:       childPipe,
 		parentPipe:      parentPipe,
 		manager:         c.cgroupManager,
 		intelRdtManager: c.intelRdtManager,
-		config:          c.newInitConfig(p),
+		config:          config,
 		container:       c,
 		process:         p,
 		bootstrapData:   data,
@@ -520,8 +633,8 @@ func (c *linuxContainer) newInitProcess(p *Process, cmd *exec.Cmd, parentPipe, c
 	return init, nil
 }
 
-func (c *linuxContainer) newSetnsProcess(p *Process, cmd *exec.Cmd, parentPipe, childPipe *os.File) (*setnsProcess, error) {
-	cmd.Env = append(cmd.Env, "_LIBCONTAINER_INITTYPE="+string(initSetns))
+func (c *linuxContainer) newSetnsProcess(p *Process, cmd *exec.Cmd, parentPipe, childPipe *os.File, it initType) (*setnsProcess, error) {
+	cmd.Env = append(cmd.Env, "_LIBCONTAINER_INITTYPE="+string(it))
 	state, err := c.currentState()
 	if err != nil {
 		return nil, newSystemErrorWithCause(err, "getting container's current state")
@@ -532,6 +645,10 @@ func (c *linuxContainer) newSetnsProcess(p *Process, cmd *exec.Cmd, parentPipe,
 	if err != nil {
 		return nil, err
 	}
+	config, err := c.newInitConfig(p)
+	if err != nil {
+		return nil, err
+	}
 	return &setnsProcess{
 		cmd:             cmd,
 		cgroupPaths:     c.cgroupManager.GetPaths(),
@@ -539,13 +656,13 @@ func (c *linuxContainer) newSetnsProcess(p *Process, cmd *exec.Cmd, parentPipe,
 		intelRdtPath:    state.IntelRdtPath,
 		childPipe:       childPipe,
 		parentPipe:      parentPipe,
-		config:          c.newInitConfig(p),
+		config:          config,
 		process:         p,
 		bootstrapData:   data,
 	}, nil
 }
 
-func (c *linuxContainer) newInitConfig(process *Process) *initConfig {
+func (c *linuxContainer) newInitConfig(process *Process) (*initConfig, error) {
 	cfg := &initConfig{
 		Config:           c.config,
 		Args:             process.Args,
@@ -562,6 +679,10 @@ func (c *linuxContainer) newInitConfig(process *Process) *initConfig {
 		AppArmorProfile:  c.config.AppArmorProfile,
 		ProcessLabel:     c.config.ProcessLabel,
 		Rlimits:          c.config.Rlimits,
+		Landlock:         c.config.Landlock,
+		Scheduler:        c.config.Scheduler,
+		IOPriority:       c.config.IOPriority,
+		CPUAffinity:      c.config.CPUAffinity,
 	}
 	if process.NoNewPrivileges != nil {
 		cfg.NoNewPrivileges = *process.NoNewPrivileges
@@ -575,10 +696,32 @@ func (c *linuxContainer) newInitConfig(process *Process) *initConfig {
 	if len(process.Rlimits) > 0 {
 		cfg.Rlimits = process.Rlimits
 	}
+	if process.Landlock != nil {
+		cfg.Landlock = process.Landlock
+	}
+	if process.Scheduler != nil {
+		cfg.Scheduler = process.Scheduler
+	}
+	if process.IOPriority != nil {
+		cfg.IOPriority = process.IOPriority
+	}
+	if process.CPUAffinity != "" {
+		cfg.CPUAffinity = process.CPUAffinity
+	}
+	if cfg.Landlock != nil && !cfg.NoNewPrivileges {
+		return nil, newGenericError(fmt.Errorf("landlock requires noNewPrivileges to be set"), ConfigInvalid)
+	}
+	// The settings of the config have been validated with it, those of the
+	// process are combined with them here.
+	if process.Scheduler != nil || process.IOPriority != nil || process.CPUAffinity != "" {
+		if err := validate.Scheduling(cfg.Scheduler, cfg.IOPriority, cfg.CPUAffinity); err != nil {
+			return nil, newGenericError(err, ConfigInvalid)
+		}
+	}
 	cfg.CreateConsole = process.ConsoleSocket != nil
 	cfg.ConsoleWidth = process.ConsoleWidth
 	cfg.ConsoleHeight = process.ConsoleHeight
-	return cfg
+	return cfg, nil
 }
 
 func (c *linuxContainer) Destroy() error {
@@ -640,6 +783,227 @@ func (c *linuxContainer) NotifyMemoryPressure(level PressureLevel) (<-chan struc
 	return notifyMemoryPressure(c.cgroupManager.GetPaths(), level)
 }
 
+func (c *linuxContainer) AddMount(m *configs.Mount) error {
+	c.m.Lock()
+	defer c.m.Unlock()
+	if err := c.checkHotpluggable(); err != nil {
+		return err
+	}
+	if m.Device != "bind" {
+		return newGenericError(fmt.Errorf("only bind mounts can be added to a running container"), ConfigInvalid)
+	}
+	if m.Extensions&configs.EXT_IDMAP == configs.EXT_IDMAP && !c.config.Namespaces.Contains(configs.NEWUSER) {
+		return newGenericError(fmt.Errorf("idmapped mounts require a USER namespace"), ConfigInvalid)
+	}
+	dest := utils.CleanPath(m.Destination)
+	for _, old := range c.config.Mounts {
+		if utils.CleanPath(old.Destination) == dest {
+			return newGenericError(fmt.Errorf("a mount already exists at %s", dest), ConfigInvalid)
+		}
+	}
+	if m.Extensions&configs.EXT_CHOWN == configs.EXT_CHOWN {
+		cfg := *c.config
+		cfg.Mounts = []*configs.Mount{m}
+		if err := chownMountSources(&cfg); err != nil {
+			return newSystemErrorWithCause(err, "changing owner of mount source")
+		}
+	}
+	var (
+		f   *os.File
+		err error
+	)
+	if m.Extensions&configs.EXT_IDMAP == configs.EXT_IDMAP {
+		f, err = openIdmappedMount(m, c.config.MountLabel, fmt.Sprintf("/proc/%d/ns/user", c.initProcess.pid()))
+	} else {
+		f, err = openBindMount(m.Source, m.Flags&unix.MS_REC == unix.MS_REC)
+	}
+	if err != nil {
+		return newSystemErrorWithCause(err, "creating detached mount")
+	}
+	defer f.Close()
+	if err := c.runHotplugHelper(&hotplugOp{Mount: m}, f); err != nil {
+		return err
+	}
+	c.config.Mounts = append(c.config.Mounts, m)
+	_, err = c.updateState(nil)
+	return err
+}
+
+func (c *linuxContainer) RemoveMount(dest string) error {
+	c.m.Lock()
+	defer c.m.Unlock()
+	if err := c.checkHotpluggable(); err != nil {
+		return err
+	}
+	dest = utils.CleanPath(dest)
+	idx := -1
+	for i, m := range c.config.Mounts {
+		if utils.CleanPath(m.Destination) == dest {
+			idx = i
+			break
+		}
+	}
+	if idx == -1 {
+		return newGenericError(fmt.Errorf("no mount exists at %s", dest), ConfigInvalid)
+	}
+	if err := c.runHotplugHelper(&hotplugOp{Unmount: dest}); err != nil {
+		return err
+	}
+	c.config.Mounts = append(c.config.Mounts[:idx], c.config.Mounts[idx+1:]...)
+	_, err := c.updateState(nil)
+	return err
+}
+
+func (c *linuxContainer) AddDevice(dev *configs.Device) error {
+	c.m.Lock()
+	defer c.m.Unlock()
+	if err := c.checkHotpluggable(); err != nil {
+		return err
+	}
+	if !filepath.IsAbs(dev.Path) {
+		return newGenericError(fmt.Errorf("device path %q is not an absolute path", dev.Path), ConfigInvalid)
+	}
+	path := utils.CleanPath(dev.Path)
+	for _, d := range c.config.Devices {
+		if utils.CleanPath(d.Path) == path {
+			return newGenericError(fmt.Errorf("a device already exists at %s", path), ConfigInvalid)
+		}
+	}
+	rule := *dev
+	rule.Allow = true
+	rules := append([]*configs.Device{}, c.config.Cgroups.Resources.Devices...)
+	config := c.deviceRulesConfig(append(rules, &rule))
+	if err := c.cgroupManager.Set(config); err != nil {
+		return newSystemErrorWithCause(err, "allowing device in cgroup")
+	}
+	var files []*os.File
+	// Containers in a user namespace are not allowed to mknod devices, so
+	// the node is bind mounted from the host instead, as in createDevices.
+	if system.RunningInUserNS() || c.config.Namespaces.Contains(configs.NEWUSER) {
+		f, err := openBindMount(dev.Path, false)
+		if err != nil {
+			return newSystemErrorWithCause(err, "creating detached mount of device node")
+		}
+		defer f.Close()
+		files = append(files, f)
+	}
+	if err := c.runHotplugHelper(&hotplugOp{Device: dev}, files...); err != nil {
+		if err2 := c.cgroupManager.Set(c.deviceRulesConfig(append(config.Cgroups.Resources.Devices, denyRule(dev)))); err2 != nil {
+			logrus.Warnf("Denying device %s in cgroup failed due to error: %v, the container may still be able to access it.", dev.Path, err2)
+		}
+		return err
+	}
+	c.config.Devices = append(c.config.Devices, dev)
+	c.config.Cgroups.Resources.Devices = config.Cgroups.Resources.Devices
+	_, err := c.updateState(nil)
+	return err
+}
+
+func (c *linuxContainer) RemoveDevice(path string) error {
+	c.m.Lock()
+	defer c.m.Unlock()
+	path = utils.CleanPath(path)
+	// The default devices are expected by every container.
+	for _, d := range configs.DefaultAutoCreatedDevices {
+		if utils.CleanPath(d.Path) == path {
+			return newGenericError(fmt.Errorf("%s is a default device and cannot be removed", path), ConfigInvalid)
+		}
+	}
+	if err := c.checkHotpluggable(); err != nil {
+		return err
+	}
+	idx := -1
+	for i, d := range c.config.Devices {
+		if utils.CleanPath(d.Path) == path {
+			idx = i
+			break
+		}
+	}
+	if idx == -1 {
+		return newGenericError(fmt.Errorf("no device exists at %s", path), ConfigInvalid)
+	}
+	dev := c.config.Devices[idx]
+	if err := c.runHotplugHelper(&hotplugOp{RemoveDevice: path}); err != nil {
+		return err
+	}
+	var rules []*configs.Device
+	for _, r := range c.config.Cgroups.Resources.Devices {
+		if r.Allow && r.Type == dev.Type && r.Major == dev.Major && r.Minor == dev.Minor {
+			continue
+		}
+		rules = append(rules, r)
+	}
+	// Dropping the allow rule is not enough, as the rules are only ever
+	// written to the cgroup, so explicitly deny the device as well.
+	if err := c.cgroupManager.Set(c.deviceRulesConfig(append(rules, denyRule(dev)))); err != nil {
+		return newSystemErrorWithCause(err, "denying device in cgroup")
+	}
+	c.config.Devices = append(c.config.Devices[:idx], c.config.Devices[idx+1:]...)
+	c.config.Cgroups.Resources.Devices = rules
+	_, err := c.updateState(nil)
+	return err
+}
+
+// deviceRulesConfig returns a copy of the container's configuration with the
+// device rules of its cgroup replaced by rules.
+func (c *linuxContainer) deviceRulesConfig(rules []*configs.Device) *configs.Config {
+	config := *c.config
+	cgroup := *c.config.Cgroups
+	resources := *c.config.Cgroups.Resources
+	resources.Devices = rules
+	cgroup.Resources = &resources
+	config.Cgroups = &cgroup
+	return &config
+}
+
+// denyRule returns a cgroup rule which denies all access to dev.
+func denyRule(dev *configs.Device) *configs.Device {
+	return &configs.Device{
+		Type:        dev.Type,
+		Major:       dev.Major,
+		Minor:       dev.Minor,
+		Permissions: "rwm",
+		Allow:       false,
+	}
+}
+
+// checkHotpluggable returns an error unless the container is in a state in
+// which a hotplug helper can join it. A paused container is rejected as the
+// helper would be frozen as soon as it enters the container's cgroup.
+func (c *linuxContainer) checkHotpluggable() error {
+	status, err := c.currentStatus()
+	if err != nil {
+		return err
+	}
+	if status != Running && status != Created {
+		return newGenericError(fmt.Errorf("container not running or created"), ContainerNotRunning)
+	}
+	return nil
+}
+
+// runHotplugHelper starts a helper process which joins the namespaces of the
+// container, performs op inside of them and exits. files are passed to the
+// helper as its extra files.
+func (c *linuxContainer) runHotplugHelper(op *hotplugOp, files ...*os.File) error {
+	p := &Process{
+		ExtraFiles: files,
+	}
+	parentPipe, childPipe, err := utils.NewSockPair("hotplug")
+	if err != nil {
+		return newSystemErrorWithCause(err, "creating new hotplug pipe")
+	}
+	cmd, err := c.commandTemplate(p, childPipe)
+	if err != nil {
+		return newSystemErrorWithCause(err, "creating new command template")
+	}
+	helper, err := c.newSetnsProcess(p, cmd, parentPipe, childPipe, initHotplug)
+	if err != nil {
+		return err
+	}
+	helper.config.Hotplug = op
+	return helper.start()
+}
+
 var criuFeatures *criurpc.CriuFeatures
 
 func (c *linuxContainer) checkCriuFeatures(criuOpts *CriuOpts, rpcOpts *criurpc.CriuOpts, criuFeat *criurpc.CriuFeatures) error {
@@ -706,6 +1070,52 @@ func (c *linuxContainer) checkCriuFeatures(criuOpts *CriuOpts, rpcOpts *criurpc.
 	return nil
 }
 
+// CriuInfo describes the CRIU binary used to checkpoint and restore
+// containers.
+type CriuInfo struct {
+	// Version is the version of CRIU, e.g. 31100 for 3.11.
+	Version int `json:"version"`
+
+	// MemTrack is set if CRIU can track memory changes for pre-dumps.
+	MemTrack bool `json:"memTrack"`
+
+	// LazyPages is set if CRIU can restore memory pages lazily.
+	LazyPages bool `json:"lazyPages"`
+}
+
+// GetCriuInfo returns the version and the features of the CRIU binary at
+// criuPath.
+func GetCriuInfo(criuPath string) (*CriuInfo, error) {
+	c := &linuxContainer{criuPath: criuPath, config: &configs.Config{}}
+	if err := c.checkCriuVersion(0); err != nil {
+		return nil, err
+	}
+	info := &CriuInfo{Version: c.criuVersion}
+	// Feature checking was introduced with CRIU 1.8.
+	if c.criuVersion < 10800 {
+		return info, nil
+	}
+	criuFeatures = nil
+	t := criurpc.CriuReqType_FEATURE_CHECK
+	req := &criurpc.CriuReq{
+		Type: &t,
+		// CRIU before 2.12 segfaults if Opts is empty.
+		Opts: &criurpc.CriuOpts{LogLevel: proto.Int32(0)},
+		Features: &criurpc.CriuFeatures{
+			MemTrack:  proto.Bool(true),
+			LazyPages: proto.Bool(true),
+		},
+	}
+	if err := c.criuSwrk(nil, req, nil, false, nil); err != nil {
+		return nil, fmt.Errorf("CRIU feature check failed: %v", err)
+	}
+	if criuFeatures != nil {
+		info.MemTrack = criuFeatures.GetMemTrack()
+		info.LazyPages = criuFeatures.GetLazyPages()
+	}
+	return info, nil
+}
+
 func parseCriuVersion(path string) (int, error) {
 	var x, y, z int
 
@@ -1288,6 +1698,9 @@ func (c *linuxContainer) criuApplyCgroups(pid int, req *criurpc.CriuReq) error {
 	if err := c.cgroupManager.Apply(pid); err != nil {
 		return err
 	}
+	if err := c.recordResources(); err != nil {
+		return err
+	}
 
 	if err := c.cgroupManager.Set(c.config); err != nil {
 		return newSystemError(err)
@@ -1556,7 +1969,7 @@ func (c *linuxContainer) criuNotifications(resp *criurpc.CriuResp, process *Proc
 			}
 			s.Pid = int(notify.GetPid())
 			for i, hook := range c.config.Hooks.Prestart {
-				if err := hook.Run(s); err != nil {
+				if err := c.runHook(hook, s); err != nil {
 					return newSystemErrorWithCausef(err, "running prestart hook %d", i)
 				}
 			}
@@ -1621,12 +2034,7 @@ func (c *linuxContainer) updateState(process parentProcess) (*State, error) {
 }
 
 func (c *linuxContainer) saveState(s *State) error {
-	f, err := os.Create(filepath.Join(c.root, stateFilename))
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-	return utils.WriteJSON(f, s)
+	return writeStateFile(c.root, s)
 }
 
 func (c *linuxContainer) deleteState() error {
@@ -1713,9 +2121,9 @@ func (c *linuxContainer) currentState() (*State, error) {
 		startTime, _ = c.initProcess.startTime()
 		externalDescriptors = c.initProcess.externalDescriptors()
 	}
-	intelRdtPath, err := intelrdt.GetIntelRdtPath(c.ID())
-	if err != nil {
-		intelRdtPath = ""
+	intelRdtPath := ""
+	if c.intelRdtManager != nil {
+		intelRdtPath = c.intelRdtManager.GetPath()
 	}
 	state := &State{
 		BaseState: BaseState{
@@ -1728,6 +2136,7 @@ func (c *linuxContainer) currentState() (*State, error) {
 		Rootless:            c.config.RootlessEUID && c.config.RootlessCgroups,
 		CgroupPaths:         c.cgroupManager.GetPaths(),
 		IntelRdtPath:        intelRdtPath,
+		AppArmorProfile:     c.appArmorProfile,
 		NamespacePaths:      make(map[configs.NamespaceType]string),
 		ExternalDescriptors: externalDescriptors,
 	}
@@ -1813,6 +2222,21 @@ func encodeIDMapping(idMap []configs.IDMap) ([]byte, error) {
 	return data.Bytes(), nil
 }
 
+// encodeTimeOffsets formats the clock offsets of a time namespace as expected
+// by /proc/self/timens_offsets.
+func encodeTimeOffsets(offsets map[string]configs.TimeOffset) []byte {
+	clocks := make([]string, 0, len(offsets))
+	for clock := range offsets {
+		clocks = append(clocks, clock)
+	}
+	sort.Strings(clocks)
+	data := bytes.NewBuffer(nil)
+	for _, clock := range clocks {
+		fmt.Fprintf(data, "%s %d %d\n", clock, offsets[clock].Secs, offsets[clock].Nanosecs)
+	}
+	return data.Bytes()
+}
+
 // bootstrapData encodes the necessary data in netlink binary format
 // as a io.Reader.
 // Consumer can write the data to a bootstrap program
@@ -1887,6 +2311,15 @@ func (c *linuxContainer) bootstrapData(cloneFlags uintptr, nsMaps map[configs.Na
 		}
 	}
 
+	// write time namespace offsets only when we are not joining an existing
+	// time ns
+	if _, joinExistingTime := nsMaps[configs.NEWTIME]; !joinExistingTime && len(c.config.TimeOffsets) > 0 {
+		r.AddData(&Bytemsg{
+			Type:  TimeOffsetsAttr,
+			Value: encodeTimeOffsets(c.config.TimeOffsets),
+		})
+	}
+
 	if c.config.OomScoreAdj != nil {
 		// write oom_score_adj
 		r.AddData(&Bytemsg{
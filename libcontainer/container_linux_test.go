@@ -353,3 +353,134 @@ func TestGetContainerStateAfterUpdate(t *testing.T) {
 		t.Fatalf("expected Memory to be 2048 but received %q", state.Config.Cgroups.Memory)
 	}
 }
+
+func TestHotplugStoppedContainer(t *testing.T) {
+	container := &linuxContainer{
+		id: "myid",
+		config: &configs.Config{
+			Mounts: []*configs.Mount{
+				{Source: "/src", Destination: "/data", Device: "bind"},
+			},
+			Devices: []*configs.Device{
+				{Type: 'c', Path: "/dev/ttyUSB0", Major: 188, Minor: 0},
+			},
+		},
+		cgroupManager: &mockCgroupManager{},
+	}
+	container.state = &stoppedState{c: container}
+	for name, err := range map[string]error{
+		"AddMount":     container.AddMount(&configs.Mount{Source: "/src", Destination: "/other", Device: "bind"}),
+		"RemoveMount":  container.RemoveMount("/data"),
+		"AddDevice":    container.AddDevice(&configs.Device{Type: 'c', Path: "/dev/ttyUSB1", Major: 188, Minor: 1}),
+		"RemoveDevice": container.RemoveDevice("/dev/ttyUSB0"),
+	} {
+		lerr, ok := err.(Error)
+		if !ok {
+			t.Fatalf("%s: expected libcontainer error but received %v", name, err)
+		}
+		if lerr.Code() != ContainerNotRunning {
+			t.Fatalf("%s: expected error code %s but received %s", name, ContainerNotRunning, lerr.Code())
+		}
+	}
+	if len(container.config.Mounts) != 1 || len(container.config.Devices) != 1 {
+		t.Fatalf("expected the config of a stopped container to be unchanged, got %d mounts and %d devices", len(container.config.Mounts), len(container.config.Devices))
+	}
+}
+
+func TestRemoveDefaultDevice(t *testing.T) {
+	container := &linuxContainer{
+		id: "myid",
+		config: &configs.Config{
+			Devices: configs.DefaultAutoCreatedDevices,
+		},
+		cgroupManager: &mockCgroupManager{},
+	}
+	container.state = &runningState{c: container}
+	for _, path := range []string{"/dev/null", "/dev/zero", "/dev//urandom"} {
+		err := container.RemoveDevice(path)
+		lerr, ok := err.(Error)
+		if !ok {
+			t.Fatalf("%s: expected libcontainer error but received %v", path, err)
+		}
+		if lerr.Code() != ConfigInvalid {
+			t.Fatalf("%s: expected error code %s but received %s", path, ConfigInvalid, lerr.Code())
+		}
+	}
+	if len(container.config.Devices) != len(configs.DefaultAutoCreatedDevices) {
+		t.Fatalf("expected the default devices to be kept, got %d devices", len(container.config.Devices))
+	}
+}
+
+func TestNewInitConfigValidatesProcess(t *testing.T) {
+	container := &linuxContainer{
+		id: "myid",
+		config: &configs.Config{
+			CPUAffinity: "0",
+		},
+	}
+	// The policy of an exec'd process is validated against the affinity
+	// it inherits from the config.
+	_, err := container.newInitConfig(&Process{
+		Scheduler: &configs.Scheduler{Policy: "SCHED_DEADLINE", Runtime: 1000000, Deadline: 5000000},
+	})
+	lerr, ok := err.(Error)
+	if !ok {
+		t.Fatalf("expected libcontainer error but received %v", err)
+	}
+	if lerr.Code() != ConfigInvalid {
+		t.Fatalf("expected error code %s but received %s", ConfigInvalid, lerr.Code())
+	}
+	if _, err := container.newInitConfig(&Process{IOPriority: &configs.IOPriority{Class: "IOPRIO_CLASS_UNKNOWN"}}); err == nil {
+		t.Fatal("expected an unknown io priority class to be refused")
+	}
+	noNewPrivileges := false
+	if _, err := container.newInitConfig(&Process{
+		Landlock:        &configs.Landlock{},
+		NoNewPrivileges: &noNewPrivileges,
+	}); err == nil {
+		t.Fatal("expected landlock without no_new_privs to be refused")
+	}
+	cfg, err := container.newInitConfig(&Process{Scheduler: &configs.Scheduler{Policy: "SCHED_BATCH"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Scheduler.Policy != "SCHED_BATCH" || cfg.CPUAffinity != "0" {
+		t.Fatalf("unexpected scheduling of the process %+v, affinity %q", cfg.Scheduler, cfg.CPUAffinity)
+	}
+}
+
+func TestDeviceRulesConfig(t *testing.T) {
+	deny := &configs.Device{Type: 'a', Major: configs.Wildcard, Minor: configs.Wildcard, Permissions: "rwm"}
+	container := &linuxContainer{
+		config: &configs.Config{
+			Cgroups: &configs.Cgroup{
+				Resources: &configs.Resources{
+					Devices: []*configs.Device{deny},
+				},
+			},
+		},
+	}
+	dev := &configs.Device{Type: 'c', Path: "/dev/ttyUSB0", Major: 188, Minor: 0, Permissions: "rw", Allow: true}
+	config := container.deviceRulesConfig([]*configs.Device{deny, dev, denyRule(dev)})
+	rules := config.Cgroups.Resources.Devices
+	if len(rules) != 3 {
+		t.Fatalf("expected 3 device rules, got %d", len(rules))
+	}
+	if s := rules[2].CgroupString(); s != "c 188:0 rwm" || rules[2].Allow {
+		t.Fatalf("expected the last rule to deny c 188:0 rwm, got allow=%v %q", rules[2].Allow, s)
+	}
+	if n := len(container.config.Cgroups.Resources.Devices); n != 1 {
+		t.Fatalf("expected the container's device rules to be unchanged, got %d rules", n)
+	}
+}
+
+func TestEncodeTimeOffsets(t *testing.T) {
+	data := encodeTimeOffsets(map[string]configs.TimeOffset{
+		"monotonic": {Secs: 86400},
+		"boottime":  {Secs: -10, Nanosecs: 500},
+	})
+	expected := "boottime -10 500\nmonotonic 86400 0\n"
+	if string(data) != expected {
+		t.Fatalf("expected %q, got %q", expected, data)
+	}
+}
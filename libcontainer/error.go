@@ -17,6 +17,8 @@ const (
 	ContainerNotStopped
 	ContainerNotRunning
 	ContainerNotPaused
+	ContainerLocked
+	ContainerStateDamaged
 
 	// Process errors
 	NoProcessOps
@@ -49,6 +51,10 @@ func (c ErrorCode) String() string {
 		return "Console exists for process"
 	case ContainerNotPaused:
 		return "Container is not paused"
+	case ContainerLocked:
+		return "Container is locked by another process"
+	case ContainerStateDamaged:
+		return "Container state is damaged"
 	case NoProcessOps:
 		return "No process operations"
 	default:
@@ -31,6 +31,32 @@ type Factory interface {
 	// System error
 	Load(id string) (Container, error)
 
+	// RepairState rebuilds a minimal state for the container id, whose state
+	// is missing or damaged, from its cgroup at cgroupsPath and the pid of its
+	// init process, which must be in that cgroup and must not share all
+	// namespaces with the caller. If pid is 0, the first process started in
+	// the cgroup is taken as the init process. The rebuilt state
+	// lacks most of the configuration of the container, but is sufficient to
+	// signal and destroy it.
+	//
+	// errors:
+	// ContainerNotExists - Container does not exist,
+	// IdInUse - Container state is not damaged,
+	// Systemerror - System error.
+	RepairState(id string, pid int, cgroupsPath string) (Container, error)
+
+	// GarbageCollect destroys the containers whose init process has exited,
+	// removes the state of containers which was left damaged by a crash and
+	// removes the resources which could not be removed when destroying
+	// containers. Containers in use by other processes are skipped. If dryRun
+	// is set, nothing is removed.
+	//
+	// Errors removing single containers are reported in their results.
+	//
+	// errors:
+	// Systemerror - System error.
+	GarbageCollect(dryRun bool) ([]GCResult, error)
+
 	// StartInitialization is an internal API to libcontainer used during the reexec of the
 	// container.
 	//
@@ -3,13 +3,14 @@
 package libcontainer
 
 import (
-	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"regexp"
 	"runtime/debug"
 	"strconv"
+	"time"
 
 	"github.com/cyphar/filepath-securejoin"
 	"github.com/opencontainers/runc/libcontainer/cgroups"
@@ -60,6 +61,22 @@ func SystemdCgroups(l *LinuxFactory) error {
 	return nil
 }
 
+// RootlessSystemdCgroups is an options func to configure a LinuxFactory to
+// return containers that use the systemd instance of the calling user to
+// create and manage cgroups, in a unit which the user instance delegates to
+// the container. Like RootlessCgroupfs, it ignores permission errors for the
+// hierarchies that are not delegated.
+func RootlessSystemdCgroups(l *LinuxFactory) error {
+	l.NewCgroupsManager = func(config *configs.Cgroup, paths map[string]string) cgroups.Manager {
+		return &systemd.Manager{
+			Cgroups:  config,
+			Rootless: true,
+			Paths:    paths,
+		}
+	}
+	return nil
+}
+
 // Cgroupfs is an options func to configure a LinuxFactory to return containers
 // that use the native cgroups filesystem implementation to create and manage
 // cgroups.
@@ -127,6 +144,19 @@ func CriuPath(criupath string) func(*LinuxFactory) error {
 	}
 }
 
+// StateLock returns an option func to configure a LinuxFactory to lock the
+// state directory of the containers it loads or creates in the given mode,
+// waiting at most timeout for other processes to release it. A timeout of
+// zero waits indefinitely, a negative one does not wait. Containers created
+// by the factory are always locked exclusively, unless mode is NoStateLock.
+func StateLock(mode StateLockMode, timeout time.Duration) func(*LinuxFactory) error {
+	return func(l *LinuxFactory) error {
+		l.StateLockMode = mode
+		l.StateLockTimeout = timeout
+		return nil
+	}
+}
+
 // New returns a linux based container factory based in the root directory and
 // configures the factory with the provided option funcs.
 func New(root string, options ...func(*LinuxFactory) error) (Factory, error) {
@@ -184,6 +214,14 @@ type LinuxFactory struct {
 
 	// NewIntelRdtManager returns an initialized Intel RDT manager for a single container.
 	NewIntelRdtManager func(config *configs.Config, id string, path string) intelrdt.Manager
+
+	// StateLockMode is the mode in which the state directory of loaded
+	// containers is locked against other processes until the lock is
+	// released with Container.ReleaseStateLock.
+	StateLockMode StateLockMode
+
+	// StateLockTimeout is the maximum time to wait for the state lock.
+	StateLockTimeout time.Duration
 }
 
 func (l *LinuxFactory) Create(id string, config *configs.Config) (Container, error) {
@@ -200,29 +238,49 @@ func (l *LinuxFactory) Create(id string, config *configs.Config) (Container, err
 	if err != nil {
 		return nil, err
 	}
-	if _, err := os.Stat(containerRoot); err == nil {
-		return nil, newGenericError(fmt.Errorf("container with id exists: %v", id), IdInUse)
-	} else if !os.IsNotExist(err) {
-		return nil, newGenericError(err, SystemError)
+	// Keep the garbage collector from taking the new state directory for
+	// the remains of a crash before it is locked.
+	if l.StateLockMode != NoStateLock {
+		rootLock, err := lockRoot(l.Root, SharedStateLock)
+		if err != nil {
+			return nil, err
+		}
+		defer rootLock.Close()
 	}
-	if err := os.MkdirAll(containerRoot, 0711); err != nil {
+	// Creating the directory fails if it exists, so that only one of several
+	// concurrent calls with the same id can succeed.
+	if err := os.Mkdir(containerRoot, 0711); err != nil {
+		if os.IsExist(err) {
+			return nil, newGenericError(fmt.Errorf("container with id exists: %v", id), IdInUse)
+		}
 		return nil, newGenericError(err, SystemError)
 	}
 	if err := os.Chown(containerRoot, unix.Geteuid(), unix.Getegid()); err != nil {
 		return nil, newGenericError(err, SystemError)
 	}
+	var stateLock *os.File
+	if l.StateLockMode != NoStateLock {
+		if stateLock, err = lockStateDir(containerRoot, id, ExclusiveStateLock, l.StateLockTimeout); err != nil {
+			return nil, err
+		}
+	}
 	c := &linuxContainer{
-		id:            id,
-		root:          containerRoot,
-		config:        config,
-		initPath:      l.InitPath,
-		initArgs:      l.InitArgs,
-		criuPath:      l.CriuPath,
-		newuidmapPath: l.NewuidmapPath,
-		newgidmapPath: l.NewgidmapPath,
-		cgroupManager: l.NewCgroupsManager(config.Cgroups, nil),
-	}
-	if intelrdt.IsCatEnabled() || intelrdt.IsMbaEnabled() {
+		stateLock:        stateLock,
+		stateLockTimeout: l.StateLockTimeout,
+		id:               id,
+		root:             containerRoot,
+		config:           config,
+		initPath:         l.InitPath,
+		initArgs:         l.InitArgs,
+		criuPath:         l.CriuPath,
+		newuidmapPath:    l.NewuidmapPath,
+		newgidmapPath:    l.NewgidmapPath,
+		cgroupManager:    l.NewCgroupsManager(config.Cgroups, nil),
+	}
+	if stateLock != nil {
+		c.stateLockMode = ExclusiveStateLock
+	}
+	if intelrdt.IsCatEnabled() || intelrdt.IsMbaEnabled() || intelrdt.IsCmtEnabled() || intelrdt.IsMbmEnabled() {
 		c.intelRdtManager = l.NewIntelRdtManager(config, id, "")
 	}
 	c.state = &stoppedState{c: c}
@@ -241,10 +299,86 @@ func (l *LinuxFactory) Load(id string) (Container, error) {
 	if err != nil {
 		return nil, err
 	}
+	// A hook of the container may query it while the process which runs
+	// the hook holds the lock.
+	mode := l.StateLockMode
+	if lockedByCaller(containerRoot) {
+		mode = NoStateLock
+	}
+	var stateLock *os.File
+	if mode != NoStateLock {
+		if stateLock, err = lockStateDir(containerRoot, id, mode, l.StateLockTimeout); err != nil {
+			return nil, err
+		}
+	}
 	state, err := l.loadState(containerRoot, id)
 	if err != nil {
+		closeStateLock(stateLock)
+		return nil, err
+	}
+	c, err := l.newLoadedContainer(id, containerRoot, state, stateLock)
+	if err != nil {
+		closeStateLock(stateLock)
 		return nil, err
 	}
+	c.stateLockMode = mode
+	return c, nil
+}
+
+func (l *LinuxFactory) RepairState(id string, pid int, cgroupsPath string) (Container, error) {
+	if l.Root == "" {
+		return nil, newGenericError(fmt.Errorf("invalid root"), ConfigInvalid)
+	}
+	if err := l.validateID(id); err != nil {
+		return nil, err
+	}
+	containerRoot, err := securejoin.SecureJoin(l.Root, id)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(containerRoot); err != nil {
+		if os.IsNotExist(err) {
+			return nil, newGenericError(fmt.Errorf("container %q does not exist", id), ContainerNotExists)
+		}
+		return nil, newGenericError(err, SystemError)
+	}
+	var stateLock *os.File
+	if l.StateLockMode != NoStateLock {
+		if stateLock, err = lockStateDir(containerRoot, id, ExclusiveStateLock, l.StateLockTimeout); err != nil {
+			return nil, err
+		}
+	}
+	c, err := l.repairState(id, containerRoot, pid, cgroupsPath, stateLock)
+	if err != nil {
+		closeStateLock(stateLock)
+		return nil, err
+	}
+	if stateLock != nil {
+		c.stateLockMode = ExclusiveStateLock
+	}
+	return c, nil
+}
+
+func (l *LinuxFactory) repairState(id, containerRoot string, pid int, cgroupsPath string, stateLock *os.File) (*linuxContainer, error) {
+	// Only a missing or damaged state is replaced, a valid one has much
+	// more information than can be rebuilt.
+	if _, err := l.loadState(containerRoot, id); err == nil {
+		return nil, newGenericError(fmt.Errorf("state of container %q is not damaged", id), IdInUse)
+	} else if lerr, ok := err.(Error); !ok || (lerr.Code() != ContainerStateDamaged && lerr.Code() != ContainerNotExists) {
+		return nil, err
+	}
+	state, err := repairState(id, pid, cgroupsPath)
+	if err != nil {
+		return nil, newSystemErrorWithCause(err, "rebuilding container state")
+	}
+	if err := writeStateFile(containerRoot, state); err != nil {
+		return nil, newSystemErrorWithCause(err, "saving container state")
+	}
+	return l.newLoadedContainer(id, containerRoot, state, stateLock)
+}
+
+// newLoadedContainer returns the container id described by state.
+func (l *LinuxFactory) newLoadedContainer(id, containerRoot string, state *State, stateLock *os.File) (*linuxContainer, error) {
 	r := &nonChildProcess{
 		processPid:       state.InitProcessPid,
 		processStartTime: state.InitProcessStartTime,
@@ -263,12 +397,15 @@ func (l *LinuxFactory) Load(id string) (Container, error) {
 		cgroupManager:        l.NewCgroupsManager(state.Config.Cgroups, state.CgroupPaths),
 		root:                 containerRoot,
 		created:              state.Created,
+		stateLock:            stateLock,
+		stateLockTimeout:     l.StateLockTimeout,
+		appArmorProfile:      state.AppArmorProfile,
 	}
 	c.state = &loadedState{c: c}
 	if err := c.refreshState(); err != nil {
 		return nil, err
 	}
-	if intelrdt.IsCatEnabled() || intelrdt.IsMbaEnabled() {
+	if intelrdt.IsCatEnabled() || intelrdt.IsMbaEnabled() || intelrdt.IsCmtEnabled() || intelrdt.IsMbmEnabled() {
 		c.intelRdtManager = l.NewIntelRdtManager(&state.Config, id, state.IntelRdtPath)
 	}
 	return c, nil
@@ -354,17 +491,16 @@ func (l *LinuxFactory) loadState(root, id string) (*State, error) {
 	if err != nil {
 		return nil, err
 	}
-	f, err := os.Open(stateFilePath)
+	data, err := ioutil.ReadFile(stateFilePath)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return nil, newGenericError(fmt.Errorf("container %q does not exist", id), ContainerNotExists)
 		}
 		return nil, newGenericError(err, SystemError)
 	}
-	defer f.Close()
-	var state *State
-	if err := json.NewDecoder(f).Decode(&state); err != nil {
-		return nil, newGenericError(err, SystemError)
+	state, err := decodeState(data)
+	if err != nil {
+		return nil, newGenericError(fmt.Errorf("container %q has a damaged state: %v", id, err), ContainerStateDamaged)
 	}
 	return state, nil
 }
@@ -0,0 +1,42 @@
+package libcontainer
+
+// GCReason is the reason why Factory.GarbageCollect collected a container.
+type GCReason string
+
+const (
+	// GCStopped is the reason for containers whose init process has exited,
+	// or whose init process pid has been reused by another process.
+	GCStopped GCReason = "stopped"
+	// GCDamagedState is the reason for containers whose state is missing or
+	// damaged, e.g. because runc crashed while creating them.
+	GCDamagedState GCReason = "damaged state"
+	// GCLeakedResources is the reason for destroyed containers whose cgroups,
+	// Intel RDT group or AppArmor profile could not be removed.
+	GCLeakedResources GCReason = "leaked resources"
+)
+
+// GCResult describes a dead container, or the resources left behind by one,
+// found by Factory.GarbageCollect.
+type GCResult struct {
+	// ID is the container ID.
+	ID string `json:"id"`
+
+	// Reason is why the container was collected.
+	Reason GCReason `json:"reason"`
+
+	// CgroupPaths are the paths of the container's cgroups, keyed by subsystem.
+	CgroupPaths map[string]string `json:"cgroup_paths,omitempty"`
+
+	// IntelRdtPath is the path of the container's Intel RDT group.
+	IntelRdtPath string `json:"intel_rdt_path,omitempty"`
+
+	// AppArmorProfile is the AppArmor profile loaded for the container.
+	AppArmorProfile string `json:"apparmor_profile,omitempty"`
+
+	// Removed is set if the container and its resources have been removed.
+	// It is never set in a dry run.
+	Removed bool `json:"removed"`
+
+	// Error is the error which prevented the removal of the container.
+	Error string `json:"error,omitempty"`
+}
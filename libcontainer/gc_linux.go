@@ -0,0 +1,263 @@
+// +build linux
+
+package libcontainer
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/opencontainers/runc/libcontainer/cgroups"
+	"github.com/opencontainers/runc/libcontainer/cgroups/fs"
+	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runc/libcontainer/utils"
+	"golang.org/x/sys/unix"
+)
+
+// leakedSuffix is the suffix of the files next to the state directories
+// which record the resources of destroyed containers that could not be
+// removed.
+const leakedSuffix = ".leaked"
+
+// resourcesFilename is the file in the state directory which records the
+// cgroups and Intel RDT group of a container as soon as they are created, so
+// that they can be collected if runc crashes before it saves the state.
+const resourcesFilename = "resources.json"
+
+// leakedResources are the resources of a destroyed container which could not
+// be removed.
+type leakedResources struct {
+	CgroupPaths     map[string]string `json:"cgroup_paths,omitempty"`
+	IntelRdtPath    string            `json:"intel_rdt_path,omitempty"`
+	AppArmorProfile string            `json:"apparmor_profile,omitempty"`
+}
+
+// recordLeakedResources records the leaked resources of the container whose
+// state directory was root.
+func recordLeakedResources(root string, leaked *leakedResources) error {
+	f, err := os.OpenFile(root+leakedSuffix, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return utils.WriteJSON(f, leaked)
+}
+
+// recordResources records the cgroups and Intel RDT group of the container in
+// its state directory.
+func (c *linuxContainer) recordResources() error {
+	resources := &leakedResources{CgroupPaths: c.cgroupManager.GetPaths()}
+	if c.intelRdtManager != nil {
+		resources.IntelRdtPath = c.intelRdtManager.GetPath()
+	}
+	f, err := os.OpenFile(filepath.Join(c.root, resourcesFilename), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return utils.WriteJSON(f, resources)
+}
+
+// readResources returns the resources recorded in the state directory root,
+// or nil if none were recorded.
+func readResources(root string) (*leakedResources, error) {
+	data, err := ioutil.ReadFile(filepath.Join(root, resourcesFilename))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var resources leakedResources
+	if err := json.Unmarshal(data, &resources); err != nil {
+		return nil, err
+	}
+	return &resources, nil
+}
+
+// destroyResources kills the processes left in the recorded cgroups of a
+// container whose state was lost and removes its cgroups and Intel RDT group.
+func destroyResources(resources *leakedResources) error {
+	if len(resources.CgroupPaths) > 0 {
+		m := &fs.Manager{
+			Cgroups: &configs.Cgroup{Resources: &configs.Resources{}},
+			Paths:   resources.CgroupPaths,
+		}
+		if err := signalAllProcesses(m, unix.SIGKILL); err != nil {
+			return err
+		}
+		paths := make(map[string]string, len(resources.CgroupPaths))
+		for ss, path := range resources.CgroupPaths {
+			paths[ss] = path
+		}
+		if err := cgroups.RemovePaths(paths); err != nil {
+			return err
+		}
+	}
+	if resources.IntelRdtPath != "" {
+		if err := os.RemoveAll(resources.IntelRdtPath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *LinuxFactory) GarbageCollect(dryRun bool) ([]GCResult, error) {
+	entries, err := ioutil.ReadDir(l.Root)
+	if err != nil {
+		return nil, newGenericError(err, SystemError)
+	}
+	var results []GCResult
+	for _, e := range entries {
+		var r *GCResult
+		if e.IsDir() {
+			r = l.collectContainer(e.Name(), dryRun)
+		} else if id := strings.TrimSuffix(e.Name(), leakedSuffix); id != e.Name() {
+			r = l.collectLeakedResources(id, dryRun)
+		}
+		if r != nil {
+			results = append(results, *r)
+		}
+	}
+	return results, nil
+}
+
+// collectContainer destroys the container id if it is stopped, or removes its
+// state directory if the state is missing or damaged. It returns nil if the
+// container is alive or in use by another process.
+func (l *LinuxFactory) collectContainer(id string, dryRun bool) *GCResult {
+	if err := l.validateID(id); err != nil {
+		return nil
+	}
+	containerRoot := filepath.Join(l.Root, id)
+	// Don't wait for other processes, the container is not dead if it is
+	// being operated on. A container which is being created is locked by
+	// the time the root is unlocked again.
+	rootLock, err := lockRoot(l.Root, ExclusiveStateLock)
+	if err != nil {
+		return &GCResult{ID: id, Reason: GCStopped, Error: err.Error()}
+	}
+	stateLock, err := lockStateDir(containerRoot, id, ExclusiveStateLock, -1)
+	rootLock.Close()
+	if err != nil {
+		return nil
+	}
+	defer stateLock.Close()
+
+	state, err := l.loadState(containerRoot, id)
+	if err != nil {
+		r := &GCResult{ID: id, Reason: GCDamagedState}
+		if lerr, ok := err.(Error); !ok || (lerr.Code() != ContainerStateDamaged && lerr.Code() != ContainerNotExists) {
+			r.Error = err.Error()
+			return r
+		}
+		// The cgroups of the container would be lost together with
+		// its state directory.
+		resources, err := readResources(containerRoot)
+		if err != nil {
+			r.Error = err.Error()
+			return r
+		}
+		if resources != nil {
+			r.CgroupPaths = resources.CgroupPaths
+			r.IntelRdtPath = resources.IntelRdtPath
+		}
+		if !dryRun {
+			if resources != nil {
+				if err := destroyResources(resources); err != nil {
+					r.Error = err.Error()
+					return r
+				}
+			}
+			if err := os.RemoveAll(containerRoot); err != nil {
+				r.Error = err.Error()
+				return r
+			}
+			r.Removed = true
+		}
+		return r
+	}
+
+	c, err := l.newLoadedContainer(id, containerRoot, state, nil)
+	if err != nil {
+		return &GCResult{ID: id, Reason: GCStopped, Error: err.Error()}
+	}
+	status, err := c.Status()
+	if err != nil || status != Stopped {
+		return nil
+	}
+	r := &GCResult{
+		ID:              id,
+		Reason:          GCStopped,
+		CgroupPaths:     state.CgroupPaths,
+		IntelRdtPath:    state.IntelRdtPath,
+		AppArmorProfile: state.AppArmorProfile,
+	}
+	if !dryRun {
+		if err := c.Destroy(); err != nil {
+			r.Error = err.Error()
+			return r
+		}
+		r.Removed = true
+	}
+	return r
+}
+
+// collectLeakedResources removes the resources recorded as leaked by the
+// destroyed container id.
+func (l *LinuxFactory) collectLeakedResources(id string, dryRun bool) *GCResult {
+	if err := l.validateID(id); err != nil {
+		return nil
+	}
+	// A new container with the same id may use the same resources.
+	if _, err := os.Stat(filepath.Join(l.Root, id)); err == nil {
+		return nil
+	}
+	recordPath := filepath.Join(l.Root, id+leakedSuffix)
+	r := &GCResult{ID: id, Reason: GCLeakedResources}
+	data, err := ioutil.ReadFile(recordPath)
+	if err != nil {
+		r.Error = err.Error()
+		return r
+	}
+	var leaked leakedResources
+	if err := json.Unmarshal(data, &leaked); err != nil {
+		r.Error = err.Error()
+		return r
+	}
+	r.CgroupPaths = leaked.CgroupPaths
+	r.IntelRdtPath = leaked.IntelRdtPath
+	r.AppArmorProfile = leaked.AppArmorProfile
+	if dryRun {
+		return r
+	}
+
+	remaining := make(map[string]string, len(leaked.CgroupPaths))
+	for ss, path := range leaked.CgroupPaths {
+		remaining[ss] = path
+	}
+	if err := cgroups.RemovePaths(remaining); err != nil {
+		r.Error = err.Error()
+		return r
+	}
+	if leaked.IntelRdtPath != "" {
+		if err := os.RemoveAll(leaked.IntelRdtPath); err != nil {
+			r.Error = err.Error()
+			return r
+		}
+	}
+	if leaked.AppArmorProfile != "" {
+		if err := releaseAppArmorProfile(l.Root, leaked.AppArmorProfile, id); err != nil {
+			r.Error = err.Error()
+			return r
+		}
+	}
+	if err := os.Remove(recordPath); err != nil {
+		r.Error = err.Error()
+		return r
+	}
+	r.Removed = true
+	return r
+}
@@ -0,0 +1,261 @@
+// +build linux
+
+package libcontainer
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/opencontainers/runc/libcontainer/cgroups"
+	"github.com/opencontainers/runc/libcontainer/cgroups/fs"
+	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runc/libcontainer/system"
+)
+
+func writeTestState(t *testing.T, root, id string, pid int, startTime uint64) {
+	if err := os.Mkdir(filepath.Join(root, id), 0700); err != nil {
+		t.Fatal(err)
+	}
+	state := &State{
+		BaseState: BaseState{
+			ID:                   id,
+			InitProcessPid:       pid,
+			InitProcessStartTime: startTime,
+			Config: configs.Config{
+				Cgroups: &configs.Cgroup{Resources: &configs.Resources{}},
+			},
+		},
+	}
+	if err := writeStateFile(filepath.Join(root, id), state); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGarbageCollect(t *testing.T) {
+	root, err := newTestRoot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	stat, err := system.Stat(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Our own process is the init of a running container, and has reused
+	// the pid of the init of a stopped one.
+	writeTestState(t, root, "running", os.Getpid(), stat.StartTime)
+	writeTestState(t, root, "stopped", os.Getpid(), stat.StartTime+1)
+	if err := os.Mkdir(filepath.Join(root, "damaged"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(root, "damaged", stateFilename), []byte(`{"id":`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	writeTestState(t, root, "locked", os.Getpid(), stat.StartTime+1)
+	lock, err := lockStateDir(filepath.Join(root, "locked"), "locked", SharedStateLock, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lock.Close()
+
+	factory, err := New(root, Cgroupfs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := factory.GarbageCollect(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]GCReason{
+		"damaged": GCDamagedState,
+		"stopped": GCStopped,
+	}
+	if len(results) != len(expected) {
+		t.Fatalf("expected %d results, got %+v", len(expected), results)
+	}
+	for _, r := range results {
+		if expected[r.ID] != r.Reason {
+			t.Fatalf("unexpected result %+v", r)
+		}
+		if r.Removed {
+			t.Fatalf("expected nothing to be removed in a dry run, got %+v", r)
+		}
+		if _, err := os.Stat(filepath.Join(root, r.ID)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	results, err = factory.GarbageCollect(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != len(expected) {
+		t.Fatalf("expected %d results, got %+v", len(expected), results)
+	}
+	for _, r := range results {
+		if !r.Removed || r.Error != "" {
+			t.Fatalf("expected %s to be removed, got %+v", r.ID, r)
+		}
+		if _, err := os.Stat(filepath.Join(root, r.ID)); !os.IsNotExist(err) {
+			t.Fatalf("expected the state directory of %s to be removed, got %v", r.ID, err)
+		}
+	}
+	for _, id := range []string{"running", "locked"} {
+		if _, err := os.Stat(filepath.Join(root, id)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestGarbageCollectLeakedResources(t *testing.T) {
+	root, err := newTestRoot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	cgroup := filepath.Join(root, "cgroup")
+	if err := os.Mkdir(cgroup, 0700); err != nil {
+		t.Fatal(err)
+	}
+	leaked := &leakedResources{CgroupPaths: map[string]string{"memory": cgroup}}
+	if err := recordLeakedResources(filepath.Join(root, "1"), leaked); err != nil {
+		t.Fatal(err)
+	}
+
+	factory, err := New(root, Cgroupfs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := factory.GarbageCollect(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("expected one result, got %+v", results)
+	}
+	if r := results[0]; r.ID != "1" || r.Reason != GCLeakedResources || !r.Removed || r.CgroupPaths["memory"] != cgroup {
+		t.Fatalf("unexpected result %+v", r)
+	}
+	if _, err := os.Stat(cgroup); !os.IsNotExist(err) {
+		t.Fatalf("expected the leaked cgroup to be removed, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "1"+leakedSuffix)); !os.IsNotExist(err) {
+		t.Fatalf("expected the record to be removed, got %v", err)
+	}
+}
+
+func TestGarbageCollectDamagedResources(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("Test requires root.")
+	}
+	root, err := newTestRoot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	mountpoint, err := cgroups.FindCgroupMountpoint("", "devices")
+	if err != nil {
+		t.Skipf("no devices cgroup: %v", err)
+	}
+	cgroup := filepath.Join(mountpoint, fmt.Sprintf("libcontainer-test-gc-%d", os.Getpid()))
+	if err := os.Mkdir(cgroup, 0755); err != nil {
+		t.Skipf("cannot create cgroup: %v", err)
+	}
+	defer os.Remove(cgroup)
+	cmd := exec.Command("sleep", "100")
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer cmd.Process.Kill()
+	if err := cgroups.WriteCgroupProc(cgroup, cmd.Process.Pid); err != nil {
+		t.Fatal(err)
+	}
+	// Reap the process as soon as it is killed, or it stays in the cgroup.
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+
+	// runc crashed after creating the cgroup, before saving the state.
+	c := &linuxContainer{
+		root:          filepath.Join(root, "1"),
+		cgroupManager: &fs.Manager{Paths: map[string]string{"devices": cgroup}},
+	}
+	if err := os.Mkdir(c.root, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.recordResources(); err != nil {
+		t.Fatal(err)
+	}
+
+	factory, err := New(root, Cgroupfs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := factory.GarbageCollect(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("expected one result, got %+v", results)
+	}
+	if r := results[0]; r.ID != "1" || r.Reason != GCDamagedState || !r.Removed || r.CgroupPaths["devices"] != cgroup {
+		t.Fatalf("unexpected result %+v", r)
+	}
+	if err := <-exited; err == nil {
+		t.Fatal("expected the process in the cgroup to be killed")
+	}
+	if _, err := os.Stat(cgroup); !os.IsNotExist(err) {
+		t.Fatalf("expected the cgroup to be removed, got %v", err)
+	}
+}
+
+func TestGarbageCollectWhileCreating(t *testing.T) {
+	root, err := newTestRoot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	// Create has made the state directory, but not locked it yet.
+	rootLock, err := lockRoot(root, SharedStateLock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(root, "1")
+	if err := os.Mkdir(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	factory, err := New(root, Cgroupfs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan []GCResult)
+	go func() {
+		results, err := factory.GarbageCollect(false)
+		if err != nil {
+			t.Error(err)
+		}
+		done <- results
+	}()
+	time.Sleep(20 * time.Millisecond)
+	stateLock, err := lockStateDir(dir, "1", ExclusiveStateLock, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stateLock.Close()
+	rootLock.Close()
+
+	if results := <-done; len(results) != 0 {
+		t.Fatalf("expected the new container to be left alone, got %+v", results)
+	}
+	if _, err := os.Stat(dir); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,87 @@
+// +build linux
+
+package libcontainer
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+	"golang.org/x/sys/unix"
+)
+
+// hotplugOp describes a change made to a running container by a hotplug
+// helper. Exactly one of its fields is set.
+type hotplugOp struct {
+	// Mount is attached inside the container. The detached mount created
+	// from its source is passed to the helper as its first extra file.
+	Mount *configs.Mount `json:"mount,omitempty"`
+	// Unmount is the destination of a mount to be removed from the container.
+	Unmount string `json:"unmount,omitempty"`
+	// Device is a device node to be created in the container. If the node
+	// has to be bind mounted from the host, the detached mount is passed to
+	// the helper as its first extra file.
+	Device *configs.Device `json:"device,omitempty"`
+	// RemoveDevice is the path of a device node to be removed from the
+	// container.
+	RemoveDevice string `json:"remove_device,omitempty"`
+}
+
+// linuxHotplugInit performs a single hotplug operation after joining the
+// namespaces of an existing container. Unlike the other initers it does not
+// exec a user process.
+type linuxHotplugInit struct {
+	pipe   *os.File
+	config *initConfig
+}
+
+func (l *linuxHotplugInit) Init() error {
+	op := l.config.Hotplug
+	if op == nil {
+		return fmt.Errorf("no hotplug operation given")
+	}
+	// Joining the container's mount namespace has already moved us to the
+	// container's root.
+	switch {
+	case op.Mount != nil:
+		f := os.NewFile(uintptr(stdioFdCount), "mount")
+		defer f.Close()
+		if err := attachMountToRootfs(op.Mount, "/", f); err != nil {
+			return newSystemErrorWithCausef(err, "mounting %q at %q", op.Mount.Source, op.Mount.Destination)
+		}
+	case op.Unmount != "":
+		if err := unmountFromRootfs(op.Unmount, "/"); err != nil {
+			return newSystemErrorWithCausef(err, "unmounting %q", op.Unmount)
+		}
+	case op.Device != nil:
+		if err := l.createDevice(op.Device); err != nil {
+			return newSystemErrorWithCausef(err, "creating device node %q", op.Device.Path)
+		}
+	case op.RemoveDevice != "":
+		if err := removeDeviceNode("/", op.RemoveDevice); err != nil {
+			return newSystemErrorWithCausef(err, "removing device node %q", op.RemoveDevice)
+		}
+	default:
+		return fmt.Errorf("empty hotplug operation")
+	}
+	if err := writeSync(l.pipe, procHelperDone); err != nil {
+		return err
+	}
+	// StartInitialization treats any return from Init as a failure, so exit
+	// here once the parent has been told that we are done.
+	os.Exit(0)
+	return nil
+}
+
+// createDevice creates the device node dev, either from the detached bind
+// mount passed by the parent or with mknod.
+func (l *linuxHotplugInit) createDevice(dev *configs.Device) error {
+	if l.config.PassedFilesCount > 0 {
+		f := os.NewFile(uintptr(stdioFdCount), "device")
+		defer f.Close()
+		return attachDeviceNode("/", dev, f)
+	}
+	oldMask := unix.Umask(0000)
+	defer unix.Umask(oldMask)
+	return createDeviceNode("/", dev, false)
+}
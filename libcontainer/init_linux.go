@@ -31,6 +31,7 @@ type initType string
 const (
 	initSetns    initType = "setns"
 	initStandard initType = "standard"
+	initHotplug  initType = "hotplug"
 )
 
 type pid struct {
@@ -63,11 +64,16 @@ type initConfig struct {
 	PassedFilesCount int                   `json:"passed_files_count"`
 	ContainerId      string                `json:"containerid"`
 	Rlimits          []configs.Rlimit      `json:"rlimits"`
+	Landlock         *configs.Landlock     `json:"landlock,omitempty"`
+	Scheduler        *configs.Scheduler    `json:"scheduler,omitempty"`
+	IOPriority       *configs.IOPriority   `json:"io_priority,omitempty"`
+	CPUAffinity      string                `json:"cpu_affinity,omitempty"`
 	CreateConsole    bool                  `json:"create_console"`
 	ConsoleWidth     uint16                `json:"console_width"`
 	ConsoleHeight    uint16                `json:"console_height"`
 	RootlessEUID     bool                  `json:"rootless_euid,omitempty"`
 	RootlessCgroups  bool                  `json:"rootless_cgroups,omitempty"`
+	Hotplug          *hotplugOp            `json:"hotplug,omitempty"`
 }
 
 type initer interface {
@@ -89,6 +95,11 @@ func newContainerInit(t initType, pipe *os.File, consoleSocket *os.File, fifoFd
 			consoleSocket: consoleSocket,
 			config:        config,
 		}, nil
+	case initHotplug:
+		return &linuxHotplugInit{
+			pipe:   pipe,
+			config: config,
+		}, nil
 	case initStandard:
 		return &linuxStandardInit{
 			pipe:          pipe,
@@ -236,6 +247,38 @@ func syncParentHooks(pipe io.ReadWriter) error {
 	return readSync(pipe, procResume)
 }
 
+// syncParentIdmapMounts asks the parent to create the idmapped mounts
+// requested in the config and receives them as detached mount fds, one for
+// each mount with the EXT_IDMAP extension (in the order of config.Mounts).
+// Only the parent has the privileges over the source filesystems that are
+// required to idmap them.
+func syncParentIdmapMounts(pipe *os.File, config *configs.Config) (map[*configs.Mount]*os.File, error) {
+	var mounts []*configs.Mount
+	for _, m := range config.Mounts {
+		if m.Extensions&configs.EXT_IDMAP == configs.EXT_IDMAP {
+			mounts = append(mounts, m)
+		}
+	}
+	if len(mounts) == 0 {
+		return nil, nil
+	}
+	if err := writeSync(pipe, procIdmapMounts); err != nil {
+		return nil, err
+	}
+	fds := make(map[*configs.Mount]*os.File, len(mounts))
+	for _, m := range mounts {
+		f, err := utils.RecvFd(pipe)
+		if err != nil {
+			for _, f := range fds {
+				f.Close()
+			}
+			return nil, fmt.Errorf("receiving idmapped mount for %q: %v", m.Destination, err)
+		}
+		fds[m] = f
+	}
+	return fds, nil
+}
+
 // setupUser changes the groups, gid, and uid for the user inside the container
 func setupUser(config *initConfig) error {
 	// Set up defaults.
@@ -13,6 +13,7 @@ import (
 	"sync"
 
 	"github.com/opencontainers/runc/libcontainer/configs"
+	"golang.org/x/sys/unix"
 )
 
 /*
@@ -31,8 +32,15 @@ import (
  * indicating the percentage of maximum memory bandwidth or memory bandwidth
  * limit in MBps unit if MBA Software Controller is enabled.
  *
- * More details about Intel RDT CAT and MBA can be found in the section 17.18
- * of Intel Software Developer Manual:
+ * Cache Monitoring Technology (CMT) and Memory Bandwidth Monitoring (MBM)
+ * are the monitoring sub-features of RDT. CMT reports the L3 cache occupancy
+ * and MBM reports the total and local memory bandwidth used by the tasks of
+ * a group, per L3 cache domain. A group is identified by resource monitoring
+ * ID (RMID) rather than by CLOS, so tasks can be monitored without being
+ * assigned their own CLOS.
+ *
+ * More details about Intel RDT CAT, MBA, CMT and MBM can be found in the
+ * section 17.18 and 17.19 of Intel Software Developer Manual:
  * https://software.intel.com/en-us/articles/intel-sdm
  *
  * About Intel RDT kernel interface:
@@ -55,16 +63,32 @@ import (
  * |   |   |-- cbm_mask
  * |   |   |-- min_cbm_bits
  * |   |   |-- num_closids
+ * |   |-- L3_MON
+ * |   |   |-- max_threshold_occupancy
+ * |   |   |-- mon_features
+ * |   |   |-- num_rmids
  * |   |-- MB
  * |       |-- bandwidth_gran
  * |       |-- delay_linear
  * |       |-- min_bandwidth
  * |       |-- num_closids
  * |-- ...
+ * |-- mon_data
+ * |   |-- mon_L3_00
+ * |   |   |-- llc_occupancy
+ * |   |   |-- mbm_local_bytes
+ * |   |   |-- mbm_total_bytes
+ * |   |-- ...
+ * |-- mon_groups
+ * |   |-- <container_id>
+ * |       |-- ...
+ * |       |-- mon_data
+ * |       |-- tasks
  * |-- schemata
  * |-- tasks
  * |-- <container_id>
  *     |-- ...
+ *     |-- mon_data
  *     |-- schemata
  *     |-- tasks
  *
@@ -119,6 +143,38 @@ import (
  * "MB:0=5000;1=7000" which means 5000 MBps memory bandwidth limit on socket 0
  * and 7000 MBps memory bandwidth limit on socket 1.
  *
+ * Monitoring:
+ * Each group has a "mon_data" directory with one "mon_L3_XX" directory per
+ * L3 cache domain, where XX is the L3 cache id. The "llc_occupancy" file has
+ * the L3 cache occupancy in bytes (CMT), and the "mbm_total_bytes" and
+ * "mbm_local_bytes" files have the total and local memory bandwidth counters
+ * in bytes (MBM).
+ *
+ * If neither an L3 cache schema nor a memory bandwidth schema is specified,
+ * runc creates a monitoring group "mon_groups/<container_id>" in the default
+ * CLOS instead of a new CLOS. The container is then only monitored and keeps
+ * sharing the L3 cache and memory bandwidth of the root group:
+ *
+ * "linux": {
+ *     "intelRdt": {}
+ * }
+ *
+ * Shared groups:
+ * The number of CLOS is limited by hardware (see "info/L3/num_closids"), so
+ * a container may join a named group with "closID" instead of getting its
+ * own group named after the container ID. The group is created if it does
+ * not exist yet. The tasks of a group are its references: on Destroy, the
+ * group is only removed once no tasks are left in it, and a group joined
+ * without any schema is considered to be managed externally and is never
+ * removed by runc.
+ *
+ * "linux": {
+ *     "intelRdt": {
+ *         "closID": "guaranteed_group",
+ *         "l3CacheSchema": "L3:0=7f0;1=1f"
+ * 	}
+ * }
+ *
  * For more information about Intel RDT kernel interface:
  * https://www.kernel.org/doc/Documentation/x86/intel_rdt_ui.txt
  *
@@ -180,6 +236,10 @@ var (
 	isMbaEnabled bool
 	// The flag to indicate if Intel RDT/MBA Software Controller is enabled
 	isMbaScEnabled bool
+	// The flag to indicate if Intel RDT/CMT is enabled
+	isCmtEnabled bool
+	// The flag to indicate if Intel RDT/MBM is enabled
+	isMbmEnabled bool
 )
 
 type intelRdtData struct {
@@ -191,8 +251,7 @@ type intelRdtData struct {
 // Check if Intel RDT sub-features are enabled in init()
 func init() {
 	// 1. Check if hardware and kernel support Intel RDT sub-features
-	// "cat_l3" flag for CAT and "mba" flag for MBA
-	isCatFlagSet, isMbaFlagSet, err := parseCpuInfoFile("/proc/cpuinfo")
+	flagsSet, err := parseCpuInfoFile("/proc/cpuinfo")
 	if err != nil {
 		return
 	}
@@ -207,7 +266,7 @@ func init() {
 	// "resource control" filesystem. Intel RDT sub-features can be
 	// selectively disabled or enabled by kernel command line
 	// (e.g., rdt=!l3cat,mba) in 4.14 and newer kernel
-	if isCatFlagSet {
+	if flagsSet.cat {
 		if _, err := os.Stat(filepath.Join(intelRdtRoot, "info", "L3")); err == nil {
 			isCatEnabled = true
 		}
@@ -217,11 +276,26 @@ func init() {
 		// MBA should be enabled because MBA Software Controller
 		// depends on MBA
 		isMbaEnabled = true
-	} else if isMbaFlagSet {
+	} else if flagsSet.mba {
 		if _, err := os.Stat(filepath.Join(intelRdtRoot, "info", "MB")); err == nil {
 			isMbaEnabled = true
 		}
 	}
+
+	// The monitoring sub-features are only usable if the events are also
+	// listed in "info/L3_MON/mon_features"
+	if flagsSet.cmt || flagsSet.mbmTotal || flagsSet.mbmLocal {
+		features, err := getMonFeatures(filepath.Join(intelRdtRoot, "info", "L3_MON", "mon_features"))
+		if err != nil {
+			return
+		}
+		features.llcOccupancy = features.llcOccupancy && flagsSet.cmt
+		features.mbmTotalBytes = features.mbmTotalBytes && flagsSet.mbmTotal
+		features.mbmLocalBytes = features.mbmLocalBytes && flagsSet.mbmLocal
+		enabledMonFeatures = features
+		isCmtEnabled = features.llcOccupancy
+		isMbmEnabled = features.mbmTotalBytes || features.mbmLocalBytes
+	}
 }
 
 // Return the mount point path of Intel RDT "resource control" filesysem
@@ -298,40 +372,54 @@ func isIntelRdtMounted() bool {
 	return true
 }
 
-func parseCpuInfoFile(path string) (bool, bool, error) {
-	isCatFlagSet := false
-	isMbaFlagSet := false
+// The Intel RDT sub-features supported by hardware and kernel
+type cpuInfoFlags struct {
+	cat bool // Cache Allocation Technology
+	mba bool // Memory Bandwidth Allocation
+
+	cmt      bool // Cache Monitoring Technology
+	mbmTotal bool // Memory Bandwidth Monitoring, total bandwidth
+	mbmLocal bool // Memory Bandwidth Monitoring, local bandwidth
+}
+
+func parseCpuInfoFile(path string) (cpuInfoFlags, error) {
+	infoFlags := cpuInfoFlags{}
 
 	f, err := os.Open(path)
 	if err != nil {
-		return false, false, err
+		return infoFlags, err
 	}
 	defer f.Close()
 
 	s := bufio.NewScanner(f)
 	for s.Scan() {
 		if err := s.Err(); err != nil {
-			return false, false, err
+			return infoFlags, err
 		}
 
 		line := s.Text()
 
-		// Search "cat_l3" and "mba" flags in first "flags" line
+		// Search the Intel RDT flags in first "flags" line
 		if strings.Contains(line, "flags") {
 			flags := strings.Split(line, " ")
-			// "cat_l3" flag for CAT and "mba" flag for MBA
 			for _, flag := range flags {
 				switch flag {
 				case "cat_l3":
-					isCatFlagSet = true
+					infoFlags.cat = true
 				case "mba":
-					isMbaFlagSet = true
+					infoFlags.mba = true
+				case "cqm_occup_llc":
+					infoFlags.cmt = true
+				case "cqm_mbm_total":
+					infoFlags.mbmTotal = true
+				case "cqm_mbm_local":
+					infoFlags.mbmLocal = true
 				}
 			}
-			return isCatFlagSet, isMbaFlagSet, nil
+			return infoFlags, nil
 		}
 	}
-	return isCatFlagSet, isMbaFlagSet, nil
+	return infoFlags, nil
 }
 
 func parseUint(s string, base, bitSize int) (uint64, error) {
@@ -510,6 +598,16 @@ func IsMbaScEnabled() bool {
 	return isMbaScEnabled
 }
 
+// Check if Intel RDT/CMT is enabled
+func IsCmtEnabled() bool {
+	return isCmtEnabled
+}
+
+// Check if Intel RDT/MBM is enabled
+func IsMbmEnabled() bool {
+	return isMbmEnabled
+}
+
 // Get the 'container_id' path in Intel RDT "resource control" filesystem
 func GetIntelRdtPath(id string) (string, error) {
 	rootPath, err := getIntelRdtRoot()
@@ -521,6 +619,132 @@ func GetIntelRdtPath(id string) (string, error) {
 	return path, nil
 }
 
+// IsMonitoringOnly reports whether the Intel RDT configuration only asks
+// for monitoring, i.e. neither an L3 cache schema nor a memory bandwidth
+// schema is specified. Such a container is placed into a monitoring group
+// of the default CLOS.
+func IsMonitoringOnly(c *configs.IntelRdt) bool {
+	return c != nil && c.ClosID == "" && c.L3CacheSchema == "" && c.MemBwSchema == ""
+}
+
+// isExternallyManaged reports whether the container joins a named group
+// without specifying any schema. Such a group is left alone on Destroy.
+func isExternallyManaged(c *configs.IntelRdt) bool {
+	return c != nil && c.ClosID != "" && c.L3CacheSchema == "" && c.MemBwSchema == ""
+}
+
+// Get the path of the group for the container with the given id, relative
+// to the root of Intel RDT "resource control" filesystem
+func groupPath(c *configs.Config, id string) string {
+	if IsMonitoringOnly(c.IntelRdt) {
+		return filepath.Join("mon_groups", id)
+	}
+	if c.IntelRdt != nil && c.IntelRdt.ClosID != "" {
+		return c.IntelRdt.ClosID
+	}
+	return id
+}
+
+// GetSchemata returns the schemata of the group with the given CLOS id. The
+// error satisfies os.IsNotExist if there is no such group.
+func GetSchemata(closID string) (string, error) {
+	path, err := GetIntelRdtPath(closID)
+	if err != nil {
+		return "", err
+	}
+	return getIntelRdtParamString(path, "schemata")
+}
+
+// Parse a single schema line, e.g. "L3:0=7f0;1=1f", into its resource name
+// and the values per cache id
+func parseSchema(schema string) (string, map[string]string, error) {
+	parts := strings.SplitN(strings.TrimSpace(schema), ":", 2)
+	if len(parts) != 2 {
+		return "", nil, fmt.Errorf("invalid schema %q", schema)
+	}
+	values := make(map[string]string)
+	for _, domain := range strings.Split(parts[1], ";") {
+		kv := strings.SplitN(domain, "=", 2)
+		if len(kv) != 2 {
+			return "", nil, fmt.Errorf("invalid schema %q", schema)
+		}
+		values[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+	return strings.TrimSpace(parts[0]), values, nil
+}
+
+// CheckSchemaConflict returns an error if schema sets a different value for
+// any cache id than the line of the same resource in schemata. The kernel
+// pads the values it reports, so they are compared as numbers: hexadecimal
+// bitmasks for the L3 cache and decimal values for memory bandwidth.
+func CheckSchemaConflict(schemata, schema string) error {
+	resource, values, err := parseSchema(schema)
+	if err != nil {
+		return err
+	}
+	base := 10
+	if strings.HasPrefix(resource, "L3") {
+		base = 16
+	}
+	for _, line := range strings.Split(schemata, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		r, existing, err := parseSchema(line)
+		if err != nil {
+			return err
+		}
+		if r != resource {
+			continue
+		}
+		for id, value := range values {
+			old, ok := existing[id]
+			if !ok {
+				continue
+			}
+			v, err := strconv.ParseUint(value, base, 64)
+			if err != nil {
+				return fmt.Errorf("invalid value %q in schema %q", value, schema)
+			}
+			o, err := strconv.ParseUint(old, base, 64)
+			if err != nil {
+				return fmt.Errorf("invalid value %q in schema %q", old, line)
+			}
+			if v != o {
+				return fmt.Errorf("schema %q conflicts with %q", schema, strings.TrimSpace(line))
+			}
+		}
+	}
+	return nil
+}
+
+// Take an exclusive lock on the root of Intel RDT "resource control"
+// filesystem, so that joining and removing shared groups by concurrent runc
+// processes do not race with each other
+func lockIntelRdtRoot(root string) (*os.File, error) {
+	f, err := os.Open(root)
+	if err != nil {
+		return nil, err
+	}
+	if err := unix.Flock(int(f.Fd()), unix.LOCK_EX); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// Get the number of tasks in the group at path
+func countTasks(path string) (int, error) {
+	tasks, err := getIntelRdtParamString(path, IntelRdtTasks)
+	if err != nil {
+		return 0, err
+	}
+	if tasks == "" {
+		return 0, nil
+	}
+	return len(strings.Split(tasks, "\n")), nil
+}
+
 // Applies Intel RDT configuration to the process with the specified pid
 func (m *IntelRdtManager) Apply(pid int) (err error) {
 	// If intelRdt is not specified in config, we do nothing
@@ -534,7 +758,14 @@ func (m *IntelRdtManager) Apply(pid int) (err error) {
 
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	path, err := d.join(m.Id)
+	if m.Config.IntelRdt.ClosID != "" {
+		lock, err := lockIntelRdtRoot(d.root)
+		if err != nil {
+			return err
+		}
+		defer lock.Close()
+	}
+	path, err := d.join(groupPath(m.Config, m.Id))
 	if err != nil {
 		return err
 	}
@@ -543,10 +774,32 @@ func (m *IntelRdtManager) Apply(pid int) (err error) {
 	return nil
 }
 
-// Destroys the Intel RDT 'container_id' group
+// Destroys the Intel RDT 'container_id' group. A shared group is only
+// removed once the last task has left it.
 func (m *IntelRdtManager) Destroy() error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	if c := m.Config.IntelRdt; c != nil && c.ClosID != "" && m.Path != "" {
+		if isExternallyManaged(c) {
+			m.Path = ""
+			return nil
+		}
+		lock, err := lockIntelRdtRoot(filepath.Dir(m.Path))
+		if err != nil {
+			return err
+		}
+		defer lock.Close()
+		// Removing a group moves its remaining tasks to the default group,
+		// which would silently take them away from the other containers.
+		n, err := countTasks(m.Path)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if n > 0 {
+			m.Path = ""
+			return nil
+		}
+	}
 	if err := os.RemoveAll(m.Path); err != nil {
 		return err
 	}
@@ -558,7 +811,7 @@ func (m *IntelRdtManager) Destroy() error {
 // restore the object later
 func (m *IntelRdtManager) GetPath() string {
 	if m.Path == "" {
-		m.Path, _ = GetIntelRdtPath(m.Id)
+		m.Path, _ = GetIntelRdtPath(groupPath(m.Config, m.Id))
 	}
 	return m.Path
 }
@@ -585,12 +838,17 @@ func (m *IntelRdtManager) GetStats() (*Stats, error) {
 	}
 	schemaRootStrings := strings.Split(tmpRootStrings, "\n")
 
-	// The L3 cache and memory bandwidth schemata in 'container_id' group
-	tmpStrings, err := getIntelRdtParamString(m.GetPath(), "schemata")
-	if err != nil {
-		return nil, err
+	// The L3 cache and memory bandwidth schemata in 'container_id' group.
+	// A monitoring group has no schemata of its own, it uses the ones of
+	// the default CLOS.
+	schemaStrings := schemaRootStrings
+	if !IsMonitoringOnly(m.Config.IntelRdt) {
+		tmpStrings, err := getIntelRdtParamString(m.GetPath(), "schemata")
+		if err != nil {
+			return nil, err
+		}
+		schemaStrings = strings.Split(tmpStrings, "\n")
 	}
-	schemaStrings := strings.Split(tmpStrings, "\n")
 
 	if IsCatEnabled() {
 		// The read-only L3 cache information
@@ -638,6 +896,12 @@ func (m *IntelRdtManager) GetStats() (*Stats, error) {
 		}
 	}
 
+	if IsCmtEnabled() || IsMbmEnabled() {
+		if err := getMonitoringStats(m.GetPath(), stats); err != nil {
+			return nil, err
+		}
+	}
+
 	return stats, nil
 }
 
@@ -3,8 +3,12 @@
 package intelrdt
 
 import (
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
 )
 
 func TestIntelRdtSetL3CacheSchema(t *testing.T) {
@@ -120,3 +124,110 @@ func TestIntelRdtSetMemBwScSchema(t *testing.T) {
 		t.Fatal("Got the wrong value, set 'schemata' failed.")
 	}
 }
+
+func TestCheckSchemaConflict(t *testing.T) {
+	// The kernel pads the values it reports
+	const schemata = "L3:0=007f0;1=0001f\nMB:0= 20;1= 70"
+
+	testCases := []struct {
+		schema   string
+		conflict bool
+	}{
+		{"L3:0=7f0;1=1f", false},
+		{"L3:1=1f", false},
+		{"MB:0=20;1=70", false},
+		{"L3:0=7f0;1=ff", true},
+		{"MB:0=30", true},
+		{"L3:2=ff", false},
+	}
+	for _, tc := range testCases {
+		err := CheckSchemaConflict(schemata, tc.schema)
+		if tc.conflict && err == nil {
+			t.Errorf("expected %q to conflict with %q", tc.schema, schemata)
+		}
+		if !tc.conflict && err != nil {
+			t.Errorf("expected %q not to conflict with %q: %v", tc.schema, schemata, err)
+		}
+	}
+
+	if err := CheckSchemaConflict(schemata, "L3"); err == nil {
+		t.Error("expected an invalid schema to be rejected")
+	}
+}
+
+func TestIntelRdtSharedGroup(t *testing.T) {
+	helper := NewIntelRdtTestUtil(t)
+	defer helper.cleanup()
+	defer helper.mockRoot()()
+
+	config := &configs.Config{
+		IntelRdt: &configs.IntelRdt{
+			ClosID:        "shared",
+			L3CacheSchema: "L3:0=f0",
+		},
+	}
+	first := &IntelRdtManager{Config: config, Id: "first"}
+	second := &IntelRdtManager{Config: config, Id: "second"}
+	for _, m := range []*IntelRdtManager{first, second} {
+		if err := m.Apply(-1); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	path := filepath.Join(helper.IntelRdtPath, "shared")
+	if first.GetPath() != path || second.GetPath() != path {
+		t.Fatalf("expected both containers in %q, got %q and %q", path, first.GetPath(), second.GetPath())
+	}
+
+	// The second container still has a task in the group
+	if err := writeFile(path, IntelRdtTasks, "1234"); err != nil {
+		t.Fatal(err)
+	}
+	if err := first.Destroy(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("expected the shared group to be kept: %v", err)
+	}
+
+	// The last task has left
+	if err := writeFile(path, IntelRdtTasks, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := second.Destroy(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("expected the shared group to be removed, got %v", err)
+	}
+}
+
+func TestIntelRdtExternallyManagedGroup(t *testing.T) {
+	helper := NewIntelRdtTestUtil(t)
+	defer helper.cleanup()
+	defer helper.mockRoot()()
+
+	path := filepath.Join(helper.IntelRdtPath, "external")
+	if err := os.MkdirAll(path, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	m := &IntelRdtManager{
+		Config: &configs.Config{
+			IntelRdt: &configs.IntelRdt{ClosID: "external"},
+		},
+		Id: "container",
+	}
+	if err := m.Apply(-1); err != nil {
+		t.Fatal(err)
+	}
+	if m.GetPath() != path {
+		t.Fatalf("expected path %q, got %q", path, m.GetPath())
+	}
+	if err := m.Destroy(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("expected the externally managed group to be kept: %v", err)
+	}
+}
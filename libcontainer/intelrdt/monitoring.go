@@ -0,0 +1,121 @@
+// +build linux
+
+package intelrdt
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// The monitoring features listed in "info/L3_MON/mon_features"
+var enabledMonFeatures monFeatures
+
+type monFeatures struct {
+	mbmTotalBytes bool
+	mbmLocalBytes bool
+	llcOccupancy  bool
+}
+
+// Parse the monitoring features supported by the "resource control"
+// filesystem, one event name per line
+func getMonFeatures(path string) (monFeatures, error) {
+	features := monFeatures{}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return features, err
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		switch strings.TrimSpace(s.Text()) {
+		case "mbm_total_bytes":
+			features.mbmTotalBytes = true
+		case "mbm_local_bytes":
+			features.mbmLocalBytes = true
+		case "llc_occupancy":
+			features.llcOccupancy = true
+		}
+	}
+	return features, s.Err()
+}
+
+// Get the Intel RDT monitoring statistics of the group at containerPath.
+// The per L3 cache domain counters are found in "mon_data/mon_L3_XX", where
+// XX is the L3 cache id.
+func getMonitoringStats(containerPath string, stats *Stats) error {
+	monDataPath := filepath.Join(containerPath, "mon_data")
+	domains, err := ioutil.ReadDir(monDataPath)
+	if err != nil {
+		return err
+	}
+
+	var (
+		mbmStats []MBMNumaNodeStats
+		cmtStats []CMTNumaNodeStats
+	)
+	for _, domain := range domains {
+		if !strings.HasPrefix(domain.Name(), "mon_L3_") {
+			continue
+		}
+		path := filepath.Join(monDataPath, domain.Name())
+
+		if IsMbmEnabled() {
+			s, err := getMBMNumaNodeStats(path)
+			if err != nil {
+				return err
+			}
+			mbmStats = append(mbmStats, *s)
+		}
+		if IsCmtEnabled() {
+			s, err := getCMTNumaNodeStats(path)
+			if err != nil {
+				return err
+			}
+			cmtStats = append(cmtStats, *s)
+		}
+	}
+
+	if IsMbmEnabled() {
+		stats.MBMStats = &mbmStats
+	}
+	if IsCmtEnabled() {
+		stats.CMTStats = &cmtStats
+	}
+	return nil
+}
+
+// Get the memory bandwidth monitoring counters of a single L3 cache domain
+func getMBMNumaNodeStats(path string) (*MBMNumaNodeStats, error) {
+	stats := &MBMNumaNodeStats{}
+	if enabledMonFeatures.mbmTotalBytes {
+		mbmTotalBytes, err := getIntelRdtParamUint(path, "mbm_total_bytes")
+		if err != nil {
+			return nil, err
+		}
+		stats.MBMTotalBytes = mbmTotalBytes
+	}
+	if enabledMonFeatures.mbmLocalBytes {
+		mbmLocalBytes, err := getIntelRdtParamUint(path, "mbm_local_bytes")
+		if err != nil {
+			return nil, err
+		}
+		stats.MBMLocalBytes = mbmLocalBytes
+	}
+	return stats, nil
+}
+
+// Get the cache occupancy of a single L3 cache domain
+func getCMTNumaNodeStats(path string) (*CMTNumaNodeStats, error) {
+	llcOccupancy, err := getIntelRdtParamUint(path, "llc_occupancy")
+	if err != nil {
+		return nil, err
+	}
+	return &CMTNumaNodeStats{
+		LLCOccupancy: llcOccupancy,
+	}, nil
+}
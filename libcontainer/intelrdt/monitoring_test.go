@@ -0,0 +1,169 @@
+// +build linux
+
+package intelrdt
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+func TestParseCpuInfoFile(t *testing.T) {
+	helper := NewIntelRdtTestUtil(t)
+	defer helper.cleanup()
+
+	helper.writeFileContents(map[string]string{
+		"cpuinfo": "processor\t: 0\n" +
+			"flags\t\t: fpu vme cat_l3 cdp_l3 cqm_llc cqm_occup_llc cqm_mbm_total\n" +
+			"bugs\t\t: spectre_v1\n",
+	})
+
+	flags, err := parseCpuInfoFile(filepath.Join(helper.IntelRdtPath, "cpuinfo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := cpuInfoFlags{cat: true, cmt: true, mbmTotal: true}
+	if flags != expected {
+		t.Fatalf("expected flags %+v, got %+v", expected, flags)
+	}
+}
+
+func TestGetMonFeatures(t *testing.T) {
+	helper := NewIntelRdtTestUtil(t)
+	defer helper.cleanup()
+
+	helper.writeFileContents(map[string]string{
+		"mon_features": "llc_occupancy\nmbm_total_bytes\n",
+	})
+
+	features, err := getMonFeatures(filepath.Join(helper.IntelRdtPath, "mon_features"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := monFeatures{llcOccupancy: true, mbmTotalBytes: true}
+	if features != expected {
+		t.Fatalf("expected features %+v, got %+v", expected, features)
+	}
+}
+
+// Set up a fake "mon_data" tree with one directory per L3 cache domain
+func mockMonData(t *testing.T, path string, domains map[string]map[string]string) {
+	for domain, files := range domains {
+		dir := filepath.Join(path, "mon_data", domain)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		for file, contents := range files {
+			if err := writeFile(dir, file, contents); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+func setMonitoringEnabled(cmt bool, features monFeatures) func() {
+	oldCmt, oldMbm, oldFeatures := isCmtEnabled, isMbmEnabled, enabledMonFeatures
+	isCmtEnabled = cmt
+	isMbmEnabled = features.mbmTotalBytes || features.mbmLocalBytes
+	enabledMonFeatures = features
+	return func() {
+		isCmtEnabled, isMbmEnabled, enabledMonFeatures = oldCmt, oldMbm, oldFeatures
+	}
+}
+
+func TestGetMonitoringStats(t *testing.T) {
+	helper := NewIntelRdtTestUtil(t)
+	defer helper.cleanup()
+	defer setMonitoringEnabled(true, monFeatures{
+		llcOccupancy:  true,
+		mbmTotalBytes: true,
+		mbmLocalBytes: true,
+	})()
+
+	mockMonData(t, helper.IntelRdtPath, map[string]map[string]string{
+		"mon_L3_00": {
+			"llc_occupancy":   "1048576",
+			"mbm_total_bytes": "4096",
+			"mbm_local_bytes": "2048",
+		},
+		"mon_L3_01": {
+			"llc_occupancy":   "524288",
+			"mbm_total_bytes": "8192",
+			"mbm_local_bytes": "1024",
+		},
+	})
+
+	stats := NewStats()
+	if err := getMonitoringStats(helper.IntelRdtPath, stats); err != nil {
+		t.Fatal(err)
+	}
+
+	expectedMBM := []MBMNumaNodeStats{
+		{MBMTotalBytes: 4096, MBMLocalBytes: 2048},
+		{MBMTotalBytes: 8192, MBMLocalBytes: 1024},
+	}
+	if stats.MBMStats == nil || !reflect.DeepEqual(*stats.MBMStats, expectedMBM) {
+		t.Fatalf("expected MBM stats %+v, got %+v", expectedMBM, stats.MBMStats)
+	}
+	expectedCMT := []CMTNumaNodeStats{
+		{LLCOccupancy: 1048576},
+		{LLCOccupancy: 524288},
+	}
+	if stats.CMTStats == nil || !reflect.DeepEqual(*stats.CMTStats, expectedCMT) {
+		t.Fatalf("expected CMT stats %+v, got %+v", expectedCMT, stats.CMTStats)
+	}
+}
+
+func TestGetMonitoringStatsMBMTotalOnly(t *testing.T) {
+	helper := NewIntelRdtTestUtil(t)
+	defer helper.cleanup()
+	defer setMonitoringEnabled(false, monFeatures{mbmTotalBytes: true})()
+
+	mockMonData(t, helper.IntelRdtPath, map[string]map[string]string{
+		"mon_L3_00": {
+			"mbm_total_bytes": "4096",
+		},
+	})
+
+	stats := NewStats()
+	if err := getMonitoringStats(helper.IntelRdtPath, stats); err != nil {
+		t.Fatal(err)
+	}
+
+	expectedMBM := []MBMNumaNodeStats{{MBMTotalBytes: 4096}}
+	if stats.MBMStats == nil || !reflect.DeepEqual(*stats.MBMStats, expectedMBM) {
+		t.Fatalf("expected MBM stats %+v, got %+v", expectedMBM, stats.MBMStats)
+	}
+	if stats.CMTStats != nil {
+		t.Fatalf("expected no CMT stats, got %+v", stats.CMTStats)
+	}
+}
+
+func TestApplyMonitoringGroup(t *testing.T) {
+	helper := NewIntelRdtTestUtil(t)
+	defer helper.cleanup()
+	defer helper.mockRoot()()
+
+	m := &IntelRdtManager{
+		Config: &configs.Config{IntelRdt: &configs.IntelRdt{}},
+		Id:     "monitored",
+	}
+	if err := m.Apply(-1); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := filepath.Join(helper.IntelRdtPath, "mon_groups", "monitored")
+	if m.GetPath() != expected {
+		t.Fatalf("expected path %q, got %q", expected, m.GetPath())
+	}
+	if _, err := os.Stat(expected); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ioutil.ReadFile(filepath.Join(helper.IntelRdtPath, "monitored", "schemata")); !os.IsNotExist(err) {
+		t.Fatalf("expected no CLOS group to be created, got %v", err)
+	}
+}
@@ -65,3 +65,17 @@ func (c *intelRdtTestUtil) writeFileContents(fileContents map[string]string) {
 		}
 	}
 }
+
+// Point the cached root of Intel RDT "resource control" filesystem at the
+// mock filesystem until the returned function is called
+func (c *intelRdtTestUtil) mockRoot() func() {
+	intelRdtRootLock.Lock()
+	oldRoot := intelRdtRoot
+	intelRdtRoot = c.IntelRdtPath
+	intelRdtRootLock.Unlock()
+	return func() {
+		intelRdtRootLock.Lock()
+		intelRdtRoot = oldRoot
+		intelRdtRootLock.Unlock()
+	}
+}
@@ -0,0 +1,84 @@
+package landlock
+
+import (
+	"fmt"
+)
+
+// Filesystem access rights of Landlock, LANDLOCK_ACCESS_FS_*.
+const (
+	AccessExecute    = 1 << 0
+	AccessWriteFile  = 1 << 1
+	AccessReadFile   = 1 << 2
+	AccessReadDir    = 1 << 3
+	AccessRemoveDir  = 1 << 4
+	AccessRemoveFile = 1 << 5
+	AccessMakeChar   = 1 << 6
+	AccessMakeDir    = 1 << 7
+	AccessMakeReg    = 1 << 8
+	AccessMakeSock   = 1 << 9
+	AccessMakeFifo   = 1 << 10
+	AccessMakeBlock  = 1 << 11
+	AccessMakeSym    = 1 << 12
+	AccessRefer      = 1 << 13
+	AccessTruncate   = 1 << 14
+	AccessIoctlDev   = 1 << 15
+)
+
+var accessRights = map[string]uint64{
+	"execute":     AccessExecute,
+	"write_file":  AccessWriteFile,
+	"read_file":   AccessReadFile,
+	"read_dir":    AccessReadDir,
+	"remove_dir":  AccessRemoveDir,
+	"remove_file": AccessRemoveFile,
+	"make_char":   AccessMakeChar,
+	"make_dir":    AccessMakeDir,
+	"make_reg":    AccessMakeReg,
+	"make_sock":   AccessMakeSock,
+	"make_fifo":   AccessMakeFifo,
+	"make_block":  AccessMakeBlock,
+	"make_sym":    AccessMakeSym,
+	"refer":       AccessRefer,
+	"truncate":    AccessTruncate,
+	"ioctl_dev":   AccessIoctlDev,
+}
+
+// fileAccess are the access rights which apply to files, as opposed to the
+// ones which only apply to the content of directories.
+const fileAccess = AccessExecute | AccessWriteFile | AccessReadFile | AccessTruncate | AccessIoctlDev
+
+// abiAccess[v] are the access rights supported by version v+1 of the
+// Landlock ABI.
+var abiAccess = []uint64{
+	AccessMakeSym<<1 - 1,
+	AccessRefer<<1 - 1,
+	AccessTruncate<<1 - 1,
+	AccessTruncate<<1 - 1,
+	AccessIoctlDev<<1 - 1,
+}
+
+// ParseAccess converts the names of access rights, e.g. "read_file", to
+// their bits.
+func ParseAccess(names []string) (uint64, error) {
+	var access uint64
+	for _, name := range names {
+		right, ok := accessRights[name]
+		if !ok {
+			return 0, fmt.Errorf("unknown landlock access right %q", name)
+		}
+		access |= right
+	}
+	return access, nil
+}
+
+// supportedAccess returns the access rights supported by the given version
+// of the Landlock ABI.
+func supportedAccess(abi int) uint64 {
+	if abi < 1 {
+		return 0
+	}
+	if abi > len(abiAccess) {
+		abi = len(abiAccess)
+	}
+	return abiAccess[abi-1]
+}
@@ -0,0 +1,34 @@
+package landlock
+
+import (
+	"testing"
+)
+
+func TestParseAccess(t *testing.T) {
+	access, err := ParseAccess([]string{"execute", "read_file", "read_dir"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := uint64(AccessExecute | AccessReadFile | AccessReadDir); access != expected {
+		t.Fatalf("expected access %#x, got %#x", expected, access)
+	}
+	if _, err := ParseAccess([]string{"read_file", "LANDLOCK_ACCESS_FS_READ_DIR"}); err == nil {
+		t.Fatal("expected an error for an unknown access right")
+	}
+}
+
+func TestSupportedAccess(t *testing.T) {
+	for abi, expected := range map[int]uint64{
+		0:  0,
+		1:  0x1fff,
+		2:  0x3fff,
+		3:  0x7fff,
+		4:  0x7fff,
+		5:  0xffff,
+		10: 0xffff,
+	} {
+		if access := supportedAccess(abi); access != expected {
+			t.Errorf("expected access %#x for ABI version %d, got %#x", expected, abi, access)
+		}
+	}
+}
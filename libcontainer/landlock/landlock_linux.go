@@ -0,0 +1,163 @@
+// +build linux
+
+package landlock
+
+import (
+	"fmt"
+	"os"
+	"unsafe"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+	"golang.org/x/sys/unix"
+)
+
+// The Landlock system calls have the same numbers on all architectures.
+const (
+	sysLandlockCreateRuleset = 444
+	sysLandlockAddRule       = 445
+	sysLandlockRestrictSelf  = 446
+)
+
+const (
+	createRulesetVersion = 1 << 0
+	rulePathBeneath      = 1
+)
+
+// rulesetAttr is struct landlock_ruleset_attr, without the network access
+// rights.
+type rulesetAttr struct {
+	handledAccessFS uint64
+}
+
+// pathBeneathAttr is struct landlock_path_beneath_attr. The kernel struct is
+// packed, which only drops the padding at the end.
+type pathBeneathAttr struct {
+	allowedAccess uint64
+	parentFd      int32
+}
+
+// ABIVersion returns the version of the Landlock ABI of the kernel.
+func ABIVersion() (int, error) {
+	v, _, err := unix.Syscall(sysLandlockCreateRuleset, 0, 0, createRulesetVersion)
+	if err != 0 {
+		return 0, err
+	}
+	return int(v), nil
+}
+
+// IsEnabled returns true if Landlock is supported and enabled by the kernel.
+func IsEnabled() bool {
+	_, err := ABIVersion()
+	return err == nil
+}
+
+// Ruleset is a Landlock ruleset which has been created but is not enforced
+// yet.
+type Ruleset struct {
+	fd int
+}
+
+// NewRuleset creates the ruleset of config. All the access rights known to
+// the kernel are handled, so anything which is not granted by a rule is
+// denied. The paths of the rules are opened right away, so that errors are
+// reported before the ruleset is enforced. It returns nil if config is nil,
+// or if Landlock is not supported and config is best-effort.
+func NewRuleset(config *configs.Landlock) (*Ruleset, error) {
+	if config == nil {
+		return nil, nil
+	}
+	abi, err := ABIVersion()
+	if err != nil {
+		if (err == unix.ENOSYS || err == unix.EOPNOTSUPP) && !config.DisableBestEffort {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("landlock is not supported: %v", err)
+	}
+	supported := supportedAccess(abi)
+	allowed := make([]uint64, len(config.Rules))
+	for i, r := range config.Rules {
+		access, err := ParseAccess(r.Access)
+		if err != nil {
+			return nil, err
+		}
+		if unsupported := access &^ supported; unsupported != 0 && config.DisableBestEffort {
+			return nil, fmt.Errorf("landlock ABI version %d does not support the access rights %#x of %s", abi, unsupported, r.Path)
+		}
+		allowed[i] = access & supported
+	}
+	attr := rulesetAttr{handledAccessFS: supported}
+	fd, _, errno := unix.Syscall(sysLandlockCreateRuleset, uintptr(unsafe.Pointer(&attr)), unsafe.Sizeof(attr), 0)
+	if errno != 0 {
+		return nil, os.NewSyscallError("landlock_create_ruleset", errno)
+	}
+	r := &Ruleset{fd: int(fd)}
+	for i, rule := range config.Rules {
+		if err := addPathRule(r.fd, rule.Path, allowed[i]); err != nil {
+			r.Close()
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// RestrictSelf restricts the filesystem access of the calling thread, and of
+// the processes it executes, to the ruleset and closes it. It does nothing if
+// r is nil.
+func (r *Ruleset) RestrictSelf() error {
+	if r == nil {
+		return nil
+	}
+	defer r.Close()
+	if _, _, errno := unix.Syscall(sysLandlockRestrictSelf, uintptr(r.fd), 0, 0); errno != 0 {
+		if errno == unix.EPERM {
+			return fmt.Errorf("landlock_restrict_self: %v (no_new_privs or CAP_SYS_ADMIN is required)", errno)
+		}
+		return os.NewSyscallError("landlock_restrict_self", errno)
+	}
+	return nil
+}
+
+// Close releases the ruleset without enforcing it.
+func (r *Ruleset) Close() error {
+	if r == nil {
+		return nil
+	}
+	return unix.Close(r.fd)
+}
+
+// Apply restricts the filesystem access of the calling thread, and of the
+// processes it executes, to the rules of config.
+func Apply(config *configs.Landlock) error {
+	r, err := NewRuleset(config)
+	if err != nil {
+		return err
+	}
+	return r.RestrictSelf()
+}
+
+// addPathRule grants access beneath path. Access rights which only apply to
+// directories are dropped if path is not a directory, because the kernel
+// rejects them.
+func addPathRule(rulesetFd int, path string, access uint64) error {
+	fd, err := unix.Open(path, unix.O_PATH|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return &os.PathError{Op: "open landlock rule path", Path: path, Err: err}
+	}
+	defer unix.Close(fd)
+	var st unix.Stat_t
+	if err := unix.Fstat(fd, &st); err != nil {
+		return &os.PathError{Op: "stat landlock rule path", Path: path, Err: err}
+	}
+	if st.Mode&unix.S_IFMT != unix.S_IFDIR {
+		access &= fileAccess
+	}
+	// The kernel rejects rules which grant nothing.
+	if access == 0 {
+		return nil
+	}
+	attr := pathBeneathAttr{allowedAccess: access, parentFd: int32(fd)}
+	if _, _, errno := unix.Syscall6(sysLandlockAddRule, uintptr(rulesetFd), rulePathBeneath, uintptr(unsafe.Pointer(&attr)), 0, 0, 0); errno != 0 {
+		return &os.PathError{Op: "landlock_add_rule", Path: path, Err: errno}
+	}
+	return nil
+}
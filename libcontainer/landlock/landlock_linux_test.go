@@ -0,0 +1,99 @@
+// +build linux
+
+package landlock
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+	"golang.org/x/sys/unix"
+)
+
+func TestApply(t *testing.T) {
+	if !IsEnabled() {
+		t.Skip("landlock is not supported")
+	}
+	allowed, err := ioutil.TempDir("", "landlock-allowed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(allowed)
+	denied, err := ioutil.TempDir("", "landlock-denied")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(denied)
+	for _, dir := range []string{allowed, denied} {
+		if err := ioutil.WriteFile(filepath.Join(dir, "file"), []byte("data"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	errs := make(chan [3]error)
+	go func() {
+		// The thread is discarded when the goroutine exits without
+		// unlocking it, so that the ruleset does not leak into the other
+		// tests.
+		runtime.LockOSThread()
+		var e [3]error
+		if e[0] = unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); e[0] == nil {
+			e[0] = Apply(&configs.Landlock{
+				Rules: []*configs.LandlockRule{
+					{Path: allowed, Access: []string{"read_file", "read_dir"}},
+				},
+			})
+		}
+		if e[0] == nil {
+			_, e[1] = ioutil.ReadFile(filepath.Join(allowed, "file"))
+			_, e[2] = ioutil.ReadFile(filepath.Join(denied, "file"))
+		}
+		errs <- e
+	}()
+	e := <-errs
+	if e[0] != nil {
+		t.Fatal(e[0])
+	}
+	if e[1] != nil {
+		t.Fatalf("expected to read inside of the ruleset, got %v", e[1])
+	}
+	if !os.IsPermission(e[2]) {
+		t.Fatalf("expected a permission error reading outside of the ruleset, got %v", e[2])
+	}
+}
+
+func TestApplyMissingPath(t *testing.T) {
+	if !IsEnabled() {
+		t.Skip("landlock is not supported")
+	}
+	err := Apply(&configs.Landlock{
+		Rules: []*configs.LandlockRule{
+			{Path: "/landlock-test-nonexistent", Access: []string{"read_file"}},
+		},
+	})
+	if !os.IsNotExist(err) {
+		t.Fatalf("expected a not exist error, got %v", err)
+	}
+}
+
+func TestNewRulesetMissingPath(t *testing.T) {
+	if !IsEnabled() {
+		t.Skip("landlock is not supported")
+	}
+	// The paths are opened when the ruleset is created, not when it is
+	// enforced.
+	r, err := NewRuleset(&configs.Landlock{
+		Rules: []*configs.LandlockRule{
+			{Path: "/landlock-test-nonexistent", Access: []string{"read_file"}},
+		},
+	})
+	if !os.IsNotExist(err) {
+		t.Fatalf("expected a not exist error, got %v", err)
+	}
+	if r != nil {
+		t.Fatalf("expected no ruleset, got %+v", r)
+	}
+}
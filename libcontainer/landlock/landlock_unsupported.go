@@ -0,0 +1,45 @@
+// +build !linux
+
+package landlock
+
+import (
+	"errors"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+var ErrLandlockNotSupported = errors.New("landlock: config provided but landlock not supported")
+
+// IsEnabled returns false, because it is not supported.
+func IsEnabled() bool {
+	return false
+}
+
+// Ruleset is a Landlock ruleset, which can't be created.
+type Ruleset struct{}
+
+// NewRuleset returns nil because landlock is not supported.
+func NewRuleset(config *configs.Landlock) (*Ruleset, error) {
+	if config != nil {
+		return nil, ErrLandlockNotSupported
+	}
+	return nil, nil
+}
+
+// RestrictSelf does nothing because landlock is not supported.
+func (r *Ruleset) RestrictSelf() error {
+	return nil
+}
+
+// Close does nothing because landlock is not supported.
+func (r *Ruleset) Close() error {
+	return nil
+}
+
+// Apply does nothing because landlock is not supported.
+func Apply(config *configs.Landlock) error {
+	if config != nil {
+		return ErrLandlockNotSupported
+	}
+	return nil
+}
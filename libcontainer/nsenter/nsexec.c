@@ -93,6 +93,10 @@ struct nlconfig_t {
 	size_t uidmappath_len;
 	char *gidmappath;
 	size_t gidmappath_len;
+
+	/* Time namespace settings. */
+	char *timensoffset;
+	size_t timensoffset_len;
 };
 
 /*
@@ -109,6 +113,7 @@ struct nlconfig_t {
 #define ROOTLESS_EUID_ATTR	27287
 #define UIDMAPPATH_ATTR	    27288
 #define GIDMAPPATH_ATTR	    27289
+#define TIMENSOFFSET_ATTR	27290
 
 /*
  * Use the raw syscall for versions of glibc which don't include a function for
@@ -303,6 +308,19 @@ static void update_gidmap(const char *path, int pid, char *map, size_t map_len)
 	}
 }
 
+/*
+ * The offsets of a new time namespace can only be set before any process has
+ * entered it, which happens when we fork the init process.
+ */
+static void update_timens_offsets(char *data, size_t len)
+{
+	if (data == NULL || len <= 0)
+		return;
+
+	if (write_file(data, len, "/proc/self/timens_offsets") < 0)
+		bail("failed to update /proc/self/timens_offsets");
+}
+
 static void update_oom_score_adj(char *data, size_t len)
 {
 	if (data == NULL || len <= 0)
@@ -369,6 +387,8 @@ static int nsflag(char *name)
 		return CLONE_NEWUSER;
 	else if (!strcmp(name, "uts"))
 		return CLONE_NEWUTS;
+	else if (!strcmp(name, "time"))
+		return CLONE_NEWTIME;
 
 	/* If we don't recognise a name, fallback to 0. */
 	return 0;
@@ -455,6 +475,10 @@ static void nl_parse(int fd, struct nlconfig_t *config)
 		case SETGROUP_ATTR:
 			config->is_setgroup = readint8(current);
 			break;
+		case TIMENSOFFSET_ATTR:
+			config->timensoffset = current;
+			config->timensoffset_len = payload_len;
+			break;
 		default:
 			bail("unknown netlink message type %d", nlattr->nla_type);
 		}
@@ -875,6 +899,10 @@ void nsexec(void)
 			if (unshare(config.cloneflags & ~CLONE_NEWCGROUP) < 0)
 				bail("failed to unshare namespaces");
 
+			/* The new time namespace only applies to our children. */
+			if (config.cloneflags & CLONE_NEWTIME)
+				update_timens_offsets(config.timensoffset, config.timensoffset_len);
+
 			/*
 			 * TODO: What about non-namespace clone flags that we're dropping here?
 			 *
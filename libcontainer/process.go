@@ -69,6 +69,22 @@ type Process struct {
 	// If Rlimits are not set, the container will inherit rlimits from the parent process
 	Rlimits []configs.Rlimit
 
+	// Landlock restricts the filesystem access of the process. If it is unset
+	// the ruleset of the container's config is used.
+	Landlock *configs.Landlock
+
+	// Scheduler sets the scheduling policy of the process. If it is unset the
+	// policy of the container's config is used.
+	Scheduler *configs.Scheduler
+
+	// IOPriority sets the I/O priority of the process. If it is unset the
+	// priority of the container's config is used.
+	IOPriority *configs.IOPriority
+
+	// CPUAffinity is the initial list of CPUs the process may run on, e.g.
+	// "0-3,7". If it is empty the affinity of the container's config is used.
+	CPUAffinity string
+
 	// ConsoleSocket provides the masterfd console.
 	ConsoleSocket *os.File
 
@@ -113,8 +113,12 @@ func (p *setnsProcess) start() (err error) {
 		return newSystemErrorWithCause(err, "writing config to pipe")
 	}
 
+	var helperDone bool
 	ierr := parseSync(p.parentPipe, func(sync *syncT) error {
 		switch sync.Type {
+		case procHelperDone:
+			helperDone = true
+			return nil
 		case procReady:
 			// This shouldn't happen.
 			panic("unexpected procReady in setns")
@@ -134,6 +138,9 @@ func (p *setnsProcess) start() (err error) {
 		p.wait()
 		return ierr
 	}
+	if p.config.Hotplug != nil && !helperDone {
+		return newSystemError(fmt.Errorf("hotplug helper exited without finishing"))
+	}
 	return nil
 }
 
@@ -315,6 +322,9 @@ func (p *initProcess) start() error {
 			return newSystemErrorWithCause(err, "applying Intel RDT configuration for process")
 		}
 	}
+	if err := p.container.recordResources(); err != nil {
+		return newSystemErrorWithCause(err, "recording container resources")
+	}
 	// Now it's time to setup cgroup namesapce
 	if p.config.Config.Namespaces.Contains(configs.NEWCGROUP) && p.config.Config.Namespaces.PathOf(configs.NEWCGROUP) == "" {
 		if _, err := p.parentPipe.Write([]byte{createCgroupns}); err != nil {
@@ -373,7 +383,7 @@ func (p *initProcess) start() error {
 					s.Pid = p.cmd.Process.Pid
 					s.Status = "creating"
 					for i, hook := range p.config.Config.Hooks.Prestart {
-						if err := hook.Run(s); err != nil {
+						if err := p.container.runHook(hook, s); err != nil {
 							return newSystemErrorWithCausef(err, "running prestart hook %d", i)
 						}
 					}
@@ -403,7 +413,7 @@ func (p *initProcess) start() error {
 				s.Pid = p.cmd.Process.Pid
 				s.Status = "creating"
 				for i, hook := range p.config.Config.Hooks.Prestart {
-					if err := hook.Run(s); err != nil {
+					if err := p.container.runHook(hook, s); err != nil {
 						return newSystemErrorWithCausef(err, "running prestart hook %d", i)
 					}
 				}
@@ -413,6 +423,10 @@ func (p *initProcess) start() error {
 				return newSystemErrorWithCause(err, "writing syncT 'resume'")
 			}
 			sentResume = true
+		case procIdmapMounts:
+			if err := p.sendIdmapMounts(); err != nil {
+				return newSystemErrorWithCause(err, "sending idmapped mounts to init process")
+			}
 		default:
 			return newSystemError(fmt.Errorf("invalid JSON payload from child"))
 		}
@@ -473,6 +487,27 @@ func (p *initProcess) sendConfig() error {
 	return utils.WriteJSON(p.parentPipe, p.config)
 }
 
+// sendIdmapMounts creates the idmapped mounts requested in the config, mapped
+// into the user namespace of the init process, and sends them to it.
+func (p *initProcess) sendIdmapMounts() error {
+	userns := fmt.Sprintf("/proc/%d/ns/user", p.pid())
+	for _, m := range p.config.Config.Mounts {
+		if m.Extensions&configs.EXT_IDMAP != configs.EXT_IDMAP {
+			continue
+		}
+		f, err := openIdmappedMount(m, p.config.Config.MountLabel, userns)
+		if err != nil {
+			return err
+		}
+		err = utils.SendFd(p.parentPipe, f.Name(), f.Fd())
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (p *initProcess) createNetworkInterfaces() error {
 	for _, config := range p.config.Config.Networks {
 		strategy, err := getStrategy(config.Type)
@@ -4,12 +4,12 @@ package libcontainer
 
 import (
 	"fmt"
-	"io"
 	"io/ioutil"
 	"os"
 	"os/exec"
 	"path"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
@@ -22,6 +22,7 @@ import (
 	libcontainerUtils "github.com/opencontainers/runc/libcontainer/utils"
 	"github.com/opencontainers/selinux/go-selinux/label"
 
+	"github.com/sirupsen/logrus"
 	"golang.org/x/sys/unix"
 )
 
@@ -40,12 +41,22 @@ func needsSetupDev(config *configs.Config) bool {
 // prepareRootfs sets up the devices, mount points, and filesystems for use
 // inside a new mount namespace. It doesn't set anything as ro. You must call
 // finalizeRootfs after this function to finish setting up the rootfs.
-func prepareRootfs(pipe io.ReadWriter, iConfig *initConfig) (err error) {
+func prepareRootfs(pipe *os.File, iConfig *initConfig) (err error) {
 	config := iConfig.Config
 	if err := prepareRoot(config); err != nil {
 		return newSystemErrorWithCause(err, "preparing rootfs")
 	}
 
+	idmapFds, err := syncParentIdmapMounts(pipe, config)
+	if err != nil {
+		return newSystemErrorWithCause(err, "getting idmapped mounts from parent")
+	}
+	defer func() {
+		for _, f := range idmapFds {
+			f.Close()
+		}
+	}()
+
 	hasCgroupns := config.Namespaces.Contains(configs.NEWCGROUP)
 	setupDev := needsSetupDev(config)
 	for _, m := range config.Mounts {
@@ -54,7 +65,12 @@ func prepareRootfs(pipe io.ReadWriter, iConfig *initConfig) (err error) {
 				return newSystemErrorWithCause(err, "running premount command")
 			}
 		}
-		if err := mountToRootfs(m, config.Rootfs, config.MountLabel, hasCgroupns); err != nil {
+		if f, ok := idmapFds[m]; ok {
+			err = attachMountToRootfs(m, config.Rootfs, f)
+		} else {
+			err = mountToRootfs(m, config.Rootfs, config.MountLabel, hasCgroupns)
+		}
+		if err != nil {
 			return newSystemErrorWithCausef(err, "mounting %q to rootfs %q at %q", m.Source, config.Rootfs, m.Destination)
 		}
 
@@ -392,6 +408,153 @@ func mountToRootfs(m *configs.Mount, rootfs, mountLabel string, enableCgroupns b
 	return nil
 }
 
+// attachMountToRootfs attaches the detached mount f, which was created by
+// the parent from m.Source, at m.Destination in the rootfs.
+func attachMountToRootfs(m *configs.Mount, rootfs string, f *os.File) error {
+	stat, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	dest, err := securejoin.SecureJoin(rootfs, m.Destination)
+	if err != nil {
+		return err
+	}
+	if err := checkMountDestination(rootfs, dest); err != nil {
+		return err
+	}
+	// update the mount with the correct dest after symlinks are resolved.
+	m.Destination = dest
+	if err := createIfNotExists(dest, stat.IsDir()); err != nil {
+		return err
+	}
+	if err := system.MoveMount(int(f.Fd()), "", unix.AT_FDCWD, dest, system.MOVE_MOUNT_F_EMPTY_PATH); err != nil {
+		return fmt.Errorf("move_mount %s: %v", dest, err)
+	}
+	if m.Flags&^(unix.MS_REC|unix.MS_REMOUNT|unix.MS_BIND) != 0 {
+		if err := remount(m, rootfs); err != nil {
+			return err
+		}
+	}
+	for _, pflag := range m.PropagationFlags {
+		if err := unix.Mount("", dest, "", uintptr(pflag), ""); err != nil {
+			return err
+		}
+	}
+	if m.RecursiveFlags != 0 {
+		if err := setRecursiveFlags(dest, m.RecursiveFlags); err != nil {
+			return fmt.Errorf("setting recursive mount flags on %s: %v", dest, err)
+		}
+	}
+	return nil
+}
+
+// unmountFromRootfs detaches the mount at dest in the rootfs, resolving dest
+// the same way attachMountToRootfs does.
+func unmountFromRootfs(dest, rootfs string) error {
+	dest, err := securejoin.SecureJoin(rootfs, dest)
+	if err != nil {
+		return err
+	}
+	if err := checkMountDestination(rootfs, dest); err != nil {
+		return err
+	}
+	if err := unix.Unmount(dest, unix.MNT_DETACH); err != nil {
+		return fmt.Errorf("unmount %s: %v", dest, err)
+	}
+	return nil
+}
+
+// openBindMount creates a detached copy of the mount at source, including
+// its submounts if recursive is set, which can be attached in another mount
+// namespace with move_mount(2).
+func openBindMount(source string, recursive bool) (*os.File, error) {
+	flags := uint(system.OPEN_TREE_CLONE | system.OPEN_TREE_CLOEXEC)
+	if recursive {
+		flags |= system.AT_RECURSIVE
+	}
+	fd, err := system.OpenTree(unix.AT_FDCWD, source, flags)
+	if err != nil {
+		return nil, fmt.Errorf("open_tree %s: %v", source, err)
+	}
+	return os.NewFile(uintptr(fd), source), nil
+}
+
+// openIdmappedMount creates a detached bind mount of the source of m, with
+// its submounts if m is recursive, whose ownership is mapped into the user
+// namespace at usernsPath. The source is relabeled first, as the container
+// cannot reach it. It has to be called with privileges over the filesystem of
+// the source, so it is done by the parent.
+func openIdmappedMount(m *configs.Mount, mountLabel, usernsPath string) (*os.File, error) {
+	if m.Relabel != "" {
+		if err := label.Validate(m.Relabel); err != nil {
+			return nil, err
+		}
+		if err := label.Relabel(m.Source, mountLabel, label.IsShared(m.Relabel)); err != nil {
+			return nil, err
+		}
+	}
+	userns, err := os.Open(usernsPath)
+	if err != nil {
+		return nil, err
+	}
+	defer userns.Close()
+
+	treeFlags := uint(system.OPEN_TREE_CLONE | system.OPEN_TREE_CLOEXEC)
+	attrFlags := uint(system.AT_EMPTY_PATH)
+	if m.Flags&unix.MS_REC == unix.MS_REC {
+		treeFlags |= system.AT_RECURSIVE
+		attrFlags |= system.AT_RECURSIVE
+	}
+	fd, err := system.OpenTree(unix.AT_FDCWD, m.Source, treeFlags)
+	if err != nil {
+		return nil, fmt.Errorf("open_tree %s: %v", m.Source, err)
+	}
+	attr := &system.MountAttr{
+		AttrSet:  system.MOUNT_ATTR_IDMAP,
+		UsernsFd: uint64(userns.Fd()),
+	}
+	if err := system.MountSetattr(fd, "", attrFlags, attr); err != nil {
+		unix.Close(fd)
+		return nil, fmt.Errorf("setting idmap on %s: %v", m.Source, err)
+	}
+	return os.NewFile(uintptr(fd), m.Source), nil
+}
+
+// chownMountSources recursively changes the owner of the sources of the
+// mounts with the EXT_CHOWN extension to the container's root user. This is
+// done by the parent, as the container's root user usually cannot change the
+// owner of files which are not mapped into its user namespace.
+func chownMountSources(config *configs.Config) error {
+	var (
+		uid, gid int
+		resolved bool
+	)
+	for _, m := range config.Mounts {
+		if m.Extensions&configs.EXT_CHOWN != configs.EXT_CHOWN {
+			continue
+		}
+		if !resolved {
+			var err error
+			if uid, err = config.HostRootUID(); err != nil {
+				return err
+			}
+			if gid, err = config.HostRootGID(); err != nil {
+				return err
+			}
+			resolved = true
+		}
+		if err := filepath.Walk(m.Source, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			return os.Lchown(path, uid, gid)
+		}); err != nil {
+			return fmt.Errorf("changing owner of %q: %v", m.Source, err)
+		}
+	}
+	return nil
+}
+
 func getCgroupMounts(m *configs.Mount) ([]*configs.Mount, error) {
 	mounts, err := cgroups.GetCgroupMounts(false)
 	if err != nil {
@@ -545,6 +708,41 @@ func bindMountDeviceNode(dest string, node *configs.Device) error {
 	return unix.Mount(node.Path, dest, "bind", unix.MS_BIND, "")
 }
 
+// attachDeviceNode attaches the detached bind mount f of the host's device
+// node at node.Path in the rootfs.
+func attachDeviceNode(rootfs string, node *configs.Device, f *os.File) error {
+	dest, err := securejoin.SecureJoin(rootfs, node.Path)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+	if err := createIfNotExists(dest, false); err != nil {
+		return err
+	}
+	if err := system.MoveMount(int(f.Fd()), "", unix.AT_FDCWD, dest, system.MOVE_MOUNT_F_EMPTY_PATH); err != nil {
+		return fmt.Errorf("move_mount %s: %v", dest, err)
+	}
+	return nil
+}
+
+// removeDeviceNode removes the device node at path in the rootfs, detaching
+// it first if it was bind mounted from the host.
+func removeDeviceNode(rootfs, path string) error {
+	dest, err := securejoin.SecureJoin(rootfs, path)
+	if err != nil {
+		return err
+	}
+	if err := unix.Unmount(dest, unix.MNT_DETACH); err != nil && err != unix.EINVAL && err != unix.ENOENT {
+		return fmt.Errorf("unmount %s: %v", dest, err)
+	}
+	if err := os.Remove(dest); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
 // Creates the device node in the rootfs of the container.
 func createDeviceNode(rootfs string, node *configs.Device, bind bool) error {
 	dest := filepath.Join(rootfs, node.Path)
@@ -670,9 +868,58 @@ func prepareRoot(config *configs.Config) error {
 		return err
 	}
 
+	if config.RootfsOverlay != nil {
+		return mountRootfsOverlay(config)
+	}
 	return unix.Mount(config.Rootfs, config.Rootfs, "bind", unix.MS_BIND|unix.MS_REC, "")
 }
 
+// mountRootfsOverlay assembles the overlay described by config.RootfsOverlay
+// on top of config.Rootfs. If the kernel refuses to mount the overlay (which
+// is the case for unprivileged user namespaces on older kernels) we fall back
+// to fuse-overlayfs, if it is available.
+func mountRootfsOverlay(config *configs.Config) error {
+	data := config.RootfsOverlay.MountData()
+	err := unix.Mount("overlay", config.Rootfs, "overlay", 0, label.FormatMountLabel(data, config.MountLabel))
+	if err == nil {
+		return nil
+	}
+	// Only fall back to fuse-overlayfs when the kernel does not allow this
+	// user to mount an overlay, other errors are problems with the layers.
+	if err != unix.EPERM && err != unix.ENODEV {
+		return fmt.Errorf("mounting overlay rootfs: %v", err)
+	}
+	fuseOverlay, lerr := exec.LookPath("fuse-overlayfs")
+	if lerr != nil {
+		return fmt.Errorf("mounting overlay rootfs: %v", err)
+	}
+	logrus.Debugf("mounting overlay rootfs failed (%v), falling back to %s", err, fuseOverlay)
+	if out, err := exec.Command(fuseOverlay, "-o", data, config.Rootfs).CombinedOutput(); err != nil {
+		return fmt.Errorf("mounting overlay rootfs with %s: %s: %v", fuseOverlay, strings.TrimSpace(string(out)), err)
+	}
+	return nil
+}
+
+// cleanupRootfsOverlay tears down whatever is left of the overlay rootfs once
+// the container has been destroyed. The overlay itself only lives in the
+// container's mount namespace, but it may still be visible on the host if the
+// container did not have one, and the kernel leaves its own work directory
+// behind in the overlay work directory.
+func cleanupRootfsOverlay(config *configs.Config) error {
+	if config.RootfsOverlay == nil {
+		return nil
+	}
+	if !config.Namespaces.Contains(configs.NEWNS) {
+		if err := unix.Unmount(config.Rootfs, unix.MNT_DETACH); err != nil && err != unix.EINVAL && err != unix.ENOENT {
+			return err
+		}
+	}
+	if workDir := config.RootfsOverlay.WorkDir; workDir != "" {
+		return os.RemoveAll(filepath.Join(workDir, "work"))
+	}
+	return nil
+}
+
 func setReadonly() error {
 	return unix.Mount("/", "/", "bind", unix.MS_BIND|unix.MS_REMOUNT|unix.MS_RDONLY|unix.MS_REC, "")
 }
@@ -878,9 +1125,85 @@ func mountPropagate(m *configs.Mount, rootfs string, mountLabel string) error {
 			return err
 		}
 	}
+	if m.RecursiveFlags != 0 {
+		if err := setRecursiveFlags(dest, m.RecursiveFlags); err != nil {
+			return fmt.Errorf("setting recursive mount flags on %s: %v", dest, err)
+		}
+	}
+	return nil
+}
+
+// setRecursiveFlags applies flags (any of MS_RDONLY, MS_NOSUID, MS_NODEV and
+// MS_NOEXEC) to the mount at dest and all of its submounts. It uses
+// mount_setattr(2) if the kernel supports it, otherwise it remounts each
+// submount found in the mount table.
+func setRecursiveFlags(dest string, flags int) error {
+	attr := &system.MountAttr{}
+	for flag, mattr := range map[int]uint64{
+		unix.MS_RDONLY: system.MOUNT_ATTR_RDONLY,
+		unix.MS_NOSUID: system.MOUNT_ATTR_NOSUID,
+		unix.MS_NODEV:  system.MOUNT_ATTR_NODEV,
+		unix.MS_NOEXEC: system.MOUNT_ATTR_NOEXEC,
+	} {
+		if flags&flag == flag {
+			attr.AttrSet |= mattr
+		}
+	}
+	err := system.MountSetattr(unix.AT_FDCWD, dest, system.AT_RECURSIVE, attr)
+	if err != unix.ENOSYS {
+		return err
+	}
+
+	mounts, err := mount.GetMounts()
+	if err != nil {
+		return err
+	}
+	// Remount parents before their submounts, mount.GetMounts doesn't
+	// guarantee any ordering.
+	sort.Slice(mounts, func(i, j int) bool {
+		return len(mounts[i].Mountpoint) < len(mounts[j].Mountpoint)
+	})
+	for _, mi := range mounts {
+		if mi.Mountpoint != dest && !strings.HasPrefix(mi.Mountpoint, dest+"/") {
+			continue
+		}
+		// Keep the flags the submount already has, as a bind remount in a
+		// user namespace is not allowed to clear locked flags.
+		mflags := mountOptsToFlags(mi.Opts) | flags | unix.MS_BIND | unix.MS_REMOUNT
+		if err := unix.Mount("", mi.Mountpoint, "", uintptr(mflags), ""); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// mountOptsToFlags converts the per mount options of a mountinfo entry into
+// the equivalent mount flags.
+func mountOptsToFlags(opts string) int {
+	var flags int
+	for _, opt := range strings.Split(opts, ",") {
+		switch opt {
+		case "ro":
+			flags |= unix.MS_RDONLY
+		case "nosuid":
+			flags |= unix.MS_NOSUID
+		case "nodev":
+			flags |= unix.MS_NODEV
+		case "noexec":
+			flags |= unix.MS_NOEXEC
+		case "noatime":
+			flags |= unix.MS_NOATIME
+		case "nodiratime":
+			flags |= unix.MS_NODIRATIME
+		case "relatime":
+			flags |= unix.MS_RELATIME
+		case "strictatime":
+			flags |= unix.MS_STRICTATIME
+		}
+	}
+	return flags
+}
+
 func mountNewCgroup(m *configs.Mount) error {
 	var (
 		data   = m.Data
@@ -6,6 +6,8 @@ import (
 	"testing"
 
 	"github.com/opencontainers/runc/libcontainer/configs"
+
+	"golang.org/x/sys/unix"
 )
 
 func TestCheckMountDestOnProc(t *testing.T) {
@@ -99,3 +101,14 @@ func TestNeedsSetupDevStrangeSourceDest(t *testing.T) {
 		t.Fatal("expected needsSetupDev to be true, got false")
 	}
 }
+
+func TestMountOptsToFlags(t *testing.T) {
+	flags := mountOptsToFlags("ro,nosuid,nodev,relatime")
+	expected := unix.MS_RDONLY | unix.MS_NOSUID | unix.MS_NODEV | unix.MS_RELATIME
+	if flags != expected {
+		t.Fatalf("expected flags %#x, got %#x", expected, flags)
+	}
+	if flags := mountOptsToFlags("rw,noexec"); flags != unix.MS_NOEXEC {
+		t.Fatalf("expected flags %#x, got %#x", unix.MS_NOEXEC, flags)
+	}
+}
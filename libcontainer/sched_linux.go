@@ -0,0 +1,120 @@
+// +build linux
+
+package libcontainer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runc/libcontainer/system"
+)
+
+var schedPolicies = map[string]uint32{
+	"SCHED_OTHER":    system.SCHED_OTHER,
+	"SCHED_FIFO":     system.SCHED_FIFO,
+	"SCHED_RR":       system.SCHED_RR,
+	"SCHED_BATCH":    system.SCHED_BATCH,
+	"SCHED_IDLE":     system.SCHED_IDLE,
+	"SCHED_DEADLINE": system.SCHED_DEADLINE,
+}
+
+var schedFlags = map[string]uint64{
+	"SCHED_FLAG_RESET_ON_FORK": system.SCHED_FLAG_RESET_ON_FORK,
+	"SCHED_FLAG_RECLAIM":       system.SCHED_FLAG_RECLAIM,
+	"SCHED_FLAG_DL_OVERRUN":    system.SCHED_FLAG_DL_OVERRUN,
+	"SCHED_FLAG_KEEP_POLICY":   system.SCHED_FLAG_KEEP_POLICY,
+	"SCHED_FLAG_KEEP_PARAMS":   system.SCHED_FLAG_KEEP_PARAMS,
+}
+
+var ioprioClasses = map[string]int{
+	"IOPRIO_CLASS_RT":   system.IOPRIO_CLASS_RT,
+	"IOPRIO_CLASS_BE":   system.IOPRIO_CLASS_BE,
+	"IOPRIO_CLASS_IDLE": system.IOPRIO_CLASS_IDLE,
+}
+
+// setupScheduling sets the CPU affinity, I/O priority and scheduling policy
+// of the calling thread, which are inherited by the process it executes.
+// The affinity is set first, the policy may keep the thread from changing it.
+func setupScheduling(config *initConfig) error {
+	if config.CPUAffinity != "" {
+		mask, err := cpuAffinityMask(config.CPUAffinity)
+		if err != nil {
+			return err
+		}
+		if err := system.SchedSetaffinity(0, mask); err != nil {
+			return newSystemErrorWithCause(err, "setting cpu affinity")
+		}
+	}
+	if p := config.IOPriority; p != nil {
+		class, ok := ioprioClasses[p.Class]
+		if !ok {
+			return fmt.Errorf("unknown io priority class %q", p.Class)
+		}
+		if err := system.IoprioSet(0, class, p.Priority); err != nil {
+			return newSystemErrorWithCause(err, "setting io priority")
+		}
+	}
+	if config.Scheduler != nil {
+		attr, err := schedAttr(config.Scheduler)
+		if err != nil {
+			return err
+		}
+		if err := system.SchedSetattr(0, attr); err != nil {
+			return newSystemErrorWithCause(err, "setting scheduling policy")
+		}
+	}
+	return nil
+}
+
+// schedAttr converts s to the attributes of sched_setattr(2).
+func schedAttr(s *configs.Scheduler) (*system.SchedAttr, error) {
+	policy, ok := schedPolicies[s.Policy]
+	if !ok {
+		return nil, fmt.Errorf("unknown scheduling policy %q", s.Policy)
+	}
+	attr := &system.SchedAttr{
+		Policy:   policy,
+		Nice:     s.Nice,
+		Priority: uint32(s.Priority),
+		Runtime:  s.Runtime,
+		Deadline: s.Deadline,
+		Period:   s.Period,
+	}
+	for _, f := range s.Flags {
+		flag, ok := schedFlags[f]
+		if !ok {
+			return nil, fmt.Errorf("unknown scheduling flag %q", f)
+		}
+		attr.Flags |= flag
+	}
+	return attr, nil
+}
+
+// cpuAffinityMask converts a list of CPUs in the format of cpuset.cpus, e.g.
+// "0-3,7", to the mask of sched_setaffinity(2).
+func cpuAffinityMask(cpus string) ([]uint64, error) {
+	var mask []uint64
+	for _, r := range strings.Split(cpus, ",") {
+		bounds := strings.SplitN(strings.TrimSpace(r), "-", 2)
+		start, err := strconv.ParseUint(bounds[0], 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cpu affinity %q", cpus)
+		}
+		end := start
+		if len(bounds) == 2 {
+			end, err = strconv.ParseUint(bounds[1], 10, 16)
+			if err != nil || end < start {
+				return nil, fmt.Errorf("invalid cpu affinity %q", cpus)
+			}
+		}
+		for cpu := start; cpu <= end; cpu++ {
+			for uint64(len(mask)) <= cpu/64 {
+				mask = append(mask, 0)
+			}
+			mask[cpu/64] |= 1 << (cpu % 64)
+		}
+	}
+	return mask, nil
+}
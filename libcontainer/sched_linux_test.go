@@ -0,0 +1,76 @@
+// +build linux
+
+package libcontainer
+
+import (
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runc/libcontainer/system"
+)
+
+func TestCPUAffinityMask(t *testing.T) {
+	for cpus, expected := range map[string][]uint64{
+		"0":        {0x1},
+		"0-3,7":    {0x8f},
+		"1, 64-65": {0x2, 0x3},
+	} {
+		mask, err := cpuAffinityMask(cpus)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(mask, expected) {
+			t.Errorf("expected mask %#x for %q, got %#x", expected, cpus, mask)
+		}
+	}
+	for _, cpus := range []string{"", "a", "3-1", "0,", "-1"} {
+		if _, err := cpuAffinityMask(cpus); err == nil {
+			t.Errorf("expected an error for %q", cpus)
+		}
+	}
+}
+
+func TestSchedAttr(t *testing.T) {
+	attr, err := schedAttr(&configs.Scheduler{
+		Policy:   "SCHED_DEADLINE",
+		Flags:    []string{"SCHED_FLAG_RESET_ON_FORK", "SCHED_FLAG_RECLAIM"},
+		Runtime:  1000,
+		Deadline: 2000,
+		Period:   3000,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &system.SchedAttr{
+		Policy:   system.SCHED_DEADLINE,
+		Flags:    system.SCHED_FLAG_RESET_ON_FORK | system.SCHED_FLAG_RECLAIM,
+		Runtime:  1000,
+		Deadline: 2000,
+		Period:   3000,
+	}
+	if !reflect.DeepEqual(attr, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, attr)
+	}
+	if _, err := schedAttr(&configs.Scheduler{Policy: "SCHED_UNKNOWN"}); err == nil {
+		t.Fatal("expected an error for an unknown policy")
+	}
+}
+
+func TestSetupScheduling(t *testing.T) {
+	errCh := make(chan error)
+	go func() {
+		// The thread is discarded when the goroutine exits without
+		// unlocking it, so that the idle policy does not leak into the
+		// other tests.
+		runtime.LockOSThread()
+		errCh <- setupScheduling(&initConfig{
+			Scheduler:  &configs.Scheduler{Policy: "SCHED_IDLE"},
+			IOPriority: &configs.IOPriority{Class: "IOPRIO_CLASS_IDLE"},
+		})
+	}()
+	if err := <-errCh; err != nil {
+		t.Fatal(err)
+	}
+}
@@ -99,6 +99,13 @@ func IsEnabled() bool {
 	return ok
 }
 
+// IsSyscallKnown returns if the syscall is known on the native architecture.
+// Rules for unknown syscalls are ignored.
+func IsSyscallKnown(name string) bool {
+	_, err := libseccomp.GetSyscallFromName(name)
+	return err == nil
+}
+
 // Convert Libcontainer Action to Libseccomp ScmpAction
 func getAction(act configs.Action) (libseccomp.ScmpAction, error) {
 	switch act {
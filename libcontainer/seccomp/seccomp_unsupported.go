@@ -22,3 +22,9 @@ func InitSeccomp(config *configs.Seccomp) error {
 func IsEnabled() bool {
 	return false
 }
+
+// IsSyscallKnown returns false, because syscalls can't be resolved without
+// seccomp support.
+func IsSyscallKnown(name string) bool {
+	return false
+}
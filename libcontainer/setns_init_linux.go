@@ -9,6 +9,7 @@ import (
 
 	"github.com/opencontainers/runc/libcontainer/apparmor"
 	"github.com/opencontainers/runc/libcontainer/keys"
+	"github.com/opencontainers/runc/libcontainer/landlock"
 	"github.com/opencontainers/runc/libcontainer/seccomp"
 	"github.com/opencontainers/runc/libcontainer/system"
 	"github.com/opencontainers/selinux/go-selinux/label"
@@ -62,6 +63,16 @@ func (l *linuxSetnsInit) Init() error {
 		return err
 	}
 	defer label.SetProcessLabel("")
+	if p := l.config.Config.Personality; p != nil {
+		if err := system.SetLinuxPersonality(p.Domain); err != nil {
+			return newSystemErrorWithCause(err, "set personality")
+		}
+	}
+	// Setting a real-time policy or raising the priority requires
+	// CAP_SYS_NICE, so do this before seccomp and dropping capabilities.
+	if err := setupScheduling(l.config); err != nil {
+		return err
+	}
 	// Without NoNewPrivileges seccomp is a privileged operation, so we need to
 	// do this before dropping capabilities; otherwise do it as late as possible
 	// just before execve so as few syscalls take place after it as possible.
@@ -76,6 +87,9 @@ func (l *linuxSetnsInit) Init() error {
 	if err := apparmor.ApplyProfile(l.config.AppArmorProfile); err != nil {
 		return err
 	}
+	if err := landlock.Apply(l.config.Landlock); err != nil {
+		return newSystemErrorWithCause(err, "apply landlock ruleset")
+	}
 	// Set seccomp as close to execve as possible, so as few syscalls take
 	// place afterward (reducing the amount of syscalls that users need to
 	// enable in their seccomp profiles).
@@ -5,12 +5,19 @@
 package specconv
 
 import (
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
+	systemdDbus "github.com/coreos/go-systemd/dbus"
+	"github.com/godbus/dbus"
+	"github.com/opencontainers/runc/libcontainer/apparmor"
 	"github.com/opencontainers/runc/libcontainer/configs"
 	"github.com/opencontainers/runc/libcontainer/seccomp"
 	libcontainerUtils "github.com/opencontainers/runc/libcontainer/utils"
@@ -21,6 +28,84 @@ import (
 
 const wildcard = -1
 
+// Annotations which let a bundle reference image layers directly instead of a
+// pre-assembled rootfs. The layers are assembled into an overlay which is
+// mounted on root.path. Relative paths are resolved against the bundle.
+const (
+	// AnnotationRootfsOverlayLowerDir is a ':'-separated list of read-only
+	// layers, ordered from the top-most one to the bottom-most one.
+	AnnotationRootfsOverlayLowerDir = "org.opencontainers.runc.rootfs.overlay.lowerdir"
+	// AnnotationRootfsOverlayUpperDir is the writable layer of the overlay.
+	AnnotationRootfsOverlayUpperDir = "org.opencontainers.runc.rootfs.overlay.upperdir"
+	// AnnotationRootfsOverlayWorkDir is the overlay work directory.
+	AnnotationRootfsOverlayWorkDir = "org.opencontainers.runc.rootfs.overlay.workdir"
+)
+
+// Annotations which set the memory controls of the unified cgroup hierarchy
+// that have no field in the runtime spec. The memory ones take a value in
+// bytes or "max", the OOM group one takes a boolean.
+const (
+	// AnnotationMemoryHigh is the throttle limit of memory usage.
+	AnnotationMemoryHigh = "org.opencontainers.runc.cgroup.memory.high"
+	// AnnotationMemoryLow is the best-effort memory protection.
+	AnnotationMemoryLow = "org.opencontainers.runc.cgroup.memory.low"
+	// AnnotationMemoryMin is the hard memory protection.
+	AnnotationMemoryMin = "org.opencontainers.runc.cgroup.memory.min"
+	// AnnotationMemoryOomGroup makes the OOM killer kill the whole container.
+	AnnotationMemoryOomGroup = "org.opencontainers.runc.cgroup.memory.oom.group"
+)
+
+// AnnotationTimeOffsets sets the clock offsets of the container's time
+// namespace, in the format of the "timeOffsets" field of newer runtime specs,
+// e.g. {"monotonic": {"secs": 86400, "nanosecs": 0}}.
+const AnnotationTimeOffsets = "org.opencontainers.runc.timeOffsets"
+
+// AppArmorRuntimeDefault is the name of the AppArmor profile which requests a
+// profile generated from the default template for the container.
+const AppArmorRuntimeDefault = "runtime/default"
+
+// Annotations which provide the AppArmor profile named by the
+// "process.apparmorProfile" field, which is loaded when the container is
+// created if it is not loaded yet. The profile must define exactly that
+// profile, and is unloaded when the last container using it is destroyed.
+const (
+	// AnnotationAppArmorProfile is the inline content of the profile.
+	AnnotationAppArmorProfile = "org.opencontainers.runc.apparmor.profile"
+	// AnnotationAppArmorProfilePath is the path of the profile, relative to
+	// the bundle.
+	AnnotationAppArmorProfilePath = "org.opencontainers.runc.apparmor.profilePath"
+)
+
+// AnnotationPersonality sets the execution domain of the container, in the
+// format of the "linux.personality" field of newer runtime specs, e.g.
+// {"domain": "LINUX32"}.
+const AnnotationPersonality = "org.opencontainers.runc.personality"
+
+// AnnotationLandlock sets the Landlock ruleset of the container, e.g.
+// {"rules": [{"path": "/usr", "access": ["execute", "read_file", "read_dir"]}]}.
+// See configs.Landlock for the format. It requires process.noNewPrivileges.
+const AnnotationLandlock = "org.opencontainers.runc.landlock"
+
+// Annotations which set the scheduling of the container's init process, in
+// the format of the corresponding process fields of newer runtime specs.
+const (
+	// AnnotationScheduler is the scheduling policy, e.g.
+	// {"policy": "SCHED_BATCH", "nice": 5}.
+	AnnotationScheduler = "org.opencontainers.runc.process.scheduler"
+	// AnnotationIOPriority is the I/O priority, e.g.
+	// {"class": "IOPRIO_CLASS_BE", "priority": 4}.
+	AnnotationIOPriority = "org.opencontainers.runc.process.ioPriority"
+	// AnnotationCPUAffinity is the initial list of CPUs, e.g. "0-3,7".
+	AnnotationCPUAffinity = "org.opencontainers.runc.process.cpuAffinity"
+)
+
+// annotationSystemdPropertyPrefix is the prefix of annotations which set
+// properties of the container's systemd unit, e.g.
+// "org.systemd.property.TimeoutStopUSec": "uint64 123456789". The value is
+// in the GVariant text format. A property ending in "Sec" is converted to the
+// corresponding "USec" property.
+const annotationSystemdPropertyPrefix = "org.systemd.property."
+
 var namespaceMapping = map[specs.LinuxNamespaceType]configs.NamespaceType{
 	specs.PIDNamespace:     configs.NEWPID,
 	specs.NetworkNamespace: configs.NEWNET,
@@ -29,8 +114,13 @@ var namespaceMapping = map[specs.LinuxNamespaceType]configs.NamespaceType{
 	specs.IPCNamespace:     configs.NEWIPC,
 	specs.UTSNamespace:     configs.NEWUTS,
 	specs.CgroupNamespace:  configs.NEWCGROUP,
+	timeNamespace:          configs.NEWTIME,
 }
 
+// timeNamespace is the name of the time namespace, which the vendored runtime
+// spec does not define yet.
+const timeNamespace specs.LinuxNamespaceType = "time"
+
 var mountPropagationMapping = map[string]int{
 	"rprivate":    unix.MS_PRIVATE | unix.MS_REC,
 	"private":     unix.MS_PRIVATE,
@@ -188,9 +278,11 @@ func CreateLibcontainerConfig(opts *CreateOpts) (*configs.Config, error) {
 		RootlessCgroups: opts.RootlessCgroups,
 	}
 
+	config.RootfsOverlay = createRootfsOverlay(cwd, spec.Annotations)
+
 	exists := false
 	for _, m := range spec.Mounts {
-		config.Mounts = append(config.Mounts, createLibcontainerMount(cwd, m))
+		config.Mounts = append(config.Mounts, CreateLibcontainerMount(cwd, m))
 	}
 	if err := createDevices(spec, config); err != nil {
 		return nil, err
@@ -219,6 +311,23 @@ func CreateLibcontainerConfig(opts *CreateOpts) (*configs.Config, error) {
 			}
 			config.Namespaces.Add(t, ns.Path)
 		}
+		if v, ok := spec.Annotations[AnnotationTimeOffsets]; ok {
+			if err := json.Unmarshal([]byte(v), &config.TimeOffsets); err != nil {
+				return nil, fmt.Errorf("invalid %s annotation: %v", AnnotationTimeOffsets, err)
+			}
+		}
+		if v, ok := spec.Annotations[AnnotationLandlock]; ok {
+			if err := json.Unmarshal([]byte(v), &config.Landlock); err != nil {
+				return nil, fmt.Errorf("invalid %s annotation: %v", AnnotationLandlock, err)
+			}
+		}
+		if v, ok := spec.Annotations[AnnotationPersonality]; ok {
+			p, err := createPersonality(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s annotation: %v", AnnotationPersonality, err)
+			}
+			config.Personality = p
+		}
 		if config.Namespaces.Contains(configs.NEWNET) && config.Namespaces.PathOf(configs.NEWNET) == "" {
 			config.Networks = []*configs.Network{
 				{
@@ -243,7 +352,9 @@ func CreateLibcontainerConfig(opts *CreateOpts) (*configs.Config, error) {
 			config.Seccomp = seccomp
 		}
 		if spec.Linux.IntelRdt != nil {
-			config.IntelRdt = &configs.IntelRdt{}
+			config.IntelRdt = &configs.IntelRdt{
+				ClosID: spec.Linux.IntelRdt.ClosID,
+			}
 			if spec.Linux.IntelRdt.L3CacheSchema != "" {
 				config.IntelRdt.L3CacheSchema = spec.Linux.IntelRdt.L3CacheSchema
 			}
@@ -266,33 +377,272 @@ func CreateLibcontainerConfig(opts *CreateOpts) (*configs.Config, error) {
 				Ambient:     spec.Process.Capabilities.Ambient,
 			}
 		}
+		if v, ok := spec.Annotations[AnnotationScheduler]; ok {
+			if err := json.Unmarshal([]byte(v), &config.Scheduler); err != nil {
+				return nil, fmt.Errorf("invalid %s annotation: %v", AnnotationScheduler, err)
+			}
+		}
+		if v, ok := spec.Annotations[AnnotationIOPriority]; ok {
+			if err := json.Unmarshal([]byte(v), &config.IOPriority); err != nil {
+				return nil, fmt.Errorf("invalid %s annotation: %v", AnnotationIOPriority, err)
+			}
+		}
+		config.CPUAffinity = spec.Annotations[AnnotationCPUAffinity]
+	}
+	if err := createAppArmorProfile(cwd, opts.CgroupName, spec, config); err != nil {
+		return nil, err
 	}
 	createHooks(spec, config)
 	config.Version = specs.Version
 	return config, nil
 }
 
-func createLibcontainerMount(cwd string, m specs.Mount) *configs.Mount {
-	flags, pgflags, data, ext := parseMountOptions(m.Options)
-	source := m.Source
-	device := m.Type
-	if flags&unix.MS_BIND != 0 {
-		if device == "" {
-			device = "bind"
+// KnownNamespaces returns the names of the namespaces known to runc.
+func KnownNamespaces() []string {
+	var names []string
+	for t := range namespaceMapping {
+		names = append(names, string(t))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// IsNamespaceSupported returns whether the namespace with the given name is
+// known to runc and supported by the kernel.
+func IsNamespaceSupported(name string) bool {
+	t, ok := namespaceMapping[specs.LinuxNamespaceType(name)]
+	return ok && configs.IsNamespaceSupported(t)
+}
+
+// KnownMountOptions returns the mount options known to runc. Other options
+// are passed to the filesystem as data.
+func KnownMountOptions() []string {
+	var options []string
+	for o := range mountFlags {
+		options = append(options, o)
+	}
+	for o := range mountPropagationFlags {
+		options = append(options, o)
+	}
+	for o := range recursiveMountFlags {
+		options = append(options, o)
+	}
+	for o := range mountExtensionFlags {
+		options = append(options, o)
+	}
+	for o := range mountRelabelOptions {
+		options = append(options, o)
+	}
+	sort.Strings(options)
+	return options
+}
+
+// KnownAnnotations returns the annotations interpreted by runc. Annotations
+// ending in "*" are prefixes.
+func KnownAnnotations() []string {
+	annotations := []string{
+		AnnotationAppArmorProfile,
+		AnnotationAppArmorProfilePath,
+		AnnotationCPUAffinity,
+		AnnotationIOPriority,
+		AnnotationLandlock,
+		AnnotationMemoryHigh,
+		AnnotationMemoryLow,
+		AnnotationMemoryMin,
+		AnnotationMemoryOomGroup,
+		AnnotationPersonality,
+		AnnotationRootfsOverlayLowerDir,
+		AnnotationRootfsOverlayUpperDir,
+		AnnotationRootfsOverlayWorkDir,
+		AnnotationScheduler,
+		AnnotationTimeOffsets,
+		annotationSystemdPropertyPrefix + "*",
+	}
+	sort.Strings(annotations)
+	return annotations
+}
+
+// createAppArmorProfile sets the AppArmor profile of the container, and the
+// content to load it from if it is provided by the bundle or generated from
+// the default template.
+func createAppArmorProfile(cwd, id string, spec *specs.Spec, config *configs.Config) error {
+	if spec.Process != nil {
+		config.AppArmorProfile = spec.Process.ApparmorProfile
+	}
+	inline, hasInline := spec.Annotations[AnnotationAppArmorProfile]
+	path, hasPath := spec.Annotations[AnnotationAppArmorProfilePath]
+	if hasInline && hasPath {
+		return fmt.Errorf("only one of the %s and %s annotations can be set", AnnotationAppArmorProfile, AnnotationAppArmorProfilePath)
+	}
+	switch {
+	case config.AppArmorProfile == AppArmorRuntimeDefault:
+		if hasInline || hasPath {
+			return fmt.Errorf("the %s apparmor profile can't be provided by the bundle", AppArmorRuntimeDefault)
+		}
+		// Name the profile after the container, so that destroying the
+		// container doesn't unload the profile of another one.
+		config.AppArmorProfile = "runc-default"
+		if id != "" {
+			config.AppArmorProfile += "-" + id
+		}
+		profile, err := apparmor.DefaultProfile(config.AppArmorProfile)
+		if err != nil {
+			return err
+		}
+		config.AppArmorProfileContent = string(profile)
+	case hasInline:
+		config.AppArmorProfileContent = inline
+	case hasPath:
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(cwd, path)
 		}
-		if !filepath.IsAbs(source) {
-			source = filepath.Join(cwd, m.Source)
+		profile, err := ioutil.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("invalid %s annotation: %v", AnnotationAppArmorProfilePath, err)
 		}
+		config.AppArmorProfileContent = string(profile)
 	}
-	return &configs.Mount{
-		Device:           device,
-		Source:           source,
-		Destination:      m.Destination,
-		Data:             data,
-		Flags:            flags,
-		PropagationFlags: pgflags,
-		Extensions:       ext,
+	return nil
+}
+
+// createPersonality converts a personality in the runtime spec format.
+func createPersonality(v string) (*configs.LinuxPersonality, error) {
+	var p struct {
+		Domain string   `json:"domain"`
+		Flags  []string `json:"flags"`
 	}
+	if err := json.Unmarshal([]byte(v), &p); err != nil {
+		return nil, err
+	}
+	if len(p.Flags) > 0 {
+		return nil, fmt.Errorf("personality flags are not supported")
+	}
+	switch p.Domain {
+	case "LINUX":
+		return &configs.LinuxPersonality{Domain: configs.PerLinux}, nil
+	case "LINUX32":
+		return &configs.LinuxPersonality{Domain: configs.PerLinux32}, nil
+	}
+	return nil, fmt.Errorf("unknown personality domain %q", p.Domain)
+}
+
+// CreateLibcontainerMount converts an OCI mount into a libcontainer mount,
+// resolving the source of bind mounts relative to cwd.
+func CreateLibcontainerMount(cwd string, m specs.Mount) *configs.Mount {
+	mnt := parseMountOptions(m.Options)
+	mnt.Destination = m.Destination
+	mnt.Source = m.Source
+	mnt.Device = m.Type
+	if mnt.Flags&unix.MS_BIND != 0 {
+		if mnt.Device == "" {
+			mnt.Device = "bind"
+		}
+		if !filepath.IsAbs(mnt.Source) {
+			mnt.Source = filepath.Join(cwd, m.Source)
+		}
+	}
+	return mnt
+}
+
+// createSystemdProps returns the systemd unit properties set by the
+// annotations with annotationSystemdPropertyPrefix.
+func createSystemdProps(annotations map[string]string) ([]systemdDbus.Property, error) {
+	var keys []string
+	for k := range annotations {
+		if strings.HasPrefix(k, annotationSystemdPropertyPrefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var props []systemdDbus.Property
+	for _, k := range keys {
+		name := strings.TrimPrefix(k, annotationSystemdPropertyPrefix)
+		if !isValidSystemdPropertyName(name) {
+			return nil, fmt.Errorf("annotation %s: invalid systemd property name %q", k, name)
+		}
+		value, err := dbus.ParseVariant(annotations[k], dbus.Signature{})
+		if err != nil {
+			return nil, fmt.Errorf("annotation %s: unable to parse value %q: %v", k, annotations[k], err)
+		}
+		// systemd only accepts time spans in microseconds over dbus.
+		if trimmed := strings.TrimSuffix(name, "Sec"); trimmed != name && !strings.HasSuffix(trimmed, "U") {
+			name = trimmed + "USec"
+			if value, err = convertSecToUSec(value); err != nil {
+				return nil, fmt.Errorf("annotation %s: %v", k, err)
+			}
+		}
+		props = append(props, systemdDbus.Property{Name: name, Value: value})
+	}
+	return props, nil
+}
+
+// isValidSystemdPropertyName reports whether name looks like a systemd
+// property name, which consists of ASCII letters and digits and starts with
+// an uppercase letter.
+func isValidSystemdPropertyName(name string) bool {
+	if name == "" || name[0] < 'A' || name[0] > 'Z' {
+		return false
+	}
+	for _, c := range name {
+		if !(c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// convertSecToUSec converts a non-negative numeric value in seconds to
+// microseconds.
+func convertSecToUSec(value dbus.Variant) (dbus.Variant, error) {
+	var sec float64
+	switch v := value.Value().(type) {
+	case byte:
+		sec = float64(v)
+	case int16:
+		sec = float64(v)
+	case uint16:
+		sec = float64(v)
+	case int32:
+		sec = float64(v)
+	case uint32:
+		sec = float64(v)
+	case int64:
+		sec = float64(v)
+	case uint64:
+		sec = float64(v)
+	case float64:
+		sec = v
+	default:
+		return value, fmt.Errorf("value %s is not a number of seconds", value)
+	}
+	if sec < 0 {
+		return value, fmt.Errorf("value %s is negative", value)
+	}
+	return dbus.MakeVariant(uint64(sec * 1000000)), nil
+}
+
+// createRootfsOverlay returns the overlay rootfs described by the bundle's
+// annotations, or nil if the bundle uses a pre-assembled rootfs.
+func createRootfsOverlay(cwd string, annotations map[string]string) *configs.Overlay {
+	lower := annotations[AnnotationRootfsOverlayLowerDir]
+	if lower == "" {
+		return nil
+	}
+	abs := func(p string) string {
+		if p != "" && !filepath.IsAbs(p) {
+			return filepath.Join(cwd, p)
+		}
+		return p
+	}
+	overlay := &configs.Overlay{
+		UpperDir: abs(annotations[AnnotationRootfsOverlayUpperDir]),
+		WorkDir:  abs(annotations[AnnotationRootfsOverlayWorkDir]),
+	}
+	for _, dir := range strings.Split(lower, ":") {
+		overlay.LowerDirs = append(overlay.LowerDirs, abs(dir))
+	}
+	return overlay
 }
 
 func createCgroupConfig(opts *CreateOpts) (*configs.Cgroup, error) {
@@ -316,8 +666,16 @@ func createCgroupConfig(opts *CreateOpts) (*configs.Cgroup, error) {
 	}
 
 	if useSystemdCgroup {
+		sp, err := createSystemdProps(spec.Annotations)
+		if err != nil {
+			return nil, err
+		}
+		c.SystemdProps = sp
 		if myCgroupPath == "" {
 			c.Parent = "system.slice"
+			if opts.RootlessCgroups {
+				c.Parent = "user.slice"
+			}
 			c.ScopePrefix = "runc"
 			c.Name = name
 		} else {
@@ -338,6 +696,10 @@ func createCgroupConfig(opts *CreateOpts) (*configs.Cgroup, error) {
 		c.Path = myCgroupPath
 	}
 
+	if err := createMemoryResources(spec.Annotations, c.Resources); err != nil {
+		return nil, err
+	}
+
 	// In rootless containers, any attempt to make cgroup changes is likely to fail.
 	// libcontainer will validate this but ignores the error.
 	c.Resources.AllowedDevices = allowedDevices
@@ -498,6 +860,51 @@ func createCgroupConfig(opts *CreateOpts) (*configs.Cgroup, error) {
 	return c, nil
 }
 
+// createMemoryResources sets the memory controls of r which are given by
+// annotations.
+func createMemoryResources(annotations map[string]string, r *configs.Resources) error {
+	for _, a := range []struct {
+		annotation string
+		dest       *int64
+	}{
+		{AnnotationMemoryHigh, &r.MemoryHigh},
+		{AnnotationMemoryLow, &r.MemoryLow},
+		{AnnotationMemoryMin, &r.MemoryMin},
+	} {
+		v, ok := annotations[a.annotation]
+		if !ok {
+			continue
+		}
+		value, err := parseMemoryValue(v)
+		if err != nil {
+			return fmt.Errorf("annotation %s: %v", a.annotation, err)
+		}
+		*a.dest = value
+	}
+	if v, ok := annotations[AnnotationMemoryOomGroup]; ok {
+		oomGroup, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("annotation %s: invalid value %q", AnnotationMemoryOomGroup, v)
+		}
+		r.MemoryOomGroup = oomGroup
+	}
+	return nil
+}
+
+// parseMemoryValue parses a memory control of the unified cgroup hierarchy,
+// which is either a number of bytes or "max", returned as -1. As with runc
+// update, "-1" is accepted as well.
+func parseMemoryValue(v string) (int64, error) {
+	if v == "max" || v == "-1" {
+		return -1, nil
+	}
+	value, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid memory value %q", v)
+	}
+	return value, nil
+}
+
 func stringToCgroupDeviceRune(s string) (rune, error) {
 	switch s {
 	case "a":
@@ -649,92 +1056,121 @@ func setupUserNamespace(spec *specs.Spec, config *configs.Config) error {
 	return nil
 }
 
-// parseMountOptions parses the string and returns the flags, propagation
-// flags and any mount data that it contains.
-func parseMountOptions(options []string) (int, []int, string, int) {
+// mountFlags are the mount options which set or clear mount flags.
+var mountFlags = map[string]struct {
+	clear bool
+	flag  int
+}{
+	"acl":           {false, unix.MS_POSIXACL},
+	"async":         {true, unix.MS_SYNCHRONOUS},
+	"atime":         {true, unix.MS_NOATIME},
+	"bind":          {false, unix.MS_BIND},
+	"defaults":      {false, 0},
+	"dev":           {true, unix.MS_NODEV},
+	"diratime":      {true, unix.MS_NODIRATIME},
+	"dirsync":       {false, unix.MS_DIRSYNC},
+	"exec":          {true, unix.MS_NOEXEC},
+	"iversion":      {false, unix.MS_I_VERSION},
+	"lazytime":      {false, unix.MS_LAZYTIME},
+	"loud":          {true, unix.MS_SILENT},
+	"mand":          {false, unix.MS_MANDLOCK},
+	"noacl":         {true, unix.MS_POSIXACL},
+	"noatime":       {false, unix.MS_NOATIME},
+	"nodev":         {false, unix.MS_NODEV},
+	"nodiratime":    {false, unix.MS_NODIRATIME},
+	"noexec":        {false, unix.MS_NOEXEC},
+	"noiversion":    {true, unix.MS_I_VERSION},
+	"nolazytime":    {true, unix.MS_LAZYTIME},
+	"nomand":        {true, unix.MS_MANDLOCK},
+	"norelatime":    {true, unix.MS_RELATIME},
+	"nostrictatime": {true, unix.MS_STRICTATIME},
+	"nosuid":        {false, unix.MS_NOSUID},
+	"rbind":         {false, unix.MS_BIND | unix.MS_REC},
+	"relatime":      {false, unix.MS_RELATIME},
+	"remount":       {false, unix.MS_REMOUNT},
+	"ro":            {false, unix.MS_RDONLY},
+	"rw":            {true, unix.MS_RDONLY},
+	"silent":        {false, unix.MS_SILENT},
+	"strictatime":   {false, unix.MS_STRICTATIME},
+	"suid":          {true, unix.MS_NOSUID},
+	"sync":          {false, unix.MS_SYNCHRONOUS},
+}
+
+// mountPropagationFlags are the mount options which set the propagation type.
+var mountPropagationFlags = map[string]int{
+	"private":     unix.MS_PRIVATE,
+	"shared":      unix.MS_SHARED,
+	"slave":       unix.MS_SLAVE,
+	"unbindable":  unix.MS_UNBINDABLE,
+	"rprivate":    unix.MS_PRIVATE | unix.MS_REC,
+	"rshared":     unix.MS_SHARED | unix.MS_REC,
+	"rslave":      unix.MS_SLAVE | unix.MS_REC,
+	"runbindable": unix.MS_UNBINDABLE | unix.MS_REC,
+}
+
+// Recursive flags are applied to the mount and all of its submounts. They
+// also apply to the mount itself, so they are set in the regular flags as
+// well.
+var recursiveMountFlags = map[string]int{
+	"rro":     unix.MS_RDONLY,
+	"rnosuid": unix.MS_NOSUID,
+	"rnodev":  unix.MS_NODEV,
+	"rnoexec": unix.MS_NOEXEC,
+}
+
+// mountExtensionFlags are the mount options implemented by runc.
+var mountExtensionFlags = map[string]struct {
+	clear bool
+	flag  int
+}{
+	"tmpcopyup": {false, configs.EXT_COPYUP},
+	"U":         {false, configs.EXT_CHOWN},
+	"idmap":     {false, configs.EXT_IDMAP},
+}
+
+// "z" relabels the source with a shared label, "Z" with a private one.
+var mountRelabelOptions = map[string]bool{
+	"z": true,
+	"Z": true,
+}
+
+// parseMountOptions parses the string and returns a mount with the flags,
+// propagation flags, recursive flags, any mount data, the runc extension
+// flags and the relabel mode that it contains.
+func parseMountOptions(options []string) *configs.Mount {
 	var (
-		flag     int
-		pgflag   []int
-		data     []string
-		extFlags int
+		m    = &configs.Mount{}
+		data []string
 	)
-	flags := map[string]struct {
-		clear bool
-		flag  int
-	}{
-		"acl":           {false, unix.MS_POSIXACL},
-		"async":         {true, unix.MS_SYNCHRONOUS},
-		"atime":         {true, unix.MS_NOATIME},
-		"bind":          {false, unix.MS_BIND},
-		"defaults":      {false, 0},
-		"dev":           {true, unix.MS_NODEV},
-		"diratime":      {true, unix.MS_NODIRATIME},
-		"dirsync":       {false, unix.MS_DIRSYNC},
-		"exec":          {true, unix.MS_NOEXEC},
-		"iversion":      {false, unix.MS_I_VERSION},
-		"lazytime":      {false, unix.MS_LAZYTIME},
-		"loud":          {true, unix.MS_SILENT},
-		"mand":          {false, unix.MS_MANDLOCK},
-		"noacl":         {true, unix.MS_POSIXACL},
-		"noatime":       {false, unix.MS_NOATIME},
-		"nodev":         {false, unix.MS_NODEV},
-		"nodiratime":    {false, unix.MS_NODIRATIME},
-		"noexec":        {false, unix.MS_NOEXEC},
-		"noiversion":    {true, unix.MS_I_VERSION},
-		"nolazytime":    {true, unix.MS_LAZYTIME},
-		"nomand":        {true, unix.MS_MANDLOCK},
-		"norelatime":    {true, unix.MS_RELATIME},
-		"nostrictatime": {true, unix.MS_STRICTATIME},
-		"nosuid":        {false, unix.MS_NOSUID},
-		"rbind":         {false, unix.MS_BIND | unix.MS_REC},
-		"relatime":      {false, unix.MS_RELATIME},
-		"remount":       {false, unix.MS_REMOUNT},
-		"ro":            {false, unix.MS_RDONLY},
-		"rw":            {true, unix.MS_RDONLY},
-		"silent":        {false, unix.MS_SILENT},
-		"strictatime":   {false, unix.MS_STRICTATIME},
-		"suid":          {true, unix.MS_NOSUID},
-		"sync":          {false, unix.MS_SYNCHRONOUS},
-	}
-	propagationFlags := map[string]int{
-		"private":     unix.MS_PRIVATE,
-		"shared":      unix.MS_SHARED,
-		"slave":       unix.MS_SLAVE,
-		"unbindable":  unix.MS_UNBINDABLE,
-		"rprivate":    unix.MS_PRIVATE | unix.MS_REC,
-		"rshared":     unix.MS_SHARED | unix.MS_REC,
-		"rslave":      unix.MS_SLAVE | unix.MS_REC,
-		"runbindable": unix.MS_UNBINDABLE | unix.MS_REC,
-	}
-	extensionFlags := map[string]struct {
-		clear bool
-		flag  int
-	}{
-		"tmpcopyup": {false, configs.EXT_COPYUP},
-	}
 	for _, o := range options {
 		// If the option does not exist in the flags table or the flag
 		// is not supported on the platform,
 		// then it is a data value for a specific fs type
-		if f, exists := flags[o]; exists && f.flag != 0 {
+		if f, exists := mountFlags[o]; exists && f.flag != 0 {
 			if f.clear {
-				flag &= ^f.flag
+				m.Flags &= ^f.flag
 			} else {
-				flag |= f.flag
+				m.Flags |= f.flag
 			}
-		} else if f, exists := propagationFlags[o]; exists && f != 0 {
-			pgflag = append(pgflag, f)
-		} else if f, exists := extensionFlags[o]; exists && f.flag != 0 {
+		} else if f, exists := mountPropagationFlags[o]; exists && f != 0 {
+			m.PropagationFlags = append(m.PropagationFlags, f)
+		} else if f, exists := recursiveMountFlags[o]; exists {
+			m.Flags |= f
+			m.RecursiveFlags |= f
+		} else if f, exists := mountExtensionFlags[o]; exists && f.flag != 0 {
 			if f.clear {
-				extFlags &= ^f.flag
+				m.Extensions &= ^f.flag
 			} else {
-				extFlags |= f.flag
+				m.Extensions |= f.flag
 			}
+		} else if mountRelabelOptions[o] {
+			m.Relabel = o
 		} else {
 			data = append(data, o)
 		}
 	}
-	return flag, pgflag, strings.Join(data, ","), extFlags
+	m.Data = strings.Join(data, ",")
+	return m
 }
 
 func SetupSeccomp(config *specs.LinuxSeccomp) (*configs.Seccomp, error) {
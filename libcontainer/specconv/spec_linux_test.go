@@ -3,13 +3,19 @@
 package specconv
 
 import (
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
 	"strings"
 	"testing"
 
 	"github.com/opencontainers/runc/libcontainer/configs"
 	"github.com/opencontainers/runc/libcontainer/configs/validate"
 	"github.com/opencontainers/runtime-spec/specs-go"
+
+	"golang.org/x/sys/unix"
 )
 
 func TestCreateCommandHookTimeout(t *testing.T) {
@@ -421,6 +427,213 @@ func TestDupNamespaces(t *testing.T) {
 	}
 }
 
+func TestTimeNamespace(t *testing.T) {
+	spec := &specs.Spec{
+		Root: &specs.Root{
+			Path: "rootfs",
+		},
+		Linux: &specs.Linux{
+			Namespaces: []specs.LinuxNamespace{
+				{
+					Type: "time",
+				},
+			},
+		},
+		Annotations: map[string]string{
+			AnnotationTimeOffsets: `{"monotonic": {"secs": 86400}, "boottime": {"secs": 3600, "nanosecs": 500}}`,
+		},
+	}
+
+	config, err := CreateLibcontainerConfig(&CreateOpts{
+		Spec: spec,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !config.Namespaces.Contains(configs.NEWTIME) {
+		t.Fatal("Expected a time namespace")
+	}
+	expected := map[string]configs.TimeOffset{
+		"monotonic": {Secs: 86400},
+		"boottime":  {Secs: 3600, Nanosecs: 500},
+	}
+	if !reflect.DeepEqual(config.TimeOffsets, expected) {
+		t.Fatalf("Expected time offsets %v, got %v", expected, config.TimeOffsets)
+	}
+}
+
+func TestAppArmorRuntimeDefault(t *testing.T) {
+	spec := Example()
+	spec.Process.ApparmorProfile = AppArmorRuntimeDefault
+
+	config, err := CreateLibcontainerConfig(&CreateOpts{
+		CgroupName: "ContainerID",
+		Spec:       spec,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.AppArmorProfile != "runc-default-ContainerID" {
+		t.Fatalf("Expected the generated profile to be named after the container, got %q", config.AppArmorProfile)
+	}
+	if !strings.Contains(config.AppArmorProfileContent, "profile runc-default-ContainerID ") {
+		t.Fatalf("Expected the generated profile content, got %q", config.AppArmorProfileContent)
+	}
+
+	spec.Annotations = map[string]string{AnnotationAppArmorProfile: "profile test {}"}
+	if _, err := CreateLibcontainerConfig(&CreateOpts{CgroupName: "ContainerID", Spec: spec}); err == nil {
+		t.Fatal("Expected an error providing the runtime default profile")
+	}
+}
+
+func TestAppArmorProfileAnnotations(t *testing.T) {
+	dir, err := ioutil.TempDir("", "apparmor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "profile"), []byte("profile test {}"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	spec := Example()
+	spec.Process.ApparmorProfile = "test"
+	for _, annotations := range []map[string]string{
+		{AnnotationAppArmorProfile: "profile test {}"},
+		{AnnotationAppArmorProfilePath: filepath.Join(dir, "profile")},
+	} {
+		spec.Annotations = annotations
+		config, err := CreateLibcontainerConfig(&CreateOpts{
+			CgroupName: "ContainerID",
+			Spec:       spec,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if config.AppArmorProfile != "test" || config.AppArmorProfileContent != "profile test {}" {
+			t.Fatalf("Expected the profile test from %v, got %q: %q", annotations, config.AppArmorProfile, config.AppArmorProfileContent)
+		}
+	}
+
+	spec.Annotations = map[string]string{
+		AnnotationAppArmorProfile:     "profile test {}",
+		AnnotationAppArmorProfilePath: filepath.Join(dir, "profile"),
+	}
+	if _, err := CreateLibcontainerConfig(&CreateOpts{CgroupName: "ContainerID", Spec: spec}); err == nil {
+		t.Fatal("Expected an error providing two profiles")
+	}
+}
+
+func TestLandlockAnnotation(t *testing.T) {
+	spec := Example()
+	spec.Annotations = map[string]string{
+		AnnotationLandlock: `{"rules": [{"path": "/usr", "access": ["execute", "read_file"]}], "disable_best_effort": true}`,
+	}
+
+	config, err := CreateLibcontainerConfig(&CreateOpts{
+		CgroupName: "ContainerID",
+		Spec:       spec,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &configs.Landlock{
+		Rules: []*configs.LandlockRule{
+			{Path: "/usr", Access: []string{"execute", "read_file"}},
+		},
+		DisableBestEffort: true,
+	}
+	if !reflect.DeepEqual(config.Landlock, expected) {
+		t.Fatalf("Expected landlock ruleset %+v, got %+v", expected, config.Landlock)
+	}
+}
+
+func TestPersonalityAnnotation(t *testing.T) {
+	spec := Example()
+	spec.Annotations = map[string]string{
+		AnnotationPersonality: `{"domain": "LINUX32"}`,
+	}
+
+	config, err := CreateLibcontainerConfig(&CreateOpts{
+		CgroupName: "ContainerID",
+		Spec:       spec,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := config.Personality; p == nil || p.Domain != configs.PerLinux32 {
+		t.Fatalf("Expected the LINUX32 personality, got %+v", p)
+	}
+
+	for _, v := range []string{`{"domain": "LINUX64"}`, `{"domain": "LINUX", "flags": ["SHORT_INODE"]}`, `LINUX32`} {
+		spec.Annotations[AnnotationPersonality] = v
+		if _, err := CreateLibcontainerConfig(&CreateOpts{CgroupName: "ContainerID", Spec: spec}); err == nil {
+			t.Errorf("Expected an error for personality %s", v)
+		}
+	}
+}
+
+func TestProcessSchedulingAnnotations(t *testing.T) {
+	spec := Example()
+	spec.Annotations = map[string]string{
+		AnnotationScheduler:   `{"policy": "SCHED_BATCH", "nice": 5}`,
+		AnnotationIOPriority:  `{"class": "IOPRIO_CLASS_IDLE"}`,
+		AnnotationCPUAffinity: "0-1",
+	}
+
+	config, err := CreateLibcontainerConfig(&CreateOpts{
+		CgroupName: "ContainerID",
+		Spec:       spec,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := config.Scheduler; s == nil || s.Policy != "SCHED_BATCH" || s.Nice != 5 {
+		t.Fatalf("Expected a SCHED_BATCH scheduler with nice 5, got %+v", s)
+	}
+	if p := config.IOPriority; p == nil || p.Class != "IOPRIO_CLASS_IDLE" || p.Priority != 0 {
+		t.Fatalf("Expected an idle io priority, got %+v", p)
+	}
+	if config.CPUAffinity != "0-1" {
+		t.Fatalf("Expected cpu affinity 0-1, got %q", config.CPUAffinity)
+	}
+
+	spec.Annotations[AnnotationScheduler] = `{"policy": 3}`
+	if _, err := CreateLibcontainerConfig(&CreateOpts{CgroupName: "ContainerID", Spec: spec}); err == nil {
+		t.Fatal("Expected an error for an invalid scheduler annotation")
+	}
+}
+
+func TestKnownMountOptions(t *testing.T) {
+	for _, o := range KnownMountOptions() {
+		if o == "defaults" {
+			continue
+		}
+		if m := parseMountOptions([]string{o}); m.Data != "" {
+			t.Errorf("Expected the known mount option %s not to be passed as data", o)
+		}
+	}
+}
+
+func TestKnownNamespaces(t *testing.T) {
+	known := KnownNamespaces()
+	if !sort.StringsAreSorted(known) {
+		t.Errorf("Expected the namespaces to be sorted, got %v", known)
+	}
+	for _, ns := range []string{"mount", "network", "time"} {
+		found := false
+		for _, k := range known {
+			found = found || k == ns
+		}
+		if !found {
+			t.Errorf("Expected the %s namespace to be known, got %v", ns, known)
+		}
+	}
+	if IsNamespaceSupported("unknown") {
+		t.Error("Expected an unknown namespace not to be supported")
+	}
+}
+
 func TestNonZeroEUIDCompatibleSpecconvValidate(t *testing.T) {
 	if _, err := os.Stat("/proc/self/ns/user"); os.IsNotExist(err) {
 		t.Skip("userns is unsupported")
@@ -448,3 +661,141 @@ func TestNonZeroEUIDCompatibleSpecconvValidate(t *testing.T) {
 		t.Errorf("Expected specconv to produce valid rootless container config: %v", err)
 	}
 }
+
+func TestRootfsOverlayAnnotations(t *testing.T) {
+	spec := Example()
+	spec.Root.Path = "/"
+	spec.Annotations = map[string]string{
+		AnnotationRootfsOverlayLowerDir: "/layers/2:layers/1",
+		AnnotationRootfsOverlayUpperDir: "/layers/upper",
+		AnnotationRootfsOverlayWorkDir:  "/layers/work",
+	}
+
+	config, err := CreateLibcontainerConfig(&CreateOpts{
+		CgroupName: "ContainerID",
+		Spec:       spec,
+	})
+	if err != nil {
+		t.Fatalf("Couldn't create libcontainer config: %v", err)
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	overlay := config.RootfsOverlay
+	if overlay == nil {
+		t.Fatal("Expected rootfs overlay to be set")
+	}
+	if len(overlay.LowerDirs) != 2 || overlay.LowerDirs[0] != "/layers/2" || overlay.LowerDirs[1] != filepath.Join(cwd, "layers/1") {
+		t.Errorf("Unexpected lower directories: %v", overlay.LowerDirs)
+	}
+	if overlay.UpperDir != "/layers/upper" || overlay.WorkDir != "/layers/work" {
+		t.Errorf("Unexpected upper/work directories: %q, %q", overlay.UpperDir, overlay.WorkDir)
+	}
+	expected := "lowerdir=/layers/2:" + filepath.Join(cwd, "layers/1") + ",upperdir=/layers/upper,workdir=/layers/work"
+	if data := overlay.MountData(); data != expected {
+		t.Errorf("Expected mount data %q, got %q", expected, data)
+	}
+}
+
+func TestCreateLibcontainerMountOwnershipOptions(t *testing.T) {
+	m := CreateLibcontainerMount("/bundle", specs.Mount{
+		Destination: "/data",
+		Source:      "data",
+		Options:     []string{"rbind", "Z", "U", "idmap", "ro"},
+	})
+	if m.Device != "bind" || m.Source != "/bundle/data" {
+		t.Errorf("Unexpected bind mount: %+v", m)
+	}
+	if m.Relabel != "Z" {
+		t.Errorf("Expected relabel to be %q, got %q", "Z", m.Relabel)
+	}
+	if m.Extensions&configs.EXT_CHOWN == 0 || m.Extensions&configs.EXT_IDMAP == 0 {
+		t.Errorf("Expected chown and idmap extensions to be set, got %#x", m.Extensions)
+	}
+	if m.Data != "" {
+		t.Errorf("Expected ownership options not to be passed as mount data, got %q", m.Data)
+	}
+}
+
+func TestParseMountOptionsRecursiveFlags(t *testing.T) {
+	m := parseMountOptions([]string{"rbind", "rro", "rnosuid", "rnodev", "rnoexec"})
+	recursive := unix.MS_RDONLY | unix.MS_NOSUID | unix.MS_NODEV | unix.MS_NOEXEC
+	if m.RecursiveFlags != recursive {
+		t.Errorf("Expected recursive flags %#x, got %#x", recursive, m.RecursiveFlags)
+	}
+	if m.Flags&recursive != recursive {
+		t.Errorf("Expected recursive flags to also be set on the mount, got %#x", m.Flags)
+	}
+	if m.Data != "" {
+		t.Errorf("Expected recursive options not to be passed as mount data, got %q", m.Data)
+	}
+}
+
+func TestCreateSystemdProps(t *testing.T) {
+	props, err := createSystemdProps(map[string]string{
+		"org.systemd.property.TimeoutStopSec":     "uint64 10",
+		"org.systemd.property.CollectMode":        "'inactive-or-failed'",
+		"org.systemd.property.CPUQuotaPeriodUSec": "uint64 50000",
+		"org.opencontainers.some.annotation":      "unrelated",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"CPUQuotaPeriodUSec": uint64(50000),
+		"CollectMode":        "inactive-or-failed",
+		"TimeoutStopUSec":    uint64(10000000),
+	}
+	if len(props) != len(expected) {
+		t.Fatalf("Expected %d properties, got %+v", len(expected), props)
+	}
+	for _, p := range props {
+		if v, ok := expected[p.Name]; !ok || p.Value.Value() != v {
+			t.Errorf("Unexpected property %s=%v", p.Name, p.Value)
+		}
+	}
+
+	for _, annotations := range []map[string]string{
+		{"org.systemd.property.lowercase": "true"},
+		{"org.systemd.property.Bad-Name": "true"},
+		{"org.systemd.property.CollectMode": "not a gvariant"},
+		{"org.systemd.property.TimeoutStopSec": "'ten'"},
+		{"org.systemd.property.TimeoutStopSec": "int32 -1"},
+	} {
+		if _, err := createSystemdProps(annotations); err == nil {
+			t.Errorf("Expected error for annotations %v", annotations)
+		}
+	}
+}
+
+func TestCreateMemoryResources(t *testing.T) {
+	r := &configs.Resources{}
+	if err := createMemoryResources(map[string]string{
+		AnnotationMemoryHigh:     "max",
+		AnnotationMemoryLow:      "1048576",
+		AnnotationMemoryOomGroup: "true",
+	}, r); err != nil {
+		t.Fatal(err)
+	}
+	if r.MemoryHigh != -1 || r.MemoryLow != 1048576 || r.MemoryMin != 0 || !r.MemoryOomGroup {
+		t.Fatalf("Unexpected memory resources %+v", r)
+	}
+	if err := createMemoryResources(map[string]string{AnnotationMemoryMin: "-1"}, r); err != nil {
+		t.Fatal(err)
+	}
+	if r.MemoryMin != -1 {
+		t.Fatalf("Expected -1 to be accepted like max, got %d", r.MemoryMin)
+	}
+
+	for _, annotations := range []map[string]string{
+		{AnnotationMemoryHigh: "-2"},
+		{AnnotationMemoryMin: "1M"},
+		{AnnotationMemoryOomGroup: "sometimes"},
+	} {
+		if err := createMemoryResources(annotations, &configs.Resources{}); err == nil {
+			t.Errorf("Expected error for annotations %v", annotations)
+		}
+	}
+}
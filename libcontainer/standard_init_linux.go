@@ -12,6 +12,7 @@ import (
 	"github.com/opencontainers/runc/libcontainer/apparmor"
 	"github.com/opencontainers/runc/libcontainer/configs"
 	"github.com/opencontainers/runc/libcontainer/keys"
+	"github.com/opencontainers/runc/libcontainer/landlock"
 	"github.com/opencontainers/runc/libcontainer/seccomp"
 	"github.com/opencontainers/runc/libcontainer/system"
 	"github.com/opencontainers/selinux/go-selinux/label"
@@ -146,6 +147,16 @@ func (l *linuxStandardInit) Init() error {
 		return errors.Wrap(err, "set process label")
 	}
 	defer label.SetProcessLabel("")
+	if p := l.config.Config.Personality; p != nil {
+		if err := system.SetLinuxPersonality(p.Domain); err != nil {
+			return newSystemErrorWithCause(err, "set personality")
+		}
+	}
+	// Setting a real-time policy or raising the priority requires
+	// CAP_SYS_NICE, so do this before seccomp and dropping capabilities.
+	if err := setupScheduling(l.config); err != nil {
+		return err
+	}
 	// Without NoNewPrivileges seccomp is a privileged operation, so we need to
 	// do this before dropping capabilities; otherwise do it as late as possible
 	// just before execve so as few syscalls take place after it as possible.
@@ -175,6 +186,14 @@ func (l *linuxStandardInit) Init() error {
 	if err != nil {
 		return err
 	}
+	// Landlock also restricts the accesses of init itself, so only enforce
+	// the ruleset once nothing but execve is left to do. Its paths are
+	// opened now, so that errors are returned as create time errors.
+	ruleset, err := landlock.NewRuleset(l.config.Landlock)
+	if err != nil {
+		return newSystemErrorWithCause(err, "create landlock ruleset")
+	}
+	defer ruleset.Close()
 	// Close the pipe to signal that we have completed our init.
 	l.pipe.Close()
 	// Wait for the FIFO to be opened on the other side before exec-ing the
@@ -195,6 +214,9 @@ func (l *linuxStandardInit) Init() error {
 	// since been resolved.
 	// https://github.com/torvalds/linux/blob/v4.9/fs/exec.c#L1290-L1318
 	unix.Close(l.fifoFd)
+	if err := ruleset.RestrictSelf(); err != nil {
+		return newSystemErrorWithCause(err, "apply landlock ruleset")
+	}
 	// Set seccomp as close to execve as possible, so as few syscalls take
 	// place afterward (reducing the amount of syscalls that users need to
 	// enable in their seccomp profiles).
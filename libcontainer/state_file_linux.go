@@ -0,0 +1,283 @@
+// +build linux
+
+package libcontainer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/opencontainers/runc/libcontainer/cgroups"
+	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runc/libcontainer/system"
+	"github.com/opencontainers/runc/libcontainer/utils"
+)
+
+// currentStateVersion is the schema version of the state files written by
+// this version of libcontainer. State files without a version were written
+// before versioning was introduced and have version 0.
+const currentStateVersion = 1
+
+// stateMigrations[v] converts the raw JSON of a state file of version v to
+// version v+1.
+var stateMigrations = []func(state map[string]interface{}) error{
+	migrateStateV0,
+}
+
+// migrateStateV0 splits the "rootless" setting of old configurations into
+// the separate rootless_euid and rootless_cgroups settings.
+func migrateStateV0(state map[string]interface{}) error {
+	config, ok := state["config"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	if rootless, ok := config["rootless"].(bool); ok {
+		delete(config, "rootless")
+		if rootless {
+			config["rootless_euid"] = true
+			config["rootless_cgroups"] = true
+		}
+	}
+	return nil
+}
+
+// writeStateFile atomically replaces the state file in dir with s. The state
+// is written to a temporary file first, which is synced to disk and renamed
+// over the state file, so that a crash never leaves a partial state behind.
+func writeStateFile(dir string, s *State) (err error) {
+	s.StateVersion = currentStateVersion
+	f, err := ioutil.TempFile(dir, stateFilename+".")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+	if err := utils.WriteJSON(f, s); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(f.Name(), filepath.Join(dir, stateFilename)); err != nil {
+		return err
+	}
+	// Make the rename itself durable.
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// decodeState decodes a state file, migrating it from older schema versions.
+func decodeState(data []byte) (*State, error) {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		return nil, fmt.Errorf("empty state")
+	}
+	version := 0
+	if v, ok := raw["state_version"]; ok {
+		f, ok := v.(float64)
+		if !ok || f < 0 || f != float64(int(f)) {
+			return nil, fmt.Errorf("invalid state version %v", v)
+		}
+		version = int(f)
+	}
+	if version > currentStateVersion {
+		return nil, fmt.Errorf("state version %d is newer than the supported version %d", version, currentStateVersion)
+	}
+	if version < currentStateVersion {
+		for _, migrate := range stateMigrations[version:] {
+			if err := migrate(raw); err != nil {
+				return nil, err
+			}
+		}
+		raw["state_version"] = currentStateVersion
+		var err error
+		if data, err = json.Marshal(raw); err != nil {
+			return nil, err
+		}
+	}
+	var state *State
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// repairState builds a minimal state for the container id from the cgroup at
+// cgroupsPath and the pid of its init process, which must be a process in
+// that cgroup, or, if pid is 0, the first process started in the cgroup. The
+// state describes a stopped container if no process is found, so that it can
+// still be destroyed.
+func repairState(id string, pid int, cgroupsPath string) (*State, error) {
+	if cgroupsPath == "" {
+		return nil, fmt.Errorf("the cgroup path of the container is required")
+	}
+	mounts, err := cgroups.GetCgroupMounts(false)
+	if err != nil {
+		return nil, err
+	}
+	cgroupPaths := absCgroupPaths(mounts, cgroupsPath)
+	if pid != 0 {
+		if len(cgroupPaths) == 0 {
+			return nil, fmt.Errorf("cgroup %s does not exist", cgroupsPath)
+		}
+		if err := checkPidCgroups(mounts, pid, cgroupPaths); err != nil {
+			return nil, err
+		}
+	} else if pid, err = findInitPid(cgroupPaths); err != nil {
+		return nil, err
+	}
+	state := &State{
+		BaseState: BaseState{
+			ID:      id,
+			Created: time.Now().UTC(),
+			Config: configs.Config{
+				Cgroups: &configs.Cgroup{
+					Path:      cgroupsPath,
+					Resources: &configs.Resources{},
+				},
+			},
+		},
+		CgroupPaths:    cgroupPaths,
+		NamespacePaths: make(map[configs.NamespaceType]string),
+	}
+	if pid == 0 {
+		return state, nil
+	}
+	stat, err := system.Stat(pid)
+	if err != nil {
+		return nil, err
+	}
+	state.InitProcessPid = pid
+	state.InitProcessStartTime = stat.StartTime
+	for _, t := range configs.NamespaceTypes() {
+		if !configs.IsNamespaceSupported(t) {
+			continue
+		}
+		ns := configs.Namespace{Type: t}
+		own, err := os.Stat(ns.GetPath(os.Getpid()))
+		if err != nil {
+			return nil, err
+		}
+		theirs, err := os.Stat(ns.GetPath(pid))
+		if err != nil {
+			return nil, err
+		}
+		if !os.SameFile(own, theirs) {
+			state.Config.Namespaces.Add(t, "")
+			state.NamespacePaths[t] = ns.GetPath(pid)
+		}
+	}
+	// Every container has at least its own mount namespace, signalling and
+	// destroying a process which shares all of ours would hit the host.
+	if len(state.NamespacePaths) == 0 {
+		return nil, fmt.Errorf("process %d shares all namespaces with runc, it is not the init process of a container", pid)
+	}
+	return state, nil
+}
+
+// checkPidCgroups checks that pid is a process in the cgroups at cgroupPaths
+// or in cgroups below them.
+func checkPidCgroups(mounts []cgroups.Mount, pid int, cgroupPaths map[string]string) error {
+	paths, err := pidCgroupPaths(mounts, pid)
+	if err != nil {
+		return err
+	}
+	for ss, path := range cgroupPaths {
+		if p := paths[ss]; p != path && !strings.HasPrefix(p, path+"/") {
+			return fmt.Errorf("process %d is not in the cgroup %s", pid, path)
+		}
+	}
+	return nil
+}
+
+// pidCgroupPaths returns the paths of the cgroups of pid, keyed by subsystem.
+func pidCgroupPaths(mounts []cgroups.Mount, pid int) (map[string]string, error) {
+	procCgroups, err := cgroups.ParseCgroupFile(fmt.Sprintf("/proc/%d/cgroup", pid))
+	if err != nil {
+		return nil, err
+	}
+	paths := make(map[string]string)
+	for _, m := range mounts {
+		cgroup, err := m.GetOwnCgroup(procCgroups)
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(m.Root, cgroup)
+		if err != nil {
+			return nil, err
+		}
+		for _, ss := range m.Subsystems {
+			paths[ss] = filepath.Join(m.Mountpoint, rel)
+		}
+	}
+	return paths, nil
+}
+
+// absCgroupPaths returns the existing paths of the cgroup at cgroupsPath,
+// relative to the root of each hierarchy, keyed by subsystem.
+func absCgroupPaths(mounts []cgroups.Mount, cgroupsPath string) map[string]string {
+	paths := make(map[string]string)
+	for _, m := range mounts {
+		path := filepath.Join(m.Mountpoint, filepath.Clean("/"+cgroupsPath))
+		if !cgroups.PathExists(path) {
+			continue
+		}
+		for _, ss := range m.Subsystems {
+			paths[ss] = path
+		}
+	}
+	return paths
+}
+
+// findInitPid returns the process which was started first in the cgroups,
+// or 0 if they have no processes.
+func findInitPid(cgroupPaths map[string]string) (int, error) {
+	subsystems := make([]string, 0, len(cgroupPaths))
+	for ss := range cgroupPaths {
+		subsystems = append(subsystems, ss)
+	}
+	sort.Strings(subsystems)
+	for _, ss := range subsystems {
+		pids, err := cgroups.GetPids(cgroupPaths[ss])
+		if err != nil {
+			return 0, err
+		}
+		var (
+			initPid   int
+			initStart uint64
+		)
+		for _, pid := range pids {
+			stat, err := system.Stat(pid)
+			if err != nil {
+				// The process has exited in the meantime.
+				continue
+			}
+			if initPid == 0 || stat.StartTime < initStart || (stat.StartTime == initStart && pid < initPid) {
+				initPid, initStart = pid, stat.StartTime
+			}
+		}
+		if initPid != 0 {
+			return initPid, nil
+		}
+	}
+	return 0, nil
+}
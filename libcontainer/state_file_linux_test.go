@@ -0,0 +1,249 @@
+// +build linux
+
+package libcontainer
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/opencontainers/runc/libcontainer/cgroups"
+	"github.com/opencontainers/runc/libcontainer/configs"
+	"golang.org/x/sys/unix"
+)
+
+func TestWriteStateFile(t *testing.T) {
+	root, err := newTestRoot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	state := &State{BaseState: BaseState{ID: "1", InitProcessPid: 1024}}
+	if err := writeStateFile(root, state); err != nil {
+		t.Fatal(err)
+	}
+	files, err := ioutil.ReadDir(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Name() != stateFilename {
+		t.Fatalf("expected only %s to be left, got %v", stateFilename, files)
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(root, stateFilename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := decodeState(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.StateVersion != currentStateVersion {
+		t.Fatalf("expected state version %d, got %d", currentStateVersion, loaded.StateVersion)
+	}
+	if loaded.ID != "1" || loaded.InitProcessPid != 1024 {
+		t.Fatalf("unexpected state %+v", loaded.BaseState)
+	}
+}
+
+func TestDecodeStateMigratesRootless(t *testing.T) {
+	state, err := decodeState([]byte(`{"id":"1","config":{"rootfs":"/rootfs","rootless":true}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.StateVersion != currentStateVersion {
+		t.Fatalf("expected state version %d, got %d", currentStateVersion, state.StateVersion)
+	}
+	if !state.Config.RootlessEUID || !state.Config.RootlessCgroups {
+		t.Fatal("expected the rootless setting to be migrated")
+	}
+	if state.Config.Rootfs != "/rootfs" {
+		t.Fatalf("expected rootfs /rootfs, got %q", state.Config.Rootfs)
+	}
+}
+
+func TestDecodeStateInvalid(t *testing.T) {
+	for _, data := range []string{
+		``,
+		`{"id":"1","config":{`,
+		`null`,
+		`{"state_version":"1"}`,
+		`{"state_version":1000}`,
+	} {
+		if _, err := decodeState([]byte(data)); err == nil {
+			t.Errorf("expected an error decoding %q", data)
+		}
+	}
+}
+
+func TestFactoryLoadDamagedState(t *testing.T) {
+	root, err := newTestRoot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	if err := os.Mkdir(filepath.Join(root, "1"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(root, "1", stateFilename), []byte(`{"id":"1","conf`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	factory, err := New(root, Cgroupfs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = factory.Load("1")
+	expectErrorCode(t, err, ContainerStateDamaged)
+}
+
+// startInCgroup starts a process in the test cgroup at cgroupsPath of the
+// pids and freezer hierarchies, in new namespaces of the types in cloneflags.
+func startInCgroup(t *testing.T, cgroupsPath string, cloneflags uintptr) *exec.Cmd {
+	cmd := exec.Command("sleep", "100")
+	cmd.SysProcAttr = &syscall.SysProcAttr{Cloneflags: cloneflags}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	for _, ss := range []string{"pids", "freezer"} {
+		mountpoint, err := cgroups.FindCgroupMountpoint("", ss)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := cgroups.WriteCgroupProc(filepath.Join(mountpoint, cgroupsPath), cmd.Process.Pid); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return cmd
+}
+
+func TestFactoryRepairState(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("Test requires root.")
+	}
+	root, err := newTestRoot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	cgroupsPath := fmt.Sprintf("/libcontainer-test-repair-%d", os.Getpid())
+	for _, ss := range []string{"pids", "freezer"} {
+		mountpoint, err := cgroups.FindCgroupMountpoint("", ss)
+		if err != nil {
+			t.Skipf("no %s cgroup: %v", ss, err)
+		}
+		path := filepath.Join(mountpoint, cgroupsPath)
+		if err := os.Mkdir(path, 0755); err != nil {
+			t.Skipf("cannot create cgroup: %v", err)
+		}
+		defer os.Remove(path)
+	}
+	init := startInCgroup(t, cgroupsPath, unix.CLONE_NEWNS|unix.CLONE_NEWUTS)
+	defer init.Wait()
+	defer init.Process.Kill()
+	for _, id := range []string{"1", "2", "3", "4"} {
+		if err := os.Mkdir(filepath.Join(root, id), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(root, id, stateFilename), []byte(`{"id":"1","conf`), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	factory, err := New(root, Cgroupfs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	container, err := factory.RepairState("1", init.Process.Pid, cgroupsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, err := container.State()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.InitProcessPid != init.Process.Pid {
+		t.Fatalf("expected init pid %d, got %d", init.Process.Pid, state.InitProcessPid)
+	}
+	if _, ok := state.NamespacePaths[configs.NEWNS]; !ok {
+		t.Fatalf("expected the mount namespace of the init process, got %v", state.NamespacePaths)
+	}
+	status, err := container.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != Running {
+		t.Fatalf("expected status %s, got %s", Running, status)
+	}
+	// The repaired state is valid and can be loaded again.
+	if _, err := factory.Load("1"); err != nil {
+		t.Fatal(err)
+	}
+	_, err = factory.RepairState("1", init.Process.Pid, cgroupsPath)
+	expectErrorCode(t, err, IdInUse)
+
+	// Without a pid, the init process is found in the cgroup.
+	container, err = factory.RepairState("2", 0, cgroupsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state, err = container.State(); err != nil {
+		t.Fatal(err)
+	}
+	if state.InitProcessPid != init.Process.Pid {
+		t.Fatalf("expected init pid %d, got %d", init.Process.Pid, state.InitProcessPid)
+	}
+
+	// A process outside of the cgroup is refused.
+	if _, err := factory.RepairState("3", os.Getpid(), cgroupsPath); err == nil {
+		t.Fatal("expected a process outside of the cgroup to be refused")
+	}
+	if _, err := factory.RepairState("3", init.Process.Pid, ""); err == nil {
+		t.Fatal("expected a missing cgroup path to be refused")
+	}
+
+	// So is a process which shares all namespaces with runc.
+	host := startInCgroup(t, cgroupsPath, 0)
+	defer host.Wait()
+	defer host.Process.Kill()
+	if _, err := factory.RepairState("4", host.Process.Pid, cgroupsPath); err == nil {
+		t.Fatal("expected a process in the namespaces of runc to be refused")
+	}
+}
+
+func TestFactoryRepairStateStopped(t *testing.T) {
+	root, err := newTestRoot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	if err := os.Mkdir(filepath.Join(root, "1"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	factory, err := New(root, Cgroupfs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	container, err := factory.RepairState("1", 0, "/libcontainer-test-nonexistent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	status, err := container.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != Stopped {
+		t.Fatalf("expected status %s, got %s", Stopped, status)
+	}
+	if err := container.Destroy(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "1")); !os.IsNotExist(err) {
+		t.Fatalf("expected the state directory to be removed, got %v", err)
+	}
+}
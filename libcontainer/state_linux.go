@@ -43,15 +43,41 @@ func destroy(c *linuxContainer) error {
 			logrus.Warn(err)
 		}
 	}
+	leaked := &leakedResources{}
 	err := c.cgroupManager.Destroy()
+	if err != nil {
+		leaked.CgroupPaths = c.cgroupManager.GetPaths()
+	}
 	if c.intelRdtManager != nil {
-		if ierr := c.intelRdtManager.Destroy(); err == nil {
-			err = ierr
+		if ierr := c.intelRdtManager.Destroy(); ierr != nil {
+			leaked.IntelRdtPath = c.intelRdtManager.GetPath()
+			if err == nil {
+				err = ierr
+			}
 		}
 	}
+	if c.appArmorProfile != "" {
+		if aerr := releaseAppArmorProfile(filepath.Dir(c.root), c.appArmorProfile, c.id); aerr != nil {
+			leaked.AppArmorProfile = c.appArmorProfile
+			if err == nil {
+				err = aerr
+			}
+		}
+		c.appArmorProfile = ""
+	}
+	if oerr := cleanupRootfsOverlay(c.config); err == nil {
+		err = oerr
+	}
 	if rerr := os.RemoveAll(c.root); err == nil {
 		err = rerr
 	}
+	if len(leaked.CgroupPaths) > 0 || leaked.IntelRdtPath != "" || leaked.AppArmorProfile != "" {
+		// Let a later garbage collection retry the removal, as the state
+		// of the container is gone.
+		if lerr := recordLeakedResources(c.root, leaked); lerr != nil {
+			logrus.Warn(lerr)
+		}
+	}
 	c.initProcess = nil
 	if herr := runPoststopHooks(c); err == nil {
 		err = herr
@@ -67,7 +93,7 @@ func runPoststopHooks(c *linuxContainer) error {
 			return err
 		}
 		for _, hook := range c.config.Hooks.Poststop {
-			if err := hook.Run(s); err != nil {
+			if err := c.runHook(hook, s); err != nil {
 				return err
 			}
 		}
@@ -0,0 +1,167 @@
+// +build linux
+
+package libcontainer
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runtime-spec/specs-go"
+	"golang.org/x/sys/unix"
+)
+
+// StateLockMode is the mode in which LinuxFactory.Load and LinuxFactory.Create
+// lock the state directory of a container against other processes.
+type StateLockMode int
+
+const (
+	// NoStateLock leaves the state directory unlocked.
+	NoStateLock StateLockMode = iota
+	// SharedStateLock may be held by several processes at once, but not
+	// together with an ExclusiveStateLock. It is meant for operations which
+	// only read the state of the container.
+	SharedStateLock
+	// ExclusiveStateLock is held by a single process at a time. It is meant
+	// for operations which change the state of the container.
+	ExclusiveStateLock
+)
+
+// stateLockedEnv is set in the environment of hooks which run while the
+// state directory of their container is locked, to the path of that
+// directory. A runc invoked by such a hook does not wait for the lock held by
+// the process which waits for the hook.
+const stateLockedEnv = "_LIBCONTAINER_STATE_LOCKED"
+
+// The maximum delay between two attempts to take a state lock when waiting
+// with a timeout.
+const maxStateLockDelay = 100 * time.Millisecond
+
+// lockStateDir takes a lock of the given mode on the state directory dir of
+// the container id and returns the locked directory. A timeout of zero waits
+// for the lock indefinitely, a negative one does not wait at all.
+func lockStateDir(dir, id string, mode StateLockMode, timeout time.Duration) (*os.File, error) {
+	how := unix.LOCK_SH
+	if mode == ExclusiveStateLock {
+		how = unix.LOCK_EX
+	}
+	for {
+		f, err := os.Open(dir)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil, newGenericError(fmt.Errorf("container %q does not exist", id), ContainerNotExists)
+			}
+			return nil, newGenericError(err, SystemError)
+		}
+		if err := flock(f, how, timeout); err != nil {
+			f.Close()
+			if err == unix.EWOULDBLOCK {
+				if timeout < 0 {
+					return nil, newGenericError(fmt.Errorf("container %q is locked by another process", id), ContainerLocked)
+				}
+				return nil, newGenericError(fmt.Errorf("timed out after %s waiting for the state lock of container %q", timeout, id), ContainerLocked)
+			}
+			return nil, newSystemErrorWithCause(err, "locking container state")
+		}
+		// The process we waited for may have destroyed the container, or
+		// destroyed it and created a new one with the same id, in which case
+		// we hold the lock of a directory which is no longer used.
+		locked, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return nil, newGenericError(err, SystemError)
+		}
+		current, err := os.Stat(dir)
+		if err != nil {
+			f.Close()
+			if os.IsNotExist(err) {
+				return nil, newGenericError(fmt.Errorf("container %q does not exist", id), ContainerNotExists)
+			}
+			return nil, newGenericError(err, SystemError)
+		}
+		if os.SameFile(locked, current) {
+			return f, nil
+		}
+		f.Close()
+	}
+}
+
+// lockRoot locks the root directory of the factory, waiting indefinitely.
+// Create holds it shared while it creates and locks the state directory of a
+// new container, and the garbage collector exclusively while it locks a state
+// directory, so that it never sees a new state directory before it is locked.
+func lockRoot(root string, mode StateLockMode) (*os.File, error) {
+	how := unix.LOCK_SH
+	if mode == ExclusiveStateLock {
+		how = unix.LOCK_EX
+	}
+	f, err := os.Open(root)
+	if err != nil {
+		return nil, newGenericError(err, SystemError)
+	}
+	if err := flock(f, how, 0); err != nil {
+		f.Close()
+		return nil, newSystemErrorWithCause(err, "locking state root")
+	}
+	return f, nil
+}
+
+// flock locks f, retrying with an increasing delay until timeout has passed.
+// It returns EWOULDBLOCK if the lock could not be taken in time.
+func flock(f *os.File, how int, timeout time.Duration) error {
+	if timeout == 0 {
+		for {
+			if err := unix.Flock(int(f.Fd()), how); err != unix.EINTR {
+				return err
+			}
+		}
+	}
+	deadline := time.Now().Add(timeout)
+	delay := time.Millisecond
+	for {
+		err := unix.Flock(int(f.Fd()), how|unix.LOCK_NB)
+		if err != unix.EWOULDBLOCK {
+			return err
+		}
+		if time.Now().After(deadline) {
+			return err
+		}
+		time.Sleep(delay)
+		delay *= 2
+		if delay > maxStateLockDelay {
+			delay = maxStateLockDelay
+		}
+	}
+}
+
+// closeStateLock releases a lock taken by lockStateDir, if any.
+func closeStateLock(f *os.File) error {
+	if f == nil {
+		return nil
+	}
+	return f.Close()
+}
+
+// lockedByCaller reports whether the state directory dir is locked by the
+// process which runs us as a hook.
+func lockedByCaller(dir string) bool {
+	return os.Getenv(stateLockedEnv) == dir
+}
+
+// runHook runs hook with the state s. If the state directory of the container
+// is locked, command hooks are told so through their environment, so that
+// they can query the container with runc without waiting for the lock.
+func (c *linuxContainer) runHook(hook configs.Hook, s *specs.State) error {
+	ch, ok := hook.(configs.CommandHook)
+	if !ok || c.stateLock == nil {
+		return hook.Run(s)
+	}
+	env := ch.Env
+	if env == nil {
+		// The hook inherits our environment.
+		env = os.Environ()
+	}
+	ch.Env = append(append([]string(nil), env...), stateLockedEnv+"="+c.root)
+	return ch.Run(s)
+}
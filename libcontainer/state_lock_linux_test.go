@@ -0,0 +1,221 @@
+// +build linux
+
+package libcontainer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runtime-spec/specs-go"
+)
+
+func expectErrorCode(t *testing.T, err error, code ErrorCode) {
+	if err == nil {
+		t.Fatalf("expected error code %s but received no error", code)
+	}
+	lerr, ok := err.(Error)
+	if !ok {
+		t.Fatalf("expected libcontainer error type, got %v", err)
+	}
+	if lerr.Code() != code {
+		t.Fatalf("expected error code %s but received %s", code, lerr.Code())
+	}
+}
+
+func TestLockStateDirShared(t *testing.T) {
+	root, err := newTestRoot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	first, err := lockStateDir(root, "1", SharedStateLock, 10*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.Close()
+	second, err := lockStateDir(root, "1", SharedStateLock, 10*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.Close()
+
+	_, err = lockStateDir(root, "1", ExclusiveStateLock, 10*time.Millisecond)
+	expectErrorCode(t, err, ContainerLocked)
+}
+
+func TestLockStateDirExclusive(t *testing.T) {
+	root, err := newTestRoot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	locked, err := lockStateDir(root, "1", ExclusiveStateLock, 10*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = lockStateDir(root, "1", SharedStateLock, 10*time.Millisecond)
+	expectErrorCode(t, err, ContainerLocked)
+
+	// A waiter gets the lock once it is released.
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		locked.Close()
+	}()
+	f, err := lockStateDir(root, "1", SharedStateLock, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+}
+
+func TestLockStateDirRemoved(t *testing.T) {
+	root, err := newTestRoot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	dir := filepath.Join(root, "1")
+	if err := os.Mkdir(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	locked, err := lockStateDir(dir, "1", ExclusiveStateLock, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Destroy the container while another process waits for the lock.
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		os.RemoveAll(dir)
+		locked.Close()
+	}()
+	_, err = lockStateDir(dir, "1", ExclusiveStateLock, time.Second)
+	expectErrorCode(t, err, ContainerNotExists)
+}
+
+func TestFactoryLoadStateLock(t *testing.T) {
+	root, err := newTestRoot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	id := "1"
+	if err := os.Mkdir(filepath.Join(root, id), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := marshal(filepath.Join(root, id, stateFilename), &State{}); err != nil {
+		t.Fatal(err)
+	}
+	factory, err := New(root, Cgroupfs, StateLock(ExclusiveStateLock, 10*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	container, err := factory.Load(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = factory.Load(id)
+	expectErrorCode(t, err, ContainerLocked)
+
+	if err := container.ReleaseStateLock(); err != nil {
+		t.Fatal(err)
+	}
+	other, err := factory.Load(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = container.AcquireStateLock()
+	expectErrorCode(t, err, ContainerLocked)
+
+	if err := other.ReleaseStateLock(); err != nil {
+		t.Fatal(err)
+	}
+	if err := container.AcquireStateLock(); err != nil {
+		t.Fatal(err)
+	}
+	_, err = factory.Load(id)
+	expectErrorCode(t, err, ContainerLocked)
+	if err := container.ReleaseStateLock(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFactoryLoadLockedByCaller(t *testing.T) {
+	root, err := newTestRoot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	id := "1"
+	if err := os.Mkdir(filepath.Join(root, id), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := marshal(filepath.Join(root, id, stateFilename), &State{}); err != nil {
+		t.Fatal(err)
+	}
+	factory, err := New(root, Cgroupfs, StateLock(ExclusiveStateLock, 10*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	container, err := factory.Load(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer container.ReleaseStateLock()
+
+	// A hook run by the lock holder loads the container without waiting.
+	os.Setenv(stateLockedEnv, filepath.Join(root, id))
+	defer os.Unsetenv(stateLockedEnv)
+	hook, err := factory.Load(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := hook.AcquireStateLock(); err != nil {
+		t.Fatal(err)
+	}
+
+	os.Setenv(stateLockedEnv, filepath.Join(root, "2"))
+	_, err = factory.Load(id)
+	expectErrorCode(t, err, ContainerLocked)
+}
+
+func TestRunHookStateLocked(t *testing.T) {
+	root, err := newTestRoot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	out := filepath.Join(root, "env")
+	hook := configs.NewCommandHook(configs.Command{
+		Path: "/bin/sh",
+		Args: []string{"/bin/sh", "-c", "echo $" + stateLockedEnv + " > " + out},
+		Env:  []string{"PATH=/bin:/usr/bin"},
+	})
+
+	c := &linuxContainer{root: filepath.Join(root, "1")}
+	if err := c.runHook(hook, &specs.State{}); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := ioutil.ReadFile(out); err != nil || strings.TrimSpace(string(data)) != "" {
+		t.Errorf("expected an unlocked container not to be reported, got %q: %v", data, err)
+	}
+
+	c.stateLock = os.Stdin
+	if err := c.runHook(hook, &specs.State{}); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := ioutil.ReadFile(out); err != nil || strings.TrimSpace(string(data)) != c.root {
+		t.Errorf("expected the locked state directory %q, got %q: %v", c.root, data, err)
+	}
+	if len(hook.Env) != 1 {
+		t.Errorf("expected the hook not to be changed, got %v", hook.Env)
+	}
+}
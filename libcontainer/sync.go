@@ -26,12 +26,19 @@ type syncType string
 //
 // procReady   --> [final setup]
 //             <-- procRun
+//
+// procIdmapMounts --> [open idmapped mounts]
+//       [recv(fd)] <-- [send(fd)] (once per idmapped mount)
+//
+// procHelperDone --> [hotplug helper finished]
 const (
-	procError  syncType = "procError"
-	procReady  syncType = "procReady"
-	procRun    syncType = "procRun"
-	procHooks  syncType = "procHooks"
-	procResume syncType = "procResume"
+	procError       syncType = "procError"
+	procReady       syncType = "procReady"
+	procRun         syncType = "procRun"
+	procHooks       syncType = "procHooks"
+	procResume      syncType = "procResume"
+	procIdmapMounts syncType = "procIdmapMounts"
+	procHelperDone  syncType = "procHelperDone"
 )
 
 type syncT struct {
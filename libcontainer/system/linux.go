@@ -69,6 +69,15 @@ func SetParentDeathSignal(sig uintptr) error {
 	return nil
 }
 
+// SetLinuxPersonality sets the execution domain of the calling process.
+func SetLinuxPersonality(personality int) error {
+	_, _, err := unix.RawSyscall(unix.SYS_PERSONALITY, uintptr(personality), 0, 0)
+	if err != 0 {
+		return err
+	}
+	return nil
+}
+
 func GetParentDeathSignal() (ParentDeathSignal, error) {
 	var sig int
 	if err := unix.Prctl(unix.PR_GET_PDEATHSIG, uintptr(unsafe.Pointer(&sig)), 0, 0, 0); err != nil {
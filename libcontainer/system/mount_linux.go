@@ -0,0 +1,78 @@
+// +build linux
+
+package system
+
+import (
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// Flags for the new mount API (open_tree(2), move_mount(2) and
+// mount_setattr(2)), which are not yet provided by golang.org/x/sys/unix.
+const (
+	OPEN_TREE_CLONE   = 0x1
+	OPEN_TREE_CLOEXEC = unix.O_CLOEXEC
+
+	MOVE_MOUNT_F_EMPTY_PATH = 0x4
+
+	AT_EMPTY_PATH = 0x1000
+	AT_RECURSIVE  = 0x8000
+
+	MOUNT_ATTR_RDONLY = 0x1
+	MOUNT_ATTR_NOSUID = 0x2
+	MOUNT_ATTR_NODEV  = 0x4
+	MOUNT_ATTR_NOEXEC = 0x8
+	MOUNT_ATTR_IDMAP  = 0x100000
+)
+
+// MountAttr is the argument of mount_setattr(2).
+type MountAttr struct {
+	AttrSet     uint64
+	AttrClr     uint64
+	Propagation uint64
+	UsernsFd    uint64
+}
+
+// OpenTree wraps open_tree(2).
+func OpenTree(dirfd int, path string, flags uint) (int, error) {
+	p, err := unix.BytePtrFromString(path)
+	if err != nil {
+		return -1, err
+	}
+	fd, _, e1 := unix.Syscall(sysOpenTree, uintptr(dirfd), uintptr(unsafe.Pointer(p)), uintptr(flags))
+	if e1 != 0 {
+		return -1, e1
+	}
+	return int(fd), nil
+}
+
+// MoveMount wraps move_mount(2).
+func MoveMount(fromDirfd int, fromPath string, toDirfd int, toPath string, flags uint) error {
+	from, err := unix.BytePtrFromString(fromPath)
+	if err != nil {
+		return err
+	}
+	to, err := unix.BytePtrFromString(toPath)
+	if err != nil {
+		return err
+	}
+	_, _, e1 := unix.Syscall6(sysMoveMount, uintptr(fromDirfd), uintptr(unsafe.Pointer(from)), uintptr(toDirfd), uintptr(unsafe.Pointer(to)), uintptr(flags), 0)
+	if e1 != 0 {
+		return e1
+	}
+	return nil
+}
+
+// MountSetattr wraps mount_setattr(2).
+func MountSetattr(dirfd int, path string, flags uint, attr *MountAttr) error {
+	p, err := unix.BytePtrFromString(path)
+	if err != nil {
+		return err
+	}
+	_, _, e1 := unix.Syscall6(sysMountSetattr, uintptr(dirfd), uintptr(unsafe.Pointer(p)), uintptr(flags), uintptr(unsafe.Pointer(attr)), unsafe.Sizeof(*attr), 0)
+	if e1 != 0 {
+		return e1
+	}
+	return nil
+}
@@ -0,0 +1,12 @@
+// +build linux
+// +build !mips,!mipsle,!mips64,!mips64le
+
+package system
+
+// Syscall numbers of the new mount API. They are shared by all architectures
+// except mips, which offsets them by its ABI base.
+const (
+	sysOpenTree     = 428
+	sysMoveMount    = 429
+	sysMountSetattr = 442
+)
@@ -0,0 +1,11 @@
+// +build linux
+// +build mips64 mips64le
+
+package system
+
+// Syscall numbers of the new mount API for the mips n64 ABI.
+const (
+	sysOpenTree     = 5000 + 428
+	sysMoveMount    = 5000 + 429
+	sysMountSetattr = 5000 + 442
+)
@@ -0,0 +1,11 @@
+// +build linux
+// +build mips mipsle
+
+package system
+
+// Syscall numbers of the new mount API for the mips o32 ABI.
+const (
+	sysOpenTree     = 4000 + 428
+	sysMoveMount    = 4000 + 429
+	sysMountSetattr = 4000 + 442
+)
@@ -0,0 +1,87 @@
+// +build linux
+
+package system
+
+import (
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// Scheduling policies of sched_setattr(2).
+const (
+	SCHED_OTHER    = 0
+	SCHED_FIFO     = 1
+	SCHED_RR       = 2
+	SCHED_BATCH    = 3
+	SCHED_IDLE     = 5
+	SCHED_DEADLINE = 6
+)
+
+// Scheduling flags of sched_setattr(2).
+const (
+	SCHED_FLAG_RESET_ON_FORK = 0x01
+	SCHED_FLAG_RECLAIM       = 0x02
+	SCHED_FLAG_DL_OVERRUN    = 0x04
+	SCHED_FLAG_KEEP_POLICY   = 0x08
+	SCHED_FLAG_KEEP_PARAMS   = 0x10
+)
+
+// I/O scheduling classes of ioprio_set(2).
+const (
+	IOPRIO_CLASS_RT   = 1
+	IOPRIO_CLASS_BE   = 2
+	IOPRIO_CLASS_IDLE = 3
+)
+
+const (
+	ioprioClassShift = 13
+	ioprioWhoProcess = 1
+)
+
+// SchedAttr is the struct sched_attr of sched_setattr(2), without the
+// utilization clamping fields.
+type SchedAttr struct {
+	Size     uint32
+	Policy   uint32
+	Flags    uint64
+	Nice     int32
+	Priority uint32
+	Runtime  uint64
+	Deadline uint64
+	Period   uint64
+}
+
+// SchedSetattr sets the scheduling policy and attributes of the thread tid,
+// or of the calling thread if tid is 0.
+func SchedSetattr(tid int, attr *SchedAttr) error {
+	attr.Size = uint32(unsafe.Sizeof(*attr))
+	_, _, err := unix.RawSyscall(unix.SYS_SCHED_SETATTR, uintptr(tid), uintptr(unsafe.Pointer(attr)), 0)
+	if err != 0 {
+		return err
+	}
+	return nil
+}
+
+// IoprioSet sets the I/O scheduling class and priority of the thread tid, or
+// of the calling thread if tid is 0.
+func IoprioSet(tid, class, priority int) error {
+	_, _, err := unix.RawSyscall(unix.SYS_IOPRIO_SET, ioprioWhoProcess, uintptr(tid), uintptr(class<<ioprioClassShift|priority))
+	if err != 0 {
+		return err
+	}
+	return nil
+}
+
+// SchedSetaffinity sets the CPU affinity of the thread tid, or of the calling
+// thread if tid is 0, to the CPUs whose bits are set in mask.
+func SchedSetaffinity(tid int, mask []uint64) error {
+	if len(mask) == 0 {
+		return unix.EINVAL
+	}
+	_, _, err := unix.RawSyscall(unix.SYS_SCHED_SETAFFINITY, uintptr(tid), uintptr(len(mask)*8), uintptr(unsafe.Pointer(&mask[0])))
+	if err != 0 {
+		return err
+	}
+	return nil
+}
@@ -16,6 +16,7 @@ import (
 	"github.com/opencontainers/runc/libcontainer"
 	"github.com/opencontainers/runc/libcontainer/user"
 	"github.com/opencontainers/runc/libcontainer/utils"
+	"github.com/opencontainers/runtime-spec/specs-go"
 	"github.com/urfave/cli"
 )
 
@@ -42,6 +43,9 @@ type containerState struct {
 	Annotations map[string]string `json:"annotations,omitempty"`
 	// The owner of the state directory (the owner of the container).
 	Owner string `json:"owner"`
+	// Mounts are the mounts of the container, including those added with
+	// runc mount. It is only set by runc state.
+	Mounts []specs.Mount `json:"mounts,omitempty"`
 }
 
 var listCommand = cli.Command{
@@ -145,10 +149,14 @@ func getContainers(context *cli.Context) ([]containerState, error) {
 			}
 			containerStatus, err := container.Status()
 			if err != nil {
+				container.ReleaseStateLock()
 				fmt.Fprintf(os.Stderr, "status for %s: %v\n", item.Name(), err)
 				continue
 			}
 			state, err := container.State()
+			// Don't keep other runc processes waiting for the rest of
+			// the containers to be listed.
+			container.ReleaseStateLock()
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "state for %s: %v\n", item.Name(), err)
 				continue
@@ -103,6 +103,10 @@ func main() {
 			Value: "criu",
 			Usage: "path to the criu binary used for checkpoint and restore",
 		},
+		cli.DurationFlag{
+			Name:  "lock-timeout",
+			Usage: "maximum time to wait for other runc processes operating on the same container (default: no limit)",
+		},
 		cli.BoolFlag{
 			Name:  "systemd-cgroup",
 			Usage: "enable systemd cgroup support, expects cgroupsPath to be of form \"slice:prefix:name\" for e.g. \"system.slice:runc:434234\"",
@@ -117,11 +121,16 @@ func main() {
 		checkpointCommand,
 		createCommand,
 		deleteCommand,
+		deviceCommand,
 		eventsCommand,
 		execCommand,
+		featuresCommand,
+		gcCommand,
 		initCommand,
 		killCommand,
 		listCommand,
+		mountCommand,
+		notifyProxyCommand,
 		pauseCommand,
 		psCommand,
 		restoreCommand,
@@ -130,7 +139,9 @@ func main() {
 		specCommand,
 		startCommand,
 		stateCommand,
+		umountCommand,
 		updateCommand,
+		validateCommand,
 	}
 	app.Before = func(context *cli.Context) error {
 		if context.GlobalBool("debug") {
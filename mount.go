@@ -0,0 +1,109 @@
+// +build linux
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/opencontainers/runc/libcontainer/specconv"
+	"github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/urfave/cli"
+)
+
+var mountCommand = cli.Command{
+	Name:  "mount",
+	Usage: "bind mount a path into a running container",
+	ArgsUsage: `<container-id>
+
+Where "<container-id>" is the name for the instance of the container.`,
+	Description: `The mount command bind mounts --source at --dest inside the mount namespace
+of a running or created container, and records the mount in the container's
+state. Only bind mounts are supported. If --options contains neither "bind"
+nor "rbind", the source is mounted recursively.
+
+EXAMPLE:
+
+       # runc mount --source /srv/data --dest /data --options ro,rbind mycontainer`,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "source",
+			Usage: "path on the host to mount into the container",
+		},
+		cli.StringFlag{
+			Name:  "dest",
+			Usage: "absolute path inside the container to mount the source at",
+		},
+		cli.StringFlag{
+			Name:  "options",
+			Usage: "comma separated list of mount options, as used in config.json",
+		},
+	},
+	Action: func(context *cli.Context) error {
+		if err := checkArgs(context, 1, exactArgs); err != nil {
+			return err
+		}
+		source, dest := context.String("source"), context.String("dest")
+		if source == "" || dest == "" {
+			return fmt.Errorf("both --source and --dest must be specified")
+		}
+		if !filepath.IsAbs(dest) {
+			return fmt.Errorf("mount destination %q is not an absolute path", dest)
+		}
+		var options []string
+		if opts := context.String("options"); opts != "" {
+			options = strings.Split(opts, ",")
+		}
+		if !hasBindOption(options) {
+			options = append(options, "rbind")
+		}
+		cwd, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		container, err := getContainer(context)
+		if err != nil {
+			return err
+		}
+		m := specconv.CreateLibcontainerMount(cwd, specs.Mount{
+			Destination: dest,
+			Type:        "bind",
+			Source:      source,
+			Options:     options,
+		})
+		return container.AddMount(m)
+	},
+}
+
+var umountCommand = cli.Command{
+	Name:  "umount",
+	Usage: "remove a mount from a running container",
+	ArgsUsage: `<container-id> <destination>
+
+Where "<container-id>" is the name for the instance of the container and
+"<destination>" is the path inside the container of the mount to remove.`,
+	Description: `The umount command detaches the mount at <destination> inside the mount
+namespace of a running or created container, and removes it from the
+container's state.`,
+	Action: func(context *cli.Context) error {
+		if err := checkArgs(context, 2, exactArgs); err != nil {
+			return err
+		}
+		container, err := getContainer(context)
+		if err != nil {
+			return err
+		}
+		return container.RemoveMount(context.Args().Get(1))
+	},
+}
+
+func hasBindOption(options []string) bool {
+	for _, o := range options {
+		if o == "bind" || o == "rbind" {
+			return true
+		}
+	}
+	return false
+}
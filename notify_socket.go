@@ -6,12 +6,19 @@ import (
 	"bytes"
 	"fmt"
 	"net"
+	"os"
+	"os/exec"
 	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
 
+	"github.com/opencontainers/runc/libcontainer/system"
 	"github.com/opencontainers/runtime-spec/specs-go"
 
 	"github.com/sirupsen/logrus"
 	"github.com/urfave/cli"
+	"golang.org/x/sys/unix"
 )
 
 type notifySocket struct {
@@ -64,46 +71,245 @@ func (s *notifySocket) setupSocket() error {
 	return nil
 }
 
-// pid1 must be set only with -d, as it is used to set the new process as the main process
-// for the service in systemd
+// The size of the buffer used to receive the ancillary data of a message.
+// It is large enough for the maximum number of fds the kernel passes in a
+// single message (SCM_MAX_FD) and the sender's credentials.
+var notifyOobSize = unix.CmsgSpace(253*4) + unix.CmsgSpace(unix.SizeofUcred)
+
+// run relays sd_notify messages, including any file descriptors passed with
+// them, from the container to the host's notify socket.
+//
+// pid1 must be set only with -d, as it is used to set the new process as the
+// main process for the service in systemd. As runc exits once the container
+// is ready in that case, the rest of the container's messages are relayed by
+// a runc notify-proxy process which is started once READY=1 is received.
 func (s *notifySocket) run(pid1 int) {
-	buf := make([]byte, 512)
-	notifySocketHostAddr := net.UnixAddr{Name: s.host, Net: "unixgram"}
-	client, err := net.DialUnix("unixgram", nil, &notifySocketHostAddr)
+	client, err := s.dialHost()
 	if err != nil {
 		logrus.Error(err)
 		return
 	}
+	defer client.Close()
+	if err := s.setPassCred(); err != nil {
+		logrus.Warnf("unable to pass credentials of notify messages: %v", err)
+	}
 	for {
-		r, err := s.socket.Read(buf)
+		ready, err := s.forward(client, pid1)
 		if err != nil {
-			break
+			return
 		}
-		var out bytes.Buffer
-		for _, line := range bytes.Split(buf[0:r], []byte{'\n'}) {
-			if bytes.HasPrefix(line, []byte("READY=")) {
-				_, err = out.Write(line)
-				if err != nil {
-					return
-				}
+		if ready && pid1 > 0 {
+			if err := s.startProxy(pid1); err != nil {
+				logrus.Errorf("unable to start notify proxy: %v", err)
+			}
+			return
+		}
+	}
+}
 
-				_, err = out.Write([]byte{'\n'})
-				if err != nil {
-					return
+// relayUntilExit relays sd_notify messages like run, until the process pid1,
+// which was started at startTime, exits.
+func (s *notifySocket) relayUntilExit(pid1 int, startTime uint64) error {
+	client, err := s.dialHost()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	for {
+		if err := s.socket.SetReadDeadline(time.Now().Add(time.Second)); err != nil {
+			return err
+		}
+		if _, err := s.forward(client, pid1); err != nil {
+			if nerr, ok := err.(net.Error); ok && nerr.Timeout() {
+				if processExited(pid1, startTime) {
+					return nil
 				}
+				continue
+			}
+			return err
+		}
+	}
+}
 
-				_, err = client.Write(out.Bytes())
-				if err != nil {
-					return
-				}
+// processExited returns whether the process pid, which was started at
+// startTime, has exited. The start time is checked in case the pid has been
+// reused by another process.
+func processExited(pid int, startTime uint64) bool {
+	stat, err := system.Stat(pid)
+	if err != nil {
+		return true
+	}
+	return stat.StartTime != startTime || stat.State == system.Zombie || stat.State == system.Dead
+}
 
-				// now we can inform systemd to use pid1 as the pid to monitor
-				if pid1 > 0 {
-					newPid := fmt.Sprintf("MAINPID=%d\n", pid1)
-					client.Write([]byte(newPid))
-				}
-				return
+func (s *notifySocket) dialHost() (*net.UnixConn, error) {
+	notifySocketHostAddr := net.UnixAddr{Name: s.host, Net: "unixgram"}
+	return net.DialUnix("unixgram", nil, &notifySocketHostAddr)
+}
+
+// setPassCred makes the kernel attach the credentials of the sender to the
+// messages received from the container, so that they can be passed on.
+func (s *notifySocket) setPassCred() error {
+	raw, err := s.socket.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var serr error
+	if err := raw.Control(func(fd uintptr) {
+		serr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_PASSCRED, 1)
+	}); err != nil {
+		return err
+	}
+	return serr
+}
+
+// forward reads a single message from the container and sends it on to
+// client, together with the file descriptors and credentials it carried.
+// MAINPID is dropped, as a pid from inside the container means nothing to
+// the host; if pid1 is set, it is sent as MAINPID after READY=1 instead. It
+// returns whether the message contained READY=1.
+func (s *notifySocket) forward(client *net.UnixConn, pid1 int) (bool, error) {
+	buf := make([]byte, 4096)
+	oob := make([]byte, notifyOobSize)
+	n, oobn, flags, _, err := s.socket.ReadMsgUnix(buf, oob)
+	if err != nil {
+		return false, err
+	}
+	if flags&unix.MSG_TRUNC != 0 {
+		logrus.Warnf("notify message from the container was truncated to %d bytes", n)
+	}
+	if flags&unix.MSG_CTRUNC != 0 {
+		logrus.Warn("file descriptors or credentials of a notify message from the container were lost, its ancillary data was truncated")
+	}
+	var (
+		fds  []int
+		cred *unix.Ucred
+	)
+	msgs, err := unix.ParseSocketControlMessage(oob[:oobn])
+	if err != nil {
+		return false, err
+	}
+	for i := range msgs {
+		switch msgs[i].Header.Type {
+		case unix.SCM_RIGHTS:
+			rights, err := unix.ParseUnixRights(&msgs[i])
+			if err != nil {
+				return false, err
 			}
+			fds = append(fds, rights...)
+		case unix.SCM_CREDENTIALS:
+			if cred, err = unix.ParseUnixCredentials(&msgs[i]); err != nil {
+				return false, err
+			}
+		}
+	}
+	defer func() {
+		for _, fd := range fds {
+			unix.Close(fd)
+		}
+	}()
+
+	var (
+		out   bytes.Buffer
+		ready bool
+	)
+	for _, line := range bytes.Split(buf[:n], []byte{'\n'}) {
+		if len(line) == 0 || bytes.HasPrefix(line, []byte("MAINPID=")) {
+			continue
+		}
+		if bytes.Equal(line, []byte("READY=1")) {
+			ready = true
+		}
+		out.Write(line)
+		out.WriteByte('\n')
+	}
+	if out.Len() > 0 || len(fds) > 0 {
+		if err := sendNotify(client, out.Bytes(), fds, cred); err != nil {
+			logrus.Warnf("unable to forward notify message: %v", err)
+		}
+	}
+	// now we can inform systemd to use pid1 as the pid to monitor
+	if ready && pid1 > 0 {
+		newPid := fmt.Sprintf("MAINPID=%d\n", pid1)
+		if err := sendNotify(client, []byte(newPid), nil, nil); err != nil {
+			logrus.Warnf("unable to forward notify message: %v", err)
+		}
+	}
+	return ready, nil
+}
+
+// sendNotify sends msg with fds to client. The credentials of the original
+// sender are passed on if given, so that NotifyAccess=main keeps working
+// once systemd monitors the container's pid. Doing so requires CAP_SYS_ADMIN,
+// so the message is sent with runc's own credentials if it is refused.
+func sendNotify(client *net.UnixConn, msg []byte, fds []int, cred *unix.Ucred) error {
+	var oob []byte
+	if len(fds) > 0 {
+		oob = unix.UnixRights(fds...)
+	}
+	if cred != nil {
+		if _, _, err := client.WriteMsgUnix(msg, append(oob, unix.UnixCredentials(cred)...), nil); err == nil {
+			return nil
 		}
 	}
+	_, _, err := client.WriteMsgUnix(msg, oob, nil)
+	return err
+}
+
+// startProxy starts a runc notify-proxy process which keeps relaying the
+// container's messages after runc has exited.
+func (s *notifySocket) startProxy(pid1 int) error {
+	stat, err := system.Stat(pid1)
+	if err != nil {
+		return err
+	}
+	f, err := s.socket.File()
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	cmd := exec.Command("/proc/self/exe", "notify-proxy", "--host", s.host, "--pid", strconv.Itoa(pid1), "--start-time", strconv.FormatUint(stat.StartTime, 10))
+	cmd.ExtraFiles = []*os.File{f}
+	cmd.Stderr = os.Stderr
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	return cmd.Process.Release()
+}
+
+var notifyProxyCommand = cli.Command{
+	Name:   "notify-proxy",
+	Usage:  "relay sd_notify messages of a detached container (do not call it outside of runc)",
+	Hidden: true,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "host",
+			Usage: "path of the host's notify socket",
+		},
+		cli.IntFlag{
+			Name:  "pid",
+			Usage: "pid of the container's init process",
+		},
+		cli.Uint64Flag{
+			Name:  "start-time",
+			Usage: "start time of the container's init process",
+		},
+	},
+	Action: func(context *cli.Context) error {
+		conn, err := net.FileConn(os.NewFile(3, "notify.sock"))
+		if err != nil {
+			return err
+		}
+		socket, ok := conn.(*net.UnixConn)
+		if !ok {
+			return fmt.Errorf("notify socket is not a unix socket")
+		}
+		s := &notifySocket{
+			socket: socket,
+			host:   context.String("host"),
+		}
+		defer s.Close()
+		return s.relayUntilExit(context.Int("pid"), context.Uint64("start-time"))
+	},
 }
@@ -0,0 +1,220 @@
+// +build linux
+
+package main
+
+import (
+	"net"
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/opencontainers/runc/libcontainer/system"
+	"golang.org/x/sys/unix"
+)
+
+// unixgramPair returns the two ends of a connected datagram socket pair.
+func unixgramPair(t *testing.T) (*net.UnixConn, *net.UnixConn) {
+	fds, err := unix.Socketpair(unix.AF_UNIX, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conns := make([]*net.UnixConn, 2)
+	for i, fd := range fds {
+		f := os.NewFile(uintptr(fd), "socketpair")
+		c, err := net.FileConn(f)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		conns[i] = c.(*net.UnixConn)
+	}
+	return conns[0], conns[1]
+}
+
+// readNotify reads a message and the fds passed with it from conn.
+func readNotify(t *testing.T, conn *net.UnixConn) (string, []int, *unix.Ucred) {
+	buf := make([]byte, 4096)
+	oob := make([]byte, notifyOobSize)
+	n, oobn, _, _, err := conn.ReadMsgUnix(buf, oob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgs, err := unix.ParseSocketControlMessage(oob[:oobn])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		fds  []int
+		cred *unix.Ucred
+	)
+	for i := range msgs {
+		switch msgs[i].Header.Type {
+		case unix.SCM_RIGHTS:
+			rights, err := unix.ParseUnixRights(&msgs[i])
+			if err != nil {
+				t.Fatal(err)
+			}
+			fds = append(fds, rights...)
+		case unix.SCM_CREDENTIALS:
+			if cred, err = unix.ParseUnixCredentials(&msgs[i]); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	return string(buf[:n]), fds, cred
+}
+
+func TestNotifyForwardReady(t *testing.T) {
+	container, socket := unixgramPair(t)
+	defer container.Close()
+	defer socket.Close()
+	client, host := unixgramPair(t)
+	defer client.Close()
+	defer host.Close()
+
+	if _, err := container.Write([]byte("READY=1\nMAINPID=42\nSTATUS=running\n")); err != nil {
+		t.Fatal(err)
+	}
+	s := &notifySocket{socket: socket}
+	ready, err := s.forward(client, 1234)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ready {
+		t.Error("expected READY=1 to be reported")
+	}
+	if msg, _, _ := readNotify(t, host); msg != "READY=1\nSTATUS=running\n" {
+		t.Errorf("expected MAINPID of the container to be dropped, got %q", msg)
+	}
+	if msg, _, _ := readNotify(t, host); msg != "MAINPID=1234\n" {
+		t.Errorf("expected MAINPID to be set to pid1, got %q", msg)
+	}
+}
+
+func TestNotifyForwardFds(t *testing.T) {
+	container, socket := unixgramPair(t)
+	defer container.Close()
+	defer socket.Close()
+	client, host := unixgramPair(t)
+	defer client.Close()
+	defer host.Close()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if _, _, err := container.WriteMsgUnix([]byte("FDSTORE=1\n"), unix.UnixRights(int(w.Fd())), nil); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	s := &notifySocket{socket: socket}
+	ready, err := s.forward(client, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ready {
+		t.Error("expected READY=1 not to be reported")
+	}
+	msg, fds, _ := readNotify(t, host)
+	if msg != "FDSTORE=1\n" {
+		t.Errorf("expected the message to be forwarded, got %q", msg)
+	}
+	if len(fds) != 1 {
+		t.Fatalf("expected 1 fd to be forwarded, got %d", len(fds))
+	}
+	// The forwarded fd must be the write end of the pipe.
+	fw := os.NewFile(uintptr(fds[0]), "pipe")
+	if _, err := fw.Write([]byte("x")); err != nil {
+		t.Fatal(err)
+	}
+	fw.Close()
+	buf := make([]byte, 1)
+	if _, err := r.Read(buf); err != nil || buf[0] != 'x' {
+		t.Errorf("expected to read from the forwarded pipe, got %q: %v", buf, err)
+	}
+}
+
+func TestNotifyForwardTruncatedFds(t *testing.T) {
+	container, socket := unixgramPair(t)
+	defer container.Close()
+	defer socket.Close()
+	client, host := unixgramPair(t)
+	defer client.Close()
+	defer host.Close()
+
+	// Room for two of the three fds sent.
+	oldSize := notifyOobSize
+	notifyOobSize = unix.CmsgSpace(2 * 4)
+	defer func() { notifyOobSize = oldSize }()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+	if _, _, err := container.WriteMsgUnix([]byte("FDSTORE=1\n"), unix.UnixRights(int(r.Fd()), int(w.Fd()), int(r.Fd())), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &notifySocket{socket: socket}
+	if _, err := s.forward(client, 0); err != nil {
+		t.Fatal(err)
+	}
+	notifyOobSize = oldSize
+	msg, fds, _ := readNotify(t, host)
+	if msg != "FDSTORE=1\n" {
+		t.Errorf("expected the message to be forwarded, got %q", msg)
+	}
+	if len(fds) != 2 {
+		t.Errorf("expected only the 2 fds which fit to be forwarded, got %d", len(fds))
+	}
+	for _, fd := range fds {
+		unix.Close(fd)
+	}
+}
+
+func TestSendNotifyCredentials(t *testing.T) {
+	client, host := unixgramPair(t)
+	defer client.Close()
+	defer host.Close()
+	s := &notifySocket{socket: host}
+	if err := s.setPassCred(); err != nil {
+		t.Fatal(err)
+	}
+
+	cred := &unix.Ucred{Pid: int32(os.Getpid()), Uid: uint32(os.Getuid()), Gid: uint32(os.Getgid())}
+	if err := sendNotify(client, []byte("READY=1\n"), nil, cred); err != nil {
+		t.Fatal(err)
+	}
+	msg, fds, got := readNotify(t, host)
+	if msg != "READY=1\n" || len(fds) != 0 {
+		t.Errorf("expected the message without fds, got %q and %d fds", msg, len(fds))
+	}
+	if got == nil || got.Pid != cred.Pid {
+		t.Errorf("expected the credentials of pid %d, got %+v", cred.Pid, got)
+	}
+}
+
+func TestProcessExited(t *testing.T) {
+	stat, err := system.Stat(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if processExited(os.Getpid(), stat.StartTime) {
+		t.Error("expected the current process not to have exited")
+	}
+	if !processExited(os.Getpid(), stat.StartTime+1) {
+		t.Error("expected a reused pid to be reported as exited")
+	}
+
+	cmd := exec.Command("true")
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if !processExited(cmd.Process.Pid, 0) {
+		t.Error("expected the reaped process to have exited")
+	}
+}
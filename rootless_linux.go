@@ -20,8 +20,10 @@ func shouldUseRootlessCgroupManager(context *cli.Context) (bool, error) {
 			return *b, nil
 		}
 
+		// With systemd, the user's own instance manages the cgroups of
+		// a rootless container.
 		if context.GlobalBool("systemd-cgroup") {
-			return false, nil
+			return os.Geteuid() != 0, nil
 		}
 	}
 	if os.Geteuid() != 0 {
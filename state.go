@@ -8,6 +8,7 @@ import (
 
 	"github.com/opencontainers/runc/libcontainer"
 	"github.com/opencontainers/runc/libcontainer/utils"
+	"github.com/opencontainers/runtime-spec/specs-go"
 	"github.com/urfave/cli"
 )
 
@@ -18,12 +19,39 @@ var stateCommand = cli.Command{
 
 Where "<container-id>" is your name for the instance of the container.`,
 	Description: `The state command outputs current state information for the
-instance of a container.`,
+instance of a container.
+
+If the state of the container is missing or damaged, --repair rebuilds a
+minimal state from the container's cgroup given by --cgroup, so that the
+container can be killed and deleted. The init process is the process given by
+--pid, which must be in that cgroup, or the first process started in it.`,
+	Flags: []cli.Flag{
+		cli.BoolFlag{
+			Name:  "repair",
+			Usage: "rebuild the state of the container if it is missing or damaged",
+		},
+		cli.IntFlag{
+			Name:  "pid",
+			Usage: "pid of the container's init process, used with --repair",
+		},
+		cli.StringFlag{
+			Name:  "cgroup",
+			Usage: "cgroups path of the container, required with --repair",
+		},
+	},
 	Action: func(context *cli.Context) error {
 		if err := checkArgs(context, 1, exactArgs); err != nil {
 			return err
 		}
-		container, err := getContainer(context)
+		var (
+			container libcontainer.Container
+			err       error
+		)
+		if context.Bool("repair") {
+			container, err = repairContainer(context)
+		} else {
+			container, err = getContainer(context)
+		}
 		if err != nil {
 			return err
 		}
@@ -50,6 +78,13 @@ instance of a container.`,
 			Created:        state.BaseState.Created,
 			Annotations:    annotations,
 		}
+		for _, m := range state.BaseState.Config.Mounts {
+			cs.Mounts = append(cs.Mounts, specs.Mount{
+				Destination: m.Destination,
+				Type:        m.Device,
+				Source:      m.Source,
+			})
+		}
 		data, err := json.MarshalIndent(cs, "", "  ")
 		if err != nil {
 			return err
@@ -58,3 +93,13 @@ instance of a container.`,
 		return nil
 	},
 }
+
+// repairContainer rebuilds the state of the specified container from its init
+// process or cgroup.
+func repairContainer(context *cli.Context) (libcontainer.Container, error) {
+	factory, err := loadFactory(context)
+	if err != nil {
+		return nil, err
+	}
+	return factory.RepairState(context.Args().First(), context.Int("pid"), context.String("cgroup"))
+}
@@ -19,3 +19,28 @@ function teardown() {
 	[ "$status" -eq 0 ]
 	[[ "${lines[0]}" =~ '/tmp/bind/config.json' ]]
 }
+
+@test "runc mount and umount [hotplug bind mount]" {
+	requires root
+
+	runc run -d --console-socket $CONSOLE_SOCKET test_hotplug_mount
+	[ "$status" -eq 0 ]
+
+	testcontainer test_hotplug_mount running
+
+	runc mount --source . --dest /tmp/hotplug --options ro test_hotplug_mount
+	[ "$status" -eq 0 ]
+
+	runc exec test_hotplug_mount ls /tmp/hotplug/config.json
+	[ "$status" -eq 0 ]
+
+	runc state test_hotplug_mount
+	[ "$status" -eq 0 ]
+	[[ "${output}" == *"/tmp/hotplug"* ]]
+
+	runc umount test_hotplug_mount /tmp/hotplug
+	[ "$status" -eq 0 ]
+
+	runc exec test_hotplug_mount ls /tmp/hotplug/config.json
+	[ "$status" -ne 0 ]
+}
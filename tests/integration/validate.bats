@@ -0,0 +1,63 @@
+#!/usr/bin/env bats
+
+load helpers
+
+function setup() {
+	teardown_busybox
+	setup_busybox
+}
+
+function teardown() {
+	teardown_busybox
+}
+
+# validate_json runs runc validate and saves the problems it reports.
+function validate_json() {
+	__runc validate --format json "$@" 2>/dev/null >"$BATS_TMPDIR/problems.json" || true
+}
+
+@test "runc validate" {
+	runc validate
+	[ "$status" -eq 0 ]
+	[[ "${lines[0]}" =~ SEVERITY\ +PATH\ +MESSAGE ]]
+}
+
+@test "runc validate --bundle" {
+	cd "$INTEGRATION_ROOT"
+	runc validate --bundle "$BUSYBOX_BUNDLE" --format json
+	[ "$status" -eq 0 ]
+}
+
+@test "runc validate [all problems with their paths]" {
+	n=$(jq '.mounts | length' config.json)
+	CONFIG=$(jq '.mounts |= . + [{"source": "/nonexistent", "destination": "/tmp/bind", "options": ["bind"]}] | .linux.sysctl = {"kernel.panic": "10", "vm.swappiness": "10"} | .process.args = []' config.json)
+	echo "${CONFIG}" >config.json
+
+	runc validate
+	[ "$status" -ne 0 ]
+	[[ "${output}" == *"config.json has 4 error(s)"* ]]
+
+	validate_json
+	run jq -r '.[] | select(.severity == "error") | .path' "$BATS_TMPDIR/problems.json"
+	[ "$status" -eq 0 ]
+	[[ "${output}" == *'process.args'* ]]
+	[[ "${output}" == *'linux.sysctl["kernel.panic"]'* ]]
+	[[ "${output}" == *'linux.sysctl["vm.swappiness"]'* ]]
+	[[ "${output}" == *"mounts[$n].source"* ]]
+}
+
+@test "runc validate [invalid annotation]" {
+	CONFIG=$(jq '.annotations = {"org.opencontainers.runc.personality": "{\"domain\": \"LINUX64\"}"} | .mounts |= . + [{"source": "/nonexistent", "destination": "/tmp/bind", "options": ["bind"]}]' config.json)
+	echo "${CONFIG}" >config.json
+
+	runc validate
+	[ "$status" -ne 0 ]
+	[[ "${output}" == *"config.json has 2 error(s)"* ]]
+
+	# The checks of the host are still done.
+	validate_json
+	run jq -r '.[] | select(.severity == "error") | .message' "$BATS_TMPDIR/problems.json"
+	[ "$status" -eq 0 ]
+	[[ "${output}" == *"org.opencontainers.runc.personality"* ]]
+	[[ "${output}" == *"/nonexistent"* ]]
+}
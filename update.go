@@ -54,7 +54,8 @@ The accepted format is as follow (unchanged values can be omitted):
 }
 
 Note: if data is to be read from a file or the standard input, all
-other options are ignored.
+other options are ignored. The --memory-high, --memory-low, --memory-min
+and --memory-oom-group options can only be given on the command line.
 `,
 		},
 
@@ -110,6 +111,22 @@ other options are ignored.
 			Name:  "memory-swap",
 			Usage: "Total memory usage (memory + swap); set '-1' to enable unlimited swap",
 		},
+		cli.StringFlag{
+			Name:  "memory-high",
+			Usage: "Memory usage throttle limit (in bytes); set '-1' to remove the limit",
+		},
+		cli.StringFlag{
+			Name:  "memory-low",
+			Usage: "Best-effort memory protection (in bytes); set '-1' to protect all memory",
+		},
+		cli.StringFlag{
+			Name:  "memory-min",
+			Usage: "Hard memory protection (in bytes); set '-1' to protect all memory",
+		},
+		cli.StringFlag{
+			Name:  "memory-oom-group",
+			Usage: "Whether the OOM killer kills all processes of the container at once (true or false)",
+		},
 		cli.IntFlag{
 			Name:  "pids-limit",
 			Usage: "Maximum number of pids allowed in the container",
@@ -264,6 +281,34 @@ other options are ignored.
 		config.Cgroups.Resources.MemorySwap = *r.Memory.Swap
 		config.Cgroups.Resources.PidsLimit = r.Pids.Limit
 
+		// Update the memory controls without a field in the runtime spec,
+		// which are left unchanged unless given
+		for _, pair := range []struct {
+			opt  string
+			dest *int64
+		}{
+			{"memory-high", &config.Cgroups.Resources.MemoryHigh},
+			{"memory-low", &config.Cgroups.Resources.MemoryLow},
+			{"memory-min", &config.Cgroups.Resources.MemoryMin},
+		} {
+			if val := context.String(pair.opt); val != "" {
+				v := int64(-1)
+				if val != "-1" && val != "max" {
+					v, err = units.RAMInBytes(val)
+					if err != nil {
+						return fmt.Errorf("invalid value for %s: %s", pair.opt, err)
+					}
+				}
+				*pair.dest = v
+			}
+		}
+		if val := context.String("memory-oom-group"); val != "" {
+			config.Cgroups.Resources.MemoryOomGroup, err = strconv.ParseBool(val)
+			if err != nil {
+				return fmt.Errorf("invalid value for memory-oom-group: %s", err)
+			}
+		}
+
 		// Update Intel RDT
 		l3CacheSchema := context.String("l3-cache-schema")
 		memBwSchema := context.String("mem-bw-schema")
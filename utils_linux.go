@@ -46,7 +46,12 @@ func loadFactory(context *cli.Context) (libcontainer.Factory, error) {
 		cgroupManager = libcontainer.RootlessCgroupfs
 	}
 	if context.GlobalBool("systemd-cgroup") {
-		if systemd.UseSystemd() {
+		if rootlessCg {
+			if !systemd.UseSystemdUser() {
+				return nil, fmt.Errorf("systemd cgroup flag passed, but the systemd user instance is not available for managing rootless cgroups")
+			}
+			cgroupManager = libcontainer.RootlessSystemdCgroups
+		} else if systemd.UseSystemd() {
 			cgroupManager = libcontainer.SystemdCgroups
 		} else {
 			return nil, fmt.Errorf("systemd cgroup flag passed, but systemd support for managing cgroups is not available")
@@ -54,7 +59,8 @@ func loadFactory(context *cli.Context) (libcontainer.Factory, error) {
 	}
 
 	intelRdtManager := libcontainer.IntelRdtFs
-	if !intelrdt.IsCatEnabled() && !intelrdt.IsMbaEnabled() {
+	if !intelrdt.IsCatEnabled() && !intelrdt.IsMbaEnabled() &&
+		!intelrdt.IsCmtEnabled() && !intelrdt.IsMbmEnabled() {
 		intelRdtManager = nil
 	}
 
@@ -73,7 +79,24 @@ func loadFactory(context *cli.Context) (libcontainer.Factory, error) {
 	return libcontainer.New(abs, cgroupManager, intelRdtManager,
 		libcontainer.CriuPath(context.GlobalString("criu")),
 		libcontainer.NewuidmapPath(newuidmap),
-		libcontainer.NewgidmapPath(newgidmap))
+		libcontainer.NewgidmapPath(newgidmap),
+		libcontainer.StateLock(stateLockMode(context), context.GlobalDuration("lock-timeout")))
+}
+
+// stateLockMode returns the mode in which the command locks the state of the
+// containers it operates on against concurrent runc processes. Commands which
+// only read the state share the lock, all others hold it exclusively.
+func stateLockMode(context *cli.Context) libcontainer.StateLockMode {
+	switch context.Command.Name {
+	case "state":
+		if context.Bool("repair") {
+			return libcontainer.ExclusiveStateLock
+		}
+		return libcontainer.SharedStateLock
+	case "events", "exec", "list", "ps":
+		return libcontainer.SharedStateLock
+	}
+	return libcontainer.ExclusiveStateLock
 }
 
 // getContainer returns the specified container instance by loading it from state
@@ -263,6 +286,9 @@ type runner struct {
 	action          CtAct
 	notifySocket    *notifySocket
 	criuOpts        *libcontainer.CriuOpts
+	scheduler       *configs.Scheduler
+	ioPriority      *configs.IOPriority
+	cpuAffinity     string
 }
 
 func (r *runner) run(config *specs.Process) (int, error) {
@@ -275,6 +301,13 @@ func (r *runner) run(config *specs.Process) (int, error) {
 		r.destroy()
 		return -1, err
 	}
+	// The profile generated for the container replaces the default one.
+	if process.AppArmorProfile == specconv.AppArmorRuntimeDefault {
+		process.AppArmorProfile = r.container.Config().AppArmorProfile
+	}
+	process.Scheduler = r.scheduler
+	process.IOPriority = r.ioPriority
+	process.CPUAffinity = r.cpuAffinity
 	if len(r.listenFDs) > 0 {
 		process.Env = append(process.Env, fmt.Sprintf("LISTEN_FDS=%d", len(r.listenFDs)), "LISTEN_PID=1")
 		process.ExtraFiles = append(process.ExtraFiles, r.listenFDs...)
@@ -338,6 +371,13 @@ func (r *runner) run(config *specs.Process) (int, error) {
 			return -1, err
 		}
 	}
+	// Don't keep other runc processes from operating on the container while
+	// waiting for the process to exit.
+	if err := r.container.ReleaseStateLock(); err != nil {
+		r.terminate(process)
+		r.destroy()
+		return -1, err
+	}
 	status, err := handler.forward(process, tty, detach)
 	if err != nil {
 		r.terminate(process)
@@ -345,6 +385,16 @@ func (r *runner) run(config *specs.Process) (int, error) {
 	if detach {
 		return 0, nil
 	}
+	// Other runc processes may have operated on the container while it was
+	// unlocked, lock it again before destroying it.
+	if r.shouldDestroy {
+		if lerr := r.container.AcquireStateLock(); lerr != nil {
+			if e, ok := lerr.(libcontainer.Error); !ok || e.Code() != libcontainer.ContainerNotExists {
+				logrus.Error(lerr)
+			}
+			return status, err
+		}
+	}
 	r.destroy()
 	return status, err
 }
@@ -0,0 +1,446 @@
+// +build linux
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/opencontainers/runc/libcontainer/cgroups"
+	"github.com/opencontainers/runc/libcontainer/configs/validate"
+	"github.com/opencontainers/runc/libcontainer/devices"
+	"github.com/opencontainers/runc/libcontainer/seccomp"
+	"github.com/opencontainers/runc/libcontainer/specconv"
+	"github.com/opencontainers/runc/libcontainer/system"
+	"github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/urfave/cli"
+)
+
+const (
+	severityError   = "error"
+	severityWarning = "warning"
+)
+
+// problem is an issue found in the configuration of a bundle. Path is the
+// JSON path of the settings of the config.json of the bundle which cause it,
+// it is empty when they are unknown.
+type problem struct {
+	Severity string `json:"severity"`
+	Path     string `json:"path,omitempty"`
+	Message  string `json:"message"`
+}
+
+// fieldPaths maps the settings of the libcontainer config checked by the
+// config validator to the settings of config.json they are converted from.
+var fieldPaths = map[string]string{
+	"Rootfs":                     "root.path",
+	"RootfsOverlay.LowerDirs":    annotationPath(specconv.AnnotationRootfsOverlayLowerDir),
+	"RootfsOverlay.UpperDir":     annotationPath(specconv.AnnotationRootfsOverlayUpperDir),
+	"RootfsOverlay.WorkDir":      annotationPath(specconv.AnnotationRootfsOverlayWorkDir),
+	"Namespaces":                 "linux.namespaces",
+	"UidMappings":                "linux.uidMappings",
+	"GidMappings":                "linux.gidMappings",
+	"Hostname":                   "hostname",
+	"MaskPaths":                  "linux.maskedPaths",
+	"ReadonlyPaths":              "linux.readonlyPaths",
+	"ProcessLabel":               "process.selinuxLabel",
+	"AppArmorProfile":            "process.apparmorProfile",
+	"NoNewPrivileges":            "process.noNewPrivileges",
+	"TimeOffsets":                annotationPath(specconv.AnnotationTimeOffsets),
+	"Scheduler":                  annotationPath(specconv.AnnotationScheduler),
+	"IOPriority":                 annotationPath(specconv.AnnotationIOPriority),
+	"CPUAffinity":                annotationPath(specconv.AnnotationCPUAffinity),
+	"Personality":                annotationPath(specconv.AnnotationPersonality),
+	"Landlock.DisableBestEffort": annotationPath(specconv.AnnotationLandlock),
+	"Landlock.Rules":             annotationPath(specconv.AnnotationLandlock),
+	"IntelRdt":                   "linux.intelRdt",
+	"IntelRdt.ClosID":            "linux.intelRdt.closID",
+	"IntelRdt.L3CacheSchema":     "linux.intelRdt.l3CacheSchema",
+	"IntelRdt.MemBwSchema":       "linux.intelRdt.memBwSchema",
+}
+
+// mountFieldPaths maps the settings of a mount of the libcontainer config to
+// the settings of the mount of config.json they are converted from.
+var mountFieldPaths = map[string]string{
+	"Source":      "source",
+	"Destination": "destination",
+	"Device":      "type",
+	"Data":        "options",
+	"Extensions":  "options",
+}
+
+var validateCommand = cli.Command{
+	Name:  "validate",
+	Usage: "check the configuration of a bundle without creating a container",
+	Description: `The validate command checks the ` + specConfig + ` of a bundle and reports all the
+problems it finds, instead of stopping at the first one like create. Besides
+the checks done when creating a container, it checks that the configuration
+can be applied on this host: that the devices and bind mount sources exist,
+that the seccomp syscalls are known on this architecture, that the cgroup
+controllers are available and that the uid and gid mapping tools are installed
+when they are needed. These checks are done even when the configuration is
+too broken to be converted.
+
+Each problem is reported with its severity, "error" or "warning", and the JSON
+path of the settings of ` + specConfig + ` which cause it. The command fails if
+any error is found. Nothing is created on the host.`,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "bundle, b",
+			Value: "",
+			Usage: `path to the root of the bundle directory, defaults to the current directory`,
+		},
+		cli.StringFlag{
+			Name:  "format, f",
+			Value: "table",
+			Usage: `select one of: ` + formatOptions,
+		},
+	},
+	Action: func(context *cli.Context) error {
+		if err := checkArgs(context, 0, exactArgs); err != nil {
+			return err
+		}
+		if bundle := context.String("bundle"); bundle != "" {
+			if err := os.Chdir(bundle); err != nil {
+				return err
+			}
+		}
+		problems := validateBundle(context)
+
+		switch context.String("format") {
+		case "table":
+			w := tabwriter.NewWriter(os.Stdout, 12, 1, 3, ' ', 0)
+			fmt.Fprint(w, "SEVERITY\tPATH\tMESSAGE\n")
+			for _, p := range problems {
+				fmt.Fprintf(w, "%s\t%s\t%s\n", p.Severity, p.Path, p.Message)
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+		case "json":
+			if problems == nil {
+				problems = []problem{}
+			}
+			if err := json.NewEncoder(os.Stdout).Encode(problems); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("invalid format option")
+		}
+
+		errors := 0
+		for _, p := range problems {
+			if p.Severity == severityError {
+				errors++
+			}
+		}
+		if errors > 0 {
+			return fmt.Errorf("%s has %d error(s)", specConfig, errors)
+		}
+		return nil
+	},
+}
+
+// validateBundle checks the config.json in the current directory. Unlike
+// loadSpec, it reports the problems of the process instead of failing, and
+// the checks of the host which only need the spec are run even when it can't
+// be converted.
+func validateBundle(context *cli.Context) []problem {
+	data, err := ioutil.ReadFile(specConfig)
+	if err != nil {
+		return []problem{{Severity: severityError, Message: err.Error()}}
+	}
+	var spec *specs.Spec
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return []problem{{Severity: severityError, Message: fmt.Sprintf("unable to parse %s: %v", specConfig, err)}}
+	}
+	if spec == nil {
+		return []problem{{Severity: severityError, Message: fmt.Sprintf("%s is empty", specConfig)}}
+	}
+	problems := checkProcess(spec.Process)
+
+	rootlessCg, err := shouldUseRootlessCgroupManager(context)
+	if err != nil {
+		problems = append(problems, problem{Severity: severityError, Message: err.Error()})
+	}
+	config, err := specconv.CreateLibcontainerConfig(&specconv.CreateOpts{
+		CgroupName:       "validate",
+		UseSystemdCgroup: context.GlobalBool("systemd-cgroup"),
+		Spec:             spec,
+		RootlessEUID:     os.Geteuid() != 0,
+		RootlessCgroups:  rootlessCg,
+	})
+	if err != nil {
+		// The config validator needs the converted configuration, the
+		// checks of the host don't.
+		problems = append(problems, problem{Severity: severityError, Message: err.Error()})
+	} else {
+		for _, err := range (&validate.ConfigValidator{}).ValidateAll(config) {
+			problems = append(problems, problem{Severity: severityError, Path: specPath(spec, err.Field), Message: err.Error()})
+		}
+	}
+
+	problems = append(problems, checkHostMounts(spec)...)
+	problems = append(problems, checkHostDevices(spec)...)
+	problems = append(problems, checkHostSeccomp(spec)...)
+	if !rootlessCg {
+		problems = append(problems, checkHostCgroups(spec)...)
+	}
+	problems = append(problems, checkHostIDMappings(spec, os.Geteuid(), os.Getegid())...)
+	return problems
+}
+
+// checkProcess does the checks of validateProcessSpec, reporting all the
+// problems of the process.
+func checkProcess(p *specs.Process) []problem {
+	if p == nil {
+		return []problem{{Severity: severityError, Path: "process", Message: "process must be set"}}
+	}
+	var problems []problem
+	if p.Cwd == "" {
+		problems = append(problems, problem{Severity: severityError, Path: "process.cwd", Message: "Cwd property must not be empty"})
+	} else if !filepath.IsAbs(p.Cwd) {
+		problems = append(problems, problem{Severity: severityError, Path: "process.cwd", Message: "Cwd must be an absolute path"})
+	}
+	if len(p.Args) == 0 {
+		problems = append(problems, problem{Severity: severityError, Path: "process.args", Message: "args must not be empty"})
+	}
+	return problems
+}
+
+// specPath returns the JSON path of the settings of spec which field of the
+// converted config comes from, it is empty when they are unknown.
+func specPath(spec *specs.Spec, field string) string {
+	var (
+		i   int
+		sub string
+	)
+	if n, _ := fmt.Sscanf(field, "Mounts[%d].%s", &i, &sub); n == 2 {
+		return fmt.Sprintf("mounts[%d].%s", i, mountFieldPaths[sub])
+	}
+	if strings.HasPrefix(field, "Sysctl[") {
+		return "linux.sysctl" + strings.TrimPrefix(field, "Sysctl")
+	}
+	if field == "AppArmorProfileContent" {
+		// The profile is given inline, by its path or is the default one.
+		for _, key := range []string{specconv.AnnotationAppArmorProfile, specconv.AnnotationAppArmorProfilePath} {
+			if _, ok := spec.Annotations[key]; ok {
+				return annotationPath(key)
+			}
+		}
+		return "process.apparmorProfile"
+	}
+	// The other lists are set by annotations, whose values are not
+	// indexed by JSON paths.
+	if i := strings.IndexByte(field, '['); i >= 0 {
+		field = field[:i]
+	}
+	return fieldPaths[field]
+}
+
+// checkHostMounts checks that the sources of the bind mounts exist.
+func checkHostMounts(spec *specs.Spec) []problem {
+	var problems []problem
+	for i, m := range spec.Mounts {
+		if m.Type != "bind" && !hasBindOption(m.Options) {
+			continue
+		}
+		if _, err := os.Stat(m.Source); err != nil {
+			problems = append(problems, problem{
+				Severity: severityError,
+				Path:     fmt.Sprintf("mounts[%d].source", i),
+				Message:  fmt.Sprintf("source of the bind mount of %s: %v", m.Destination, err),
+			})
+		}
+	}
+	return problems
+}
+
+// checkHostDevices checks the devices of the container against the ones of
+// the host. In a user namespace the devices are bind mounted from the host,
+// so they must exist, otherwise they are created and only need to match the
+// devices of the host at the same paths.
+func checkHostDevices(spec *specs.Spec) []problem {
+	if spec.Linux == nil {
+		return nil
+	}
+	useBindMount := system.RunningInUserNS() || hasUserNamespace(spec)
+	var problems []problem
+	for i, d := range spec.Linux.Devices {
+		path := fmt.Sprintf("linux.devices[%d]", i)
+		host, err := devices.DeviceFromPath(d.Path, "rwm")
+		if err != nil {
+			if useBindMount {
+				problems = append(problems, problem{
+					Severity: severityError,
+					Path:     path,
+					Message:  fmt.Sprintf("device %s is bind mounted from the host in a user namespace: %v", d.Path, err),
+				})
+			} else {
+				problems = append(problems, problem{
+					Severity: severityWarning,
+					Path:     path,
+					Message:  fmt.Sprintf("device %s is not a device of the host, it may not be usable: %v", d.Path, err),
+				})
+			}
+			continue
+		}
+		typ := d.Type
+		if typ == "u" {
+			typ = "c"
+		}
+		if string(host.Type) != typ || host.Major != d.Major || host.Minor != d.Minor {
+			problems = append(problems, problem{
+				Severity: severityWarning,
+				Path:     path,
+				Message: fmt.Sprintf("device %s (%s %d:%d) does not match the device of the host (%c %d:%d)",
+					d.Path, d.Type, d.Major, d.Minor, host.Type, host.Major, host.Minor),
+			})
+		}
+	}
+	return problems
+}
+
+// checkHostSeccomp checks that seccomp is supported and that the syscalls of
+// the rules are known on this architecture, the rules of unknown syscalls are
+// ignored.
+func checkHostSeccomp(spec *specs.Spec) []problem {
+	if spec.Linux == nil || spec.Linux.Seccomp == nil {
+		return nil
+	}
+	if !seccomp.IsEnabled() {
+		return []problem{{
+			Severity: severityError,
+			Path:     "linux.seccomp",
+			Message:  "seccomp is not supported by runc or by the kernel",
+		}}
+	}
+	var problems []problem
+	for i, s := range spec.Linux.Seccomp.Syscalls {
+		for j, name := range s.Names {
+			if !seccomp.IsSyscallKnown(name) {
+				problems = append(problems, problem{
+					Severity: severityWarning,
+					Path:     fmt.Sprintf("linux.seccomp.syscalls[%d].names[%d]", i, j),
+					Message:  fmt.Sprintf("syscall %s is unknown on this architecture, its rule is ignored", name),
+				})
+			}
+		}
+	}
+	return problems
+}
+
+// checkHostCgroups checks that the cgroup controllers of the resources are
+// available.
+func checkHostCgroups(spec *specs.Spec) []problem {
+	if spec.Linux == nil || spec.Linux.Resources == nil {
+		return nil
+	}
+	subsystems, err := cgroups.GetAllSubsystems()
+	if err != nil {
+		return []problem{{
+			Severity: severityWarning,
+			Path:     "linux.resources",
+			Message:  fmt.Sprintf("unable to list the cgroup controllers: %v", err),
+		}}
+	}
+	available := make(map[string]bool, len(subsystems))
+	for _, s := range subsystems {
+		available[s] = true
+	}
+	return checkCgroupControllers(spec.Linux.Resources, available)
+}
+
+// checkCgroupControllers checks the resources against the available cgroup
+// controllers. The limits of unavailable controllers are ignored, except for
+// the devices controller which is required.
+func checkCgroupControllers(r *specs.LinuxResources, available map[string]bool) []problem {
+	type controller struct {
+		name string
+		path string
+		used bool
+	}
+	controllers := []controller{
+		{"devices", "linux.resources.devices", true},
+		{"memory", "linux.resources.memory", r.Memory != nil},
+		{"cpu", "linux.resources.cpu", r.CPU != nil && (r.CPU.Shares != nil || r.CPU.Quota != nil || r.CPU.Period != nil || r.CPU.RealtimeRuntime != nil || r.CPU.RealtimePeriod != nil)},
+		{"cpuset", "linux.resources.cpu", r.CPU != nil && (r.CPU.Cpus != "" || r.CPU.Mems != "")},
+		{"pids", "linux.resources.pids", r.Pids != nil},
+		{"blkio", "linux.resources.blockIO", r.BlockIO != nil},
+		{"hugetlb", "linux.resources.hugepageLimits", len(r.HugepageLimits) > 0},
+		{"net_cls", "linux.resources.network", r.Network != nil && r.Network.ClassID != nil},
+		{"net_prio", "linux.resources.network", r.Network != nil && len(r.Network.Priorities) > 0},
+	}
+	var problems []problem
+	for _, c := range controllers {
+		if !c.used || available[c.name] {
+			continue
+		}
+		p := problem{
+			Severity: severityWarning,
+			Path:     c.path,
+			Message:  fmt.Sprintf("the %s cgroup controller is not available, its limits are ignored", c.name),
+		}
+		if c.name == "devices" {
+			p.Severity = severityError
+			p.Message = "the devices cgroup controller is not available"
+		}
+		problems = append(problems, p)
+	}
+	return problems
+}
+
+// checkHostIDMappings checks that newuidmap and newgidmap are installed when
+// a rootless container, run by euid and egid, maps more than its own user and
+// group, which only they are allowed to do.
+func checkHostIDMappings(spec *specs.Spec, euid, egid int) []problem {
+	if euid == 0 || !hasUserNamespace(spec) {
+		return nil
+	}
+	var problems []problem
+	for _, m := range []struct {
+		tool     string
+		path     string
+		id       uint32
+		mappings []specs.LinuxIDMapping
+	}{
+		{"newuidmap", "linux.uidMappings", uint32(euid), spec.Linux.UIDMappings},
+		{"newgidmap", "linux.gidMappings", uint32(egid), spec.Linux.GIDMappings},
+	} {
+		if len(m.mappings) == 0 || (len(m.mappings) == 1 && m.mappings[0].HostID == m.id && m.mappings[0].Size == 1) {
+			continue
+		}
+		if _, err := exec.LookPath(m.tool); err != nil {
+			problems = append(problems, problem{
+				Severity: severityError,
+				Path:     m.path,
+				Message:  fmt.Sprintf("%s is required to map more than the current user or group: %v", m.tool, err),
+			})
+		}
+	}
+	return problems
+}
+
+// hasUserNamespace returns whether the container has a user namespace.
+func hasUserNamespace(spec *specs.Spec) bool {
+	if spec.Linux == nil {
+		return false
+	}
+	for _, ns := range spec.Linux.Namespaces {
+		if ns.Type == specs.UserNamespace {
+			return true
+		}
+	}
+	return false
+}
+
+// annotationPath returns the JSON path of an annotation.
+func annotationPath(key string) string {
+	return fmt.Sprintf("annotations[%q]", key)
+}
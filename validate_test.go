@@ -0,0 +1,154 @@
+// +build linux
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/opencontainers/runc/libcontainer/seccomp"
+	"github.com/opencontainers/runc/libcontainer/specconv"
+	"github.com/opencontainers/runc/libcontainer/system"
+	"github.com/opencontainers/runtime-spec/specs-go"
+)
+
+// problemPaths returns the severities and paths of problems.
+func problemPaths(problems []problem) []string {
+	var paths []string
+	for _, p := range problems {
+		paths = append(paths, p.Severity+" "+p.Path)
+	}
+	return paths
+}
+
+func checkProblems(t *testing.T, problems []problem, expected ...string) {
+	t.Helper()
+	if paths := problemPaths(problems); !reflect.DeepEqual(paths, expected) {
+		t.Errorf("expected the problems %q, got %q (%+v)", expected, paths, problems)
+	}
+}
+
+func TestCheckProcess(t *testing.T) {
+	checkProblems(t, checkProcess(nil), "error process")
+	checkProblems(t, checkProcess(&specs.Process{Cwd: "/", Args: []string{"sh"}}))
+	checkProblems(t, checkProcess(&specs.Process{Cwd: "tmp"}), "error process.cwd", "error process.args")
+}
+
+func TestSpecPath(t *testing.T) {
+	spec := &specs.Spec{
+		Annotations: map[string]string{
+			specconv.AnnotationAppArmorProfilePath: "profile",
+		},
+	}
+	for _, tc := range []struct {
+		field string
+		path  string
+	}{
+		{"Rootfs", "root.path"},
+		{"Mounts[3].Extensions", "mounts[3].options"},
+		{"Mounts[0].Device", "mounts[0].type"},
+		{`Sysctl["net.ipv4.ip_forward"]`, `linux.sysctl["net.ipv4.ip_forward"]`},
+		{"RootfsOverlay.LowerDirs[1]", annotationPath(specconv.AnnotationRootfsOverlayLowerDir)},
+		{`TimeOffsets["boottime"]`, annotationPath(specconv.AnnotationTimeOffsets)},
+		{"Landlock.Rules[2]", annotationPath(specconv.AnnotationLandlock)},
+		{"AppArmorProfileContent", annotationPath(specconv.AnnotationAppArmorProfilePath)},
+		{"IntelRdt.ClosID", "linux.intelRdt.closID"},
+		{"", ""},
+	} {
+		if path := specPath(spec, tc.field); path != tc.path {
+			t.Errorf("expected the path of %s to be %q, got %q", tc.field, tc.path, path)
+		}
+	}
+}
+
+func TestCheckHostMounts(t *testing.T) {
+	dir, err := ioutil.TempDir("", "validate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	spec := &specs.Spec{
+		Mounts: []specs.Mount{
+			{Destination: "/proc", Type: "proc", Source: "proc"},
+			{Destination: "/a", Type: "bind", Source: dir},
+			{Destination: "/b", Type: "none", Source: dir + "/missing", Options: []string{"rbind"}},
+			{Destination: "/c", Type: "bind", Source: dir + "/missing"},
+		},
+	}
+	checkProblems(t, checkHostMounts(spec), "error mounts[2].source", "error mounts[3].source")
+}
+
+func TestCheckHostDevices(t *testing.T) {
+	spec := &specs.Spec{
+		Linux: &specs.Linux{
+			Devices: []specs.LinuxDevice{
+				{Path: "/dev/null", Type: "c", Major: 1, Minor: 3},
+				{Path: "/dev/null", Type: "c", Major: 1, Minor: 5},
+				{Path: "/dev/missing", Type: "c", Major: 1, Minor: 9},
+			},
+		},
+	}
+	missing := "warning linux.devices[2]"
+	if system.RunningInUserNS() {
+		missing = "error linux.devices[2]"
+	}
+	checkProblems(t, checkHostDevices(spec), "warning linux.devices[1]", missing)
+
+	spec.Linux.Namespaces = []specs.LinuxNamespace{{Type: specs.UserNamespace}}
+	checkProblems(t, checkHostDevices(spec), "warning linux.devices[1]", "error linux.devices[2]")
+}
+
+func TestCheckHostSeccomp(t *testing.T) {
+	if !seccomp.IsEnabled() {
+		t.Skip("seccomp is not supported")
+	}
+	spec := &specs.Spec{
+		Linux: &specs.Linux{
+			Seccomp: &specs.LinuxSeccomp{
+				DefaultAction: specs.ActAllow,
+				Syscalls: []specs.LinuxSyscall{
+					{Names: []string{"read", "notasyscall"}, Action: specs.ActErrno},
+				},
+			},
+		},
+	}
+	checkProblems(t, checkHostSeccomp(spec), "warning linux.seccomp.syscalls[0].names[1]")
+}
+
+func TestCheckCgroupControllers(t *testing.T) {
+	var limit int64 = 1024
+	r := &specs.LinuxResources{
+		Memory: &specs.LinuxMemory{Limit: &limit},
+		Pids:   &specs.LinuxPids{Limit: 10},
+	}
+	checkProblems(t, checkCgroupControllers(r, map[string]bool{"devices": true, "memory": true, "pids": true}))
+	checkProblems(t, checkCgroupControllers(r, map[string]bool{"memory": true}),
+		"error linux.resources.devices", "warning linux.resources.pids")
+}
+
+func TestCheckHostIDMappings(t *testing.T) {
+	dir, err := ioutil.TempDir("", "validate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer os.Setenv("PATH", os.Getenv("PATH"))
+	// Neither newuidmap nor newgidmap are found.
+	os.Setenv("PATH", dir)
+
+	spec := &specs.Spec{
+		Linux: &specs.Linux{
+			Namespaces:  []specs.LinuxNamespace{{Type: specs.UserNamespace}},
+			UIDMappings: []specs.LinuxIDMapping{{HostID: 1000, ContainerID: 0, Size: 1}},
+			GIDMappings: []specs.LinuxIDMapping{{HostID: 100000, ContainerID: 0, Size: 65536}},
+		},
+	}
+	checkProblems(t, checkHostIDMappings(spec, 0, 0))
+	checkProblems(t, checkHostIDMappings(spec, 1000, 1000), "error linux.gidMappings")
+
+	spec.Linux.Namespaces = nil
+	checkProblems(t, checkHostIDMappings(spec, 1000, 1000))
+}